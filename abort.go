@@ -0,0 +1,97 @@
+package cdb
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrAborted is returned by a Writer's methods once Abort has been called.
+var ErrAborted = errors.New("writer was aborted")
+
+// Abort discards the database being written, instead of finalizing it, and
+// closes the underlying stream if it's an io.Closer. Afterwards, every other
+// method returns ErrAborted.
+//
+// If the Writer was created with Create, the file is removed. For a Writer
+// from OpenForAppend or NewAppendWriter, the new records are discarded, and
+// the database is restored with just the records it had before. Otherwise,
+// the stream is truncated to nothing if it has a Truncate(int64) error method,
+// like *os.File; if it doesn't, the index at the start of the stream is never
+// written, so the partial database can't be opened. Nothing is written to
+// the destination of a Writer from NewStreamWriter.
+//
+// Abort returns ErrFinalized if the database has already been finalized.
+func (cdb *Writer) Abort() error {
+	err := cdb.checkWritable()
+	if err != nil {
+		return err
+	}
+
+	cdb.aborted = true
+	err = cdb.discard()
+
+	if closer, ok := cdb.writer.(io.Closer); ok {
+		closeErr := closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+
+	if cdb.path != "" {
+		removeErr := os.Remove(cdb.path)
+		if err == nil {
+			err = removeErr
+		}
+	}
+
+	return err
+}
+
+// discard throws away whatever has been written to the stream, or, for an
+// append writer, puts back the database as it was.
+func (cdb *Writer) discard() error {
+	truncater, canTruncate := cdb.writer.(interface{ Truncate(int64) error })
+	if cdb.appendOrigin != nil {
+		return cdb.restore(truncater)
+	} else if cdb.path != "" || !canTruncate {
+		return nil
+	}
+
+	return truncater.Truncate(0)
+}
+
+// restore rewrites the hash tables and index of an append writer, as they
+// were before any records were added or deleted.
+func (cdb *Writer) restore(truncater interface{ Truncate(int64) error }) error {
+	origin := cdb.appendOrigin
+	if truncater != nil {
+		err := truncater.Truncate(origin.end)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := cdb.resetBuffer(origin.end)
+	if err != nil {
+		return err
+	}
+
+	cdb.entries = origin.entries
+	cdb.dead = nil
+	cdb.records = origin.records
+	cdb.metadata = origin.metadata
+	cdb.estimatedFooterSize = cdb.footerSizePerEntry() * origin.records
+
+	_, err = cdb.finalize()
+	return err
+}
+
+// appendOrigin is the state of a database opened for appending, before any
+// changes were made.
+type appendOrigin struct {
+	end      int64
+	entries  [256][]entry
+	records  int64
+	metadata map[string]string
+}
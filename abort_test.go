@@ -0,0 +1,100 @@
+package cdb_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "aborted.cdb")
+	writer, err := cdb.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Abort())
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Equal(t, cdb.ErrAborted, writer.Put([]byte("foo"), []byte("bar")))
+	assert.Equal(t, cdb.ErrAborted, writer.PutReader([]byte("foo"), 3, strings.NewReader("bar")))
+	assert.Equal(t, cdb.ErrAborted, writer.Close())
+	assert.Equal(t, cdb.ErrAborted, writer.Abort())
+	_, err = writer.Freeze()
+	assert.Equal(t, cdb.ErrAborted, err)
+}
+
+func TestAbortTruncates(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Abort())
+
+	info, err := os.Stat(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	_, err = cdb.Open(f.Name())
+	assert.Error(t, err)
+}
+
+func TestAbortAfterClose(t *testing.T) {
+	writer := cdb.NewMem()
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	_, err := writer.Freeze()
+	require.NoError(t, err)
+
+	assert.Equal(t, cdb.ErrFinalized, writer.Abort())
+}
+
+func TestAbortAppend(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	src, err := os.Open("./test/test.cdb")
+	require.NoError(t, err)
+	size, err := io.Copy(f, src)
+	require.NoError(t, err)
+	src.Close()
+	f.Close()
+
+	writer, err := cdb.OpenForAppend(f.Name())
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("appended"), []byte("value")))
+	require.NoError(t, writer.Delete([]byte("foo")))
+	require.NoError(t, writer.Abort())
+
+	info, err := os.Stat(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, size, info.Size())
+
+	db, err := cdb.Open(f.Name())
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	value, err := db.Get([]byte("appended"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
@@ -0,0 +1,90 @@
+package cdb
+
+import "encoding/binary"
+
+// maxAlignment is the largest boundary WithAlignment accepts.
+const maxAlignment = 1 << 20
+
+// WithAlignment makes a Writer pad the data section so that every record
+// starts at a multiple of n bytes from the start of the file, so that records
+// can be read with O_DIRECT, or mapped page-aligned. n must be a power of two
+// between 2 and 1MB; other values are ignored. Each record takes up to n-1
+// bytes of padding.
+//
+// The alignment is stored after the hash tables, and picked up automatically
+// when the database is opened, as long as the size of the underlying
+// io.ReaderAt is known, as it is for files and for OpenURL. Lookups in other
+// cdb implementations aren't affected, but tools that scan the data section
+// from start to finish, such as cdbdump, won't understand the padding.
+// Appending to a database always uses the alignment it was written with, or
+// none.
+func WithAlignment(n int) Option {
+	return func(o *options) {
+		if n >= 2 && n <= maxAlignment && n&(n-1) == 0 {
+			o.alignment = uint32(n)
+		}
+	}
+}
+
+// align rounds offset up to the alignment set in the options, if any.
+func (o *options) align(offset int64) int64 {
+	if o.alignment == 0 {
+		return offset
+	}
+
+	mask := int64(o.alignment) - 1
+	return (offset + mask) &^ mask
+}
+
+// firstRecord returns the offset of the first record.
+func (cdb *CDB) firstRecord() uint32 {
+	return cdb.nextRecord(indexSize)
+}
+
+// nextRecord returns the offset of the record after one that ends at end.
+func (cdb *CDB) nextRecord(end uint32) uint32 {
+	aligned := cdb.opts.align(int64(end))
+	if aligned > int64(cdb.index[0].offset) {
+		return cdb.index[0].offset
+	}
+
+	return uint32(aligned)
+}
+
+// writePadding pads the data section, if necessary, so that the next record
+// starts on the boundary set by WithAlignment.
+func (cdb *Writer) writePadding() error {
+	pad := cdb.opts.align(cdb.bufferedOffset) - cdb.bufferedOffset
+	if pad == 0 {
+		return nil
+	}
+
+	_, err := cdb.bufferedWriter.Write(make([]byte, pad))
+	if err != nil {
+		return err
+	}
+
+	cdb.bufferedOffset += pad
+	return nil
+}
+
+// buildAlignment encodes the alignment for the extension block.
+func (cdb *Writer) buildAlignment() []byte {
+	return binary.LittleEndian.AppendUint32(nil, cdb.opts.alignment)
+}
+
+// parseAlignment decodes the alignment section, which takes precedence over
+// the options the database was opened with.
+func (cdb *CDB) parseAlignment(data []byte) error {
+	if len(data) != 4 {
+		return ErrCorrupt
+	}
+
+	n := binary.LittleEndian.Uint32(data)
+	if n < 2 || n > maxAlignment || n&(n-1) != 0 {
+		return ErrCorrupt
+	}
+
+	cdb.opts.alignment = n
+	return nil
+}
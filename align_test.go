@@ -0,0 +1,150 @@
+package cdb_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlignment(t *testing.T) {
+	for _, c := range []struct {
+		alignment uint32
+		opts      []cdb.Option
+	}{
+		{4096, nil},
+		{512, []cdb.Option{cdb.WithGrouping(cdb.GroupByKey)}},
+		{64, []cdb.Option{cdb.WithGrouping(cdb.GroupByTable), cdb.WithHeader()}},
+	} {
+		opts := append(c.opts, cdb.WithAlignment(int(c.alignment)))
+		f, err := ioutil.TempFile("", "test-cdb")
+		require.NoError(t, err)
+		defer os.Remove(f.Name())
+
+		writer, err := cdb.NewWriter(f, nil, opts...)
+		require.NoError(t, err)
+
+		expected := make(map[string]string)
+		for i := 0; i < 100; i++ {
+			key, value := fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i)
+			if i%2 == 0 {
+				require.NoError(t, writer.Put([]byte(key), []byte(value)))
+			} else {
+				require.NoError(t, writer.PutReader([]byte(key), uint32(len(value)), bytes.NewReader([]byte(value))))
+			}
+
+			expected[key] = value
+		}
+
+		require.NoError(t, writer.Delete([]byte("key0")))
+		require.NoError(t, writer.Delete([]byte("key50")))
+		require.NoError(t, writer.Put([]byte("key100"), []byte("value100")))
+		delete(expected, "key0")
+		delete(expected, "key50")
+		expected["key100"] = "value100"
+		require.NoError(t, writer.Close())
+
+		// The alignment is picked up from the file.
+		db, err := cdb.Open(f.Name())
+		require.NoError(t, err)
+		checkAligned(t, db, c.alignment, expected)
+		db.Close()
+	}
+}
+
+func TestAlignmentParallelWriter(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil, cdb.WithAlignment(256))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("first"), []byte("record")))
+
+	pw := cdb.NewParallelWriter(writer, "")
+	expected := map[string]string{"first": "record"}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("key%d-%d", g, i)
+				assert.NoError(t, pw.Put([]byte(key), []byte(key)))
+			}
+		}(g)
+
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprintf("key%d-%d", g, i)
+			expected[key] = key
+		}
+	}
+
+	wg.Wait()
+	db, err := pw.Freeze()
+	require.NoError(t, err)
+	defer db.Close()
+
+	checkAligned(t, db, 256, expected)
+}
+
+// checkAligned checks that every record in the database starts on the given
+// boundary, and that the database contains
+// exactly the expected records.
+func checkAligned(t *testing.T, db *cdb.CDB, alignment uint32, expected map[string]string) {
+	t.Helper()
+
+	report, err := cdb.Analyze(db)
+	require.NoError(t, err)
+	assert.Equal(t, len(expected), report.Records)
+
+	n := 0
+	err = db.EachRecord(func(rec cdb.Record) error {
+		n++
+		assert.True(t, rec.Offset()%alignment == 0, "record at %d isn't aligned", rec.Offset())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, len(expected), n)
+
+	found := make(map[string]string)
+	iter := db.Iter()
+	for iter.Next() {
+		found[string(iter.Key())] = string(iter.Value())
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, expected, found)
+
+	for key, value := range expected {
+		v, err := db.Get([]byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, value, string(v))
+	}
+}
+
+func TestAlignmentAppend(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil, cdb.WithAlignment(128))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Close())
+
+	writer, err = cdb.OpenForAppend(f.Name())
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("baz"), []byte("qux")))
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	defer db.Close()
+
+	checkAligned(t, db, 128, map[string]string{"foo": "bar", "baz": "qux"})
+}
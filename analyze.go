@@ -0,0 +1,159 @@
+package cdb
+
+import "math/bits"
+
+// Report is a detailed analysis of a database, returned by Analyze, for
+// capacity planning and diagnosing slow lookups.
+type Report struct {
+	// Records is the number of records in the database.
+	Records int
+	// DataSize is the size of the data section, in bytes, including record
+	// headers.
+	DataSize int64
+	// AverageProbeLength is the mean number of slots that need to be read to
+	// find a record, over every record in the database.
+	AverageProbeLength float64
+	// MaxProbeLength is the number of slots that need to be read to find the
+	// hardest-to-reach record in the database.
+	MaxProbeLength int
+	// HashGroups is the number of hashes shared by more than one record, and
+	// HashGroupRecords the number of records with those hashes. Records with
+	// the same key always share a hash; otherwise, they're collisions, which
+	// cost a key comparison on every lookup that reaches them.
+	HashGroups       int
+	HashGroupRecords int
+	// ValueSizes is a histogram of the size of the values in the data
+	// section, as they're stored, with a bucket for each power of two up to
+	// the largest value.
+	ValueSizes []SizeBucket
+	// Tables contains an analysis of each of the 256 hash tables.
+	Tables [256]TableReport
+}
+
+// TableReport is the part of a Report for a single hash table.
+type TableReport struct {
+	// Slots is the length of the hash table.
+	Slots int
+	// Entries is the number of filled slots in the hash table.
+	Entries int
+	// AverageProbeLength is the mean number of slots that need to be read to
+	// find a record in this table.
+	AverageProbeLength float64
+	// MaxProbeLength is the number of slots that need to be read to find the
+	// hardest-to-reach record in this table.
+	MaxProbeLength int
+	// HashGroups is the number of hashes shared by more than one record in
+	// this table.
+	HashGroups int
+}
+
+// SizeBucket counts the values in a range of sizes.
+type SizeBucket struct {
+	// Min and Max are the smallest and largest value sizes in the bucket, in
+	// bytes.
+	Min, Max int64
+	// Records is the number of records with values in the bucket.
+	Records int
+	// Bytes is the total size of those records, including their headers and
+	// keys.
+	Bytes int64
+}
+
+// Analyze reads the hash tables and the header of every record, and reports
+// on the database's layout in more detail than Stats. Since it reads through
+// the whole data section, it's much slower than Stats for large databases.
+func Analyze(db *CDB) (Report, error) {
+	report := Report{DataSize: db.DataSize()}
+	err := db.acquire(opScan)
+	if err != nil {
+		return report, err
+	}
+	defer db.release(opScan)
+
+	totalProbe := 0
+	for i, table := range db.index {
+		tr := TableReport{Slots: int(table.length)}
+		hashes := make(map[uint32]int)
+		tableProbe := 0
+
+		err := db.scanTable(table, func(slot, hash, offset uint32) {
+			ideal := (hash >> 8) % table.length
+			probeLength := int((slot+table.length-ideal)%table.length) + 1
+
+			tr.Entries++
+			tableProbe += probeLength
+			if probeLength > tr.MaxProbeLength {
+				tr.MaxProbeLength = probeLength
+			}
+
+			hashes[hash]++
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, n := range hashes {
+			if n > 1 {
+				tr.HashGroups++
+				report.HashGroupRecords += n
+			}
+		}
+
+		if tr.Entries > 0 {
+			tr.AverageProbeLength = float64(tableProbe) / float64(tr.Entries)
+		}
+
+		report.Records += tr.Entries
+		report.HashGroups += tr.HashGroups
+		if tr.MaxProbeLength > report.MaxProbeLength {
+			report.MaxProbeLength = tr.MaxProbeLength
+		}
+
+		totalProbe += tableProbe
+		report.Tables[i] = tr
+	}
+
+	if report.Records > 0 {
+		report.AverageProbeLength = float64(totalProbe) / float64(report.Records)
+	}
+
+	report.ValueSizes, err = db.valueSizes()
+	return report, err
+}
+
+// valueSizes reads the header of every record in the data section, and builds
+// a histogram of the value sizes.
+func (cdb *CDB) valueSizes() ([]SizeBucket, error) {
+	var buckets []SizeBucket
+	tuple := make([]byte, 8)
+	end := cdb.index[0].offset
+	for offset := cdb.firstRecord(); offset < end; {
+		keyLength, valueLength, err := cdb.readHeader(offset, tuple)
+		if err != nil {
+			return nil, err
+		}
+
+		// Bucket zero holds empty values, and bucket i values from 2^(i-1)
+		// to 2^i - 1 bytes long.
+		i := bits.Len32(valueLength)
+		for len(buckets) <= i {
+			buckets = append(buckets, sizeBucket(len(buckets)))
+		}
+
+		length := 8 + int64(keyLength) + int64(valueLength)
+		buckets[i].Records++
+		buckets[i].Bytes += length
+		offset = cdb.nextRecord(offset + uint32(length))
+	}
+
+	return buckets, nil
+}
+
+// sizeBucket returns the empty bucket at position i in the histogram.
+func sizeBucket(i int) SizeBucket {
+	if i == 0 {
+		return SizeBucket{}
+	}
+
+	return SizeBucket{Min: 1 << (i - 1), Max: 1<<i - 1}
+}
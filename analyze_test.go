@@ -0,0 +1,49 @@
+package cdb_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	writer := cdb.NewMem()
+	require.NoError(t, writer.Put([]byte("a"), nil))
+	require.NoError(t, writer.Put([]byte("b"), []byte("x")))
+	require.NoError(t, writer.Put([]byte("b"), []byte("xyz")))
+	require.NoError(t, writer.Put([]byte("c"), bytes.Repeat([]byte("x"), 100)))
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	report, err := cdb.Analyze(db)
+	require.NoError(t, err)
+
+	stats, err := db.Stats()
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.Records)
+	assert.Equal(t, stats.DataSize, report.DataSize)
+	assert.Equal(t, stats.MaxProbeLength, report.MaxProbeLength)
+	assert.True(t, report.AverageProbeLength >= 1)
+
+	// The two records for "b" share a hash.
+	assert.Equal(t, 1, report.HashGroups)
+	assert.Equal(t, 2, report.HashGroupRecords)
+
+	for i, table := range report.Tables {
+		assert.Equal(t, stats.Tables[i].Slots, table.Slots)
+		assert.Equal(t, stats.Tables[i].Entries, table.Entries)
+		assert.Equal(t, stats.Tables[i].MaxProbeLength, table.MaxProbeLength)
+	}
+
+	require.Len(t, report.ValueSizes, 8)
+	expected := map[int]int{0: 1, 1: 1, 2: 1, 7: 1}
+	for i, bucket := range report.ValueSizes {
+		assert.Equal(t, expected[i], bucket.Records, "bucket %d", i)
+	}
+
+	assert.Equal(t, cdb.SizeBucket{Min: 64, Max: 127, Records: 1, Bytes: 109}, report.ValueSizes[7])
+}
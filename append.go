@@ -0,0 +1,119 @@
+package cdb
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+// ReadWriteSeekerAt is the interface required by NewAppendWriter.
+type ReadWriteSeekerAt interface {
+	io.ReaderAt
+	io.WriteSeeker
+}
+
+// OpenForAppend opens the existing database at path, so that more records can
+// be added to it. The existing records are kept in place, and the new ones
+// are written after them, so only the hash tables need to be rebuilt.
+//
+// The file is modified in place: until the returned Writer is closed, the
+// file is invalid. If that's a problem, copy the file first, or read it and
+// write a new one with Convert.
+func OpenForAppend(path string, opts ...Option) (*Writer, error) {
+	f, err := openFile(path, os.O_RDWR, buildOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := NewAppendWriter(f, nil, opts...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// NewAppendWriter opens an existing database in the given file, so that more
+// records can be added to it, as with OpenForAppend.
+//
+// The hash function must be the one the database was created with; if hash is
+// nil, it will default to the CDB hash function.
+func NewAppendWriter(file ReadWriteSeekerAt, hash func([]byte) uint32, opts ...Option) (*Writer, error) {
+	db, err := New(file, hash, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebuild the in-memory hash tables from the ones in the file. Records
+	// are appended to the tables in the order they were written, so sorting
+	// by offset recovers the original order, which matters for duplicate
+	// keys.
+	var entries [256][]entry
+	var records int64
+	for i, table := range db.index {
+		err := db.scanTable(table, func(slot, hash, offset uint32) {
+			entries[i] = append(entries[i], entry{hash: hash, offset: offset})
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Slice(entries[i], func(a, b int) bool {
+			return entries[i][a].offset < entries[i][b].offset
+		})
+
+		records += int64(len(entries[i]))
+	}
+
+	// The new records overwrite the old hash tables.
+	end, _ := db.tablesRegion()
+	if truncater, ok := file.(interface{ Truncate(int64) error }); ok {
+		err = truncater.Truncate(int64(end))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w := &Writer{
+		hash:       db.hash,
+		customHash: db.customHash,
+		writer:     file,
+		opts:       buildOptions(opts),
+		entries:    entries,
+		records:    records,
+		metadata:   db.metadata,
+	}
+
+	// Iterating depends on every record being padded the same way, and
+	// encoded in the same byte order, so the database keeps the alignment
+	// and byte order it was written with, whatever the options say.
+	w.opts.alignment = db.opts.alignment
+	w.opts.byteOrder = db.opts.order()
+
+	// Likewise, if the database has a header, it says how the existing values
+	// are encoded, so the new ones are encoded the same way, and the header
+	// is kept. Opening an encrypted database without WithEncryption has
+	// already failed with ErrEncrypted.
+	if db.header != nil {
+		w.opts.header = true
+		w.opts.expiry = db.opts.expiry
+		w.opts.compressor = db.opts.compressor
+	}
+
+	err = w.resetBuffer(int64(end))
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep a copy of the original tables, so that Abort can restore them.
+	w.appendOrigin = &appendOrigin{end: int64(end), records: records, metadata: db.Metadata()}
+	for i := range entries {
+		w.appendOrigin.entries[i] = append([]entry(nil), entries[i]...)
+	}
+
+	w.preallocateEntries()
+
+	w.estimatedFooterSize = w.footerSizePerEntry() * records
+	return w, nil
+}
@@ -0,0 +1,159 @@
+package cdb_test
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenForAppend(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	src, err := os.Open("./test/test.cdb")
+	require.NoError(t, err)
+	_, err = io.Copy(f, src)
+	require.NoError(t, err)
+	src.Close()
+	f.Close()
+
+	writer, err := cdb.OpenForAppend(f.Name())
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Put([]byte("appended"), []byte("value")))
+	require.NoError(t, writer.Put([]byte("foo"), []byte("shadowed")))
+	require.NoError(t, writer.Close())
+
+	db, err := cdb.Open(f.Name())
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	value, err := db.Get([]byte("appended"))
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+
+	var keys []string
+	iter := db.Iter()
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+
+	require.NoError(t, iter.Err())
+	require.Len(t, keys, len(expectedRecords)+1)
+	assert.Equal(t, []string{"appended", "foo"}, keys[len(keys)-2:])
+}
+
+func TestOpenForAppendBigEndian(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.Close()
+
+	writer, err := cdb.Create(f.Name(), cdb.WithByteOrder(binary.BigEndian))
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("old")))
+	}
+
+	require.NoError(t, writer.Close())
+
+	writer, err = cdb.OpenForAppend(f.Name(), cdb.WithDetectedByteOrder())
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("appended"), []byte("new")))
+	require.NoError(t, writer.Close())
+
+	db, err := cdb.Open(f.Name(), cdb.WithByteOrder(binary.BigEndian))
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 100; i++ {
+		value, err := db.Get([]byte(strconv.Itoa(i)))
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(value))
+	}
+
+	value, err := db.Get([]byte("appended"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(value))
+
+	n := 0
+	iter := db.Iter()
+	for iter.Next() {
+		n++
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, 101, n)
+}
+
+func TestOpenForAppendHeader(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.Close()
+
+	big := strings.Repeat("compressible ", 100)
+	writer, err := cdb.Create(f.Name(), cdb.WithHeader(), cdb.WithExpiry(), cdb.WithCompression(cdb.Gzip))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("old"), []byte(big)))
+	require.NoError(t, writer.PutWithExpiry([]byte("expired"), []byte("x"), time.Now().Add(-time.Hour)))
+	require.NoError(t, writer.Close())
+
+	// None of the options are repeated.
+	writer, err = cdb.OpenForAppend(f.Name())
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("new"), []byte(big)))
+	require.NoError(t, writer.Close())
+
+	db, err := cdb.Open(f.Name())
+	require.NoError(t, err)
+	defer db.Close()
+
+	header, ok := db.Header()
+	require.True(t, ok)
+	assert.True(t, header.Expiry)
+	assert.Equal(t, cdb.Gzip.ID(), header.Compression)
+	assert.Equal(t, uint64(3), header.Records)
+	require.NoError(t, db.VerifyHeader())
+
+	for _, key := range []string{"old", "new"} {
+		value, err := db.Get([]byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, big, string(value), key)
+	}
+
+	value, err := db.Get([]byte("expired"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestOpenForAppendEncrypted(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.Close()
+
+	writer, err := cdb.Create(f.Name(), cdb.WithHeader(), cdb.WithEncryption(newAEAD(t, "0123456789abcdef")))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Close())
+
+	_, err = cdb.OpenForAppend(f.Name())
+	assert.Equal(t, cdb.ErrEncrypted, err)
+}
@@ -0,0 +1,324 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// An archive is a sequence of databases, followed by a directory and a
+// trailer. The directory is itself a database, mapping each name to the
+// offset and length of the member, as two little-endian uint64s. The trailer
+// is the offset of the directory, as a little-endian uint64, followed by
+// archiveMagic.
+var archiveMagic = []byte("cdbarch1")
+
+const archiveTrailerSize = 16
+
+// ErrNotArchive is returned by OpenArchive and NewArchive if the file doesn't
+// end with an archive trailer.
+var ErrNotArchive = errors.New("not a cdb archive")
+
+// ErrDuplicateMember is returned by ArchiveWriter.Create if a member with the
+// same name has already been added.
+var ErrDuplicateMember = errors.New("duplicate archive member")
+
+// Archive represents an open archive file, which stores several named
+// databases in a single file. To create one, use ArchiveWriter.
+//
+// The databases in an archive use the default hash function.
+type Archive struct {
+	reader    io.ReaderAt
+	closer    io.Closer
+	directory *CDB
+
+	// dataEnd is the offset of the directory, where the members end.
+	dataEnd int64
+}
+
+// OpenArchive opens an existing archive at the given path.
+func OpenArchive(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	archive, err := NewArchive(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	archive.closer = f
+	return archive, nil
+}
+
+// NewArchive opens an archive read from r, which is size bytes long.
+func NewArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	if size < archiveTrailerSize {
+		return nil, ErrNotArchive
+	}
+
+	trailer := make([]byte, archiveTrailerSize)
+	_, err := r.ReadAt(trailer, size-archiveTrailerSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(trailer[8:], archiveMagic) {
+		return nil, ErrNotArchive
+	}
+
+	offset := int64(binary.LittleEndian.Uint64(trailer))
+	if offset < 0 || offset > size-archiveTrailerSize {
+		return nil, ErrNotArchive
+	}
+
+	section := io.NewSectionReader(r, offset, size-archiveTrailerSize-offset)
+	directory, err := New(section, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{reader: r, directory: directory, dataEnd: offset}, nil
+}
+
+// DB opens the member database with the given name, returning
+// ErrMemberNotFound if there isn't one. The returned CDB reads from the
+// archive, so it can't be used after the archive is closed. If the directory
+// entry for the member is malformed, or points outside the archive, DB
+// returns ErrCorrupt.
+func (a *Archive) DB(name string, opts ...Option) (*CDB, error) {
+	location, err := a.directory.Get([]byte(name))
+	if err != nil {
+		return nil, err
+	} else if location == nil {
+		return nil, ErrMemberNotFound
+	}
+
+	if len(location) != 16 {
+		return nil, ErrCorrupt
+	}
+
+	// Members are stored before the directory.
+	offset := binary.LittleEndian.Uint64(location)
+	length := binary.LittleEndian.Uint64(location[8:])
+	if offset > uint64(a.dataEnd) || length > uint64(a.dataEnd)-offset {
+		return nil, ErrCorrupt
+	}
+
+	return New(io.NewSectionReader(a.reader, int64(offset), int64(length)), nil, opts...)
+}
+
+// Names returns the names of the databases in the archive, in the order they
+// were added.
+func (a *Archive) Names() ([]string, error) {
+	var names []string
+	iter := a.directory.Iter()
+	for iter.Next() {
+		names = append(names, string(iter.Key()))
+	}
+
+	return names, iter.Err()
+}
+
+// Close closes the archive. If it was opened with OpenArchive, this closes
+// the underlying file.
+func (a *Archive) Close() error {
+	a.directory.Close()
+	if a.closer != nil {
+		return a.closer.Close()
+	}
+
+	return nil
+}
+
+// ArchiveWriter creates an archive, database by database.
+//
+// Close must be called to write the directory, or the resulting file will be
+// invalid.
+type ArchiveWriter struct {
+	writer  io.WriteSeeker
+	names   map[string]bool
+	members [][]byte
+	current *memberWriter
+	end     int64
+}
+
+// CreateArchive creates an archive at the given path. If the file exists, it
+// will be overwritten.
+func CreateArchive(path string) (*ArchiveWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewArchiveWriter(f), nil
+}
+
+// NewArchiveWriter creates an archive, writing it to the given
+// io.WriteSeeker.
+func NewArchiveWriter(writer io.WriteSeeker) *ArchiveWriter {
+	return &ArchiveWriter{writer: writer, names: make(map[string]bool)}
+}
+
+// Create adds a database with the given name to the archive, and returns a
+// Writer for it. The Writer must be closed before the next call to Create or
+// Close. Freeze and Delete are supported if the underlying stream is an
+// io.ReaderAt.
+func (aw *ArchiveWriter) Create(name string, opts ...Option) (*Writer, error) {
+	if aw.names[name] {
+		return nil, ErrDuplicateMember
+	}
+
+	aw.finishMember()
+	aw.names[name] = true
+	aw.current = &memberWriter{writer: aw.writer, base: aw.end, name: name}
+
+	var stream io.WriteSeeker = aw.current
+	if _, ok := aw.writer.(io.ReaderAt); ok {
+		stream = memberReadWriter{aw.current}
+	}
+
+	return NewWriter(stream, nil, opts...)
+}
+
+// finishMember records the location of the last member created, if any.
+func (aw *ArchiveWriter) finishMember() {
+	if aw.current == nil {
+		return
+	}
+
+	location := make([]byte, 16)
+	binary.LittleEndian.PutUint64(location, uint64(aw.current.base))
+	binary.LittleEndian.PutUint64(location[8:], uint64(aw.current.end))
+	aw.members = append(aw.members, append([]byte(aw.current.name), location...))
+
+	aw.end += aw.current.end
+	aw.current = nil
+}
+
+// Close writes out the directory and trailer, then closes the underlying
+// stream if it's an io.Closer.
+func (aw *ArchiveWriter) Close() error {
+	aw.finishMember()
+
+	directory := &memberWriter{writer: aw.writer, base: aw.end}
+	w, err := NewWriter(directory, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range aw.members {
+		split := len(member) - 16
+		err = w.Put(member[:split], member[split:])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = aw.writer.Seek(aw.end+directory.end, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	trailer := make([]byte, 8, archiveTrailerSize)
+	binary.LittleEndian.PutUint64(trailer, uint64(aw.end))
+	_, err = aw.writer.Write(append(trailer, archiveMagic...))
+	if err != nil {
+		return err
+	}
+
+	if closer, ok := aw.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// memberWriter presents the part of an archive starting at base as a stream
+// of its own, and keeps track of how much of it has been written.
+type memberWriter struct {
+	writer io.WriteSeeker
+	base   int64
+	pos    int64
+	end    int64
+	name   string
+}
+
+func (mw *memberWriter) Write(b []byte) (int, error) {
+	n, err := mw.writer.Write(b)
+	mw.pos += int64(n)
+	if mw.pos > mw.end {
+		mw.end = mw.pos
+	}
+
+	return n, err
+}
+
+func (mw *memberWriter) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += mw.pos
+	case io.SeekEnd:
+		offset += mw.end
+	default:
+		return 0, os.ErrInvalid
+	}
+
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+
+	_, err := mw.writer.Seek(mw.base+offset, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	mw.pos = offset
+	return offset, nil
+}
+
+// Truncate sets the end of the member, for a Writer that shrinks its data
+// section when it deletes or regroups records. The member is the last thing
+// in the archive so far, so the underlying stream is truncated too, if it
+// can be.
+func (mw *memberWriter) Truncate(size int64) error {
+	if size < 0 {
+		return os.ErrInvalid
+	}
+
+	if truncater, ok := mw.writer.(interface{ Truncate(int64) error }); ok {
+		err := truncater.Truncate(mw.base + size)
+		if err != nil {
+			return err
+		}
+	}
+
+	mw.end = size
+	return nil
+}
+
+// memberReadWriter is a memberWriter for an underlying stream that supports
+// reads, so that Freeze and Delete work.
+type memberReadWriter struct {
+	*memberWriter
+}
+
+func (mrw memberReadWriter) ReadAt(b []byte, off int64) (int, error) {
+	return mrw.writer.(io.ReaderAt).ReadAt(b, mrw.base+off)
+}
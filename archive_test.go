@@ -0,0 +1,163 @@
+package cdb_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchive(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb-archive")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	aw := cdb.NewArchiveWriter(f)
+
+	w, err := aw.Create("users")
+	require.NoError(t, err)
+	require.NoError(t, w.Put([]byte("alice"), []byte("1")))
+	require.NoError(t, w.Put([]byte("bob"), []byte("2")))
+	require.NoError(t, w.Close())
+
+	_, err = aw.Create("users")
+	assert.Equal(t, cdb.ErrDuplicateMember, err)
+
+	w, err = aw.Create("test")
+	require.NoError(t, err)
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, w.Put(record[0], record[1]))
+		}
+	}
+
+	// Frozen members should be readable right away.
+	frozen, err := w.Freeze()
+	require.NoError(t, err)
+	testArchivedGet(t, frozen)
+
+	w, err = aw.Create("empty")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, aw.Close())
+
+	archive, err := cdb.OpenArchive(f.Name())
+	require.NoError(t, err)
+	defer archive.Close()
+
+	names, err := archive.Names()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users", "test", "empty"}, names)
+
+	users, err := archive.DB("users")
+	require.NoError(t, err)
+	value, err := users.Get([]byte("bob"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(value))
+
+	db, err := archive.DB("test")
+	require.NoError(t, err)
+	testArchivedGet(t, db)
+
+	empty, err := archive.DB("empty")
+	require.NoError(t, err)
+	n, err := empty.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = archive.DB("missing")
+	assert.Equal(t, cdb.ErrMemberNotFound, err)
+}
+
+func TestOpenArchiveNotArchive(t *testing.T) {
+	_, err := cdb.OpenArchive("./test/test.cdb")
+	assert.Equal(t, cdb.ErrNotArchive, err)
+}
+
+func TestArchiveCorruptDirectory(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb-archive")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	// Write a directory with bad entries by hand, with no members before it.
+	outside := make([]byte, 16)
+	binary.LittleEndian.PutUint64(outside[8:], 1000)
+
+	w, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.Put([]byte("short"), []byte("1234")))
+	require.NoError(t, w.Put([]byte("outside"), outside))
+	_, err = w.Freeze()
+	require.NoError(t, err)
+
+	_, err = f.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	_, err = f.Write(append(make([]byte, 8), "cdbarch1"...))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	archive, err := cdb.OpenArchive(f.Name())
+	require.NoError(t, err)
+	defer archive.Close()
+
+	_, err = archive.DB("short")
+	assert.Equal(t, cdb.ErrCorrupt, err)
+
+	_, err = archive.DB("outside")
+	assert.Equal(t, cdb.ErrCorrupt, err)
+}
+
+func TestArchiveDeleteAndGroup(t *testing.T) {
+	dir := t.TempDir()
+	write := func(w *cdb.Writer) {
+		for i := 0; i < 100; i++ {
+			require.NoError(t, w.Put([]byte(fmt.Sprintf("key%d", i)), bytes.Repeat([]byte("x"), 100)))
+		}
+
+		for i := 0; i < 50; i++ {
+			require.NoError(t, w.Delete([]byte(fmt.Sprintf("key%d", i))))
+		}
+
+		require.NoError(t, w.Close())
+	}
+
+	// Write the same database on its own, to compare its size.
+	standalone := filepath.Join(dir, "standalone.cdb")
+	w, err := cdb.Create(standalone, cdb.WithGrouping(cdb.GroupByKey))
+	require.NoError(t, err)
+	write(w)
+
+	info, err := os.Stat(standalone)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "archive")
+	aw, err := cdb.CreateArchive(path)
+	require.NoError(t, err)
+	w, err = aw.Create("member", cdb.WithGrouping(cdb.GroupByKey))
+	require.NoError(t, err)
+	write(w)
+	require.NoError(t, aw.Close())
+
+	// The directory starts where the only member ends.
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.EqualValues(t, info.Size(), binary.LittleEndian.Uint64(data[len(data)-16:]))
+
+	archive, err := cdb.OpenArchive(path)
+	require.NoError(t, err)
+	defer archive.Close()
+
+	db, err := archive.DB("member")
+	require.NoError(t, err)
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 50, n)
+}
@@ -0,0 +1,173 @@
+package cdb
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrWriterClosed is returned by AsyncWriter.Put and Flush once the
+// AsyncWriter has been closed.
+var ErrWriterClosed = errors.New("writer is closed")
+
+// AsyncWriter wraps a Writer, so that records can be added from any number of
+// goroutines without waiting on disk I/O. Put copies the record onto a
+// bounded queue, and a background goroutine writes the records out in the
+// order they were queued. Once the queue is full, Put blocks until there's
+// room, so a slow disk slows producers down rather than using unbounded
+// memory.
+//
+// If a write fails, the error is sent on Errors, and returned by every
+// subsequent call to Put, Flush, and Close; records queued after the failure
+// are discarded. Because the background goroutine stops at the first failure,
+// the error reported is always the first one, in queue order.
+type AsyncWriter struct {
+	writer *Writer
+	queue  chan asyncOp
+	errs   chan error
+	done   chan struct{}
+
+	// mu guards closed, and is held by Put while it waits for room in the
+	// queue, so that Close can't close the queue out from under it.
+	mu     sync.RWMutex
+	closed bool
+
+	errOnce sync.Once
+	errMu   sync.Mutex
+	err     error
+}
+
+// asyncOp is a record to write, or, if flushed is set, a request to flush the
+// records before it.
+type asyncOp struct {
+	key, value []byte
+	flushed    chan error
+}
+
+// NewAsyncWriter starts a background goroutine writing to w, with a queue of
+// up to queueSize records. If queueSize is less than 1, it defaults to 1024.
+// The Writer must not be used directly until the AsyncWriter is closed.
+func NewAsyncWriter(w *Writer, queueSize int) *AsyncWriter {
+	if queueSize < 1 {
+		queueSize = 1024
+	}
+
+	aw := &AsyncWriter{
+		writer: w,
+		queue:  make(chan asyncOp, queueSize),
+		errs:   make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+
+	go aw.run()
+	return aw
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.done)
+	for op := range aw.queue {
+		failed := aw.failed()
+		switch {
+		case op.flushed != nil && failed != nil:
+			op.flushed <- failed
+		case op.flushed != nil:
+			err := aw.writer.Sync()
+			if err != nil {
+				aw.fail(err)
+			}
+
+			op.flushed <- err
+		case failed == nil:
+			err := aw.writer.Put(op.key, op.value)
+			if err != nil {
+				aw.fail(err)
+			}
+		}
+	}
+}
+
+// fail records the first error, and sends it on the error channel.
+func (aw *AsyncWriter) fail(err error) {
+	aw.errOnce.Do(func() {
+		aw.errMu.Lock()
+		aw.err = err
+		aw.errMu.Unlock()
+
+		aw.errs <- err
+	})
+}
+
+func (aw *AsyncWriter) failed() error {
+	aw.errMu.Lock()
+	defer aw.errMu.Unlock()
+
+	return aw.err
+}
+
+// Put queues a key/value pair to be added to the database. The key and value
+// are copied, so the caller is free to reuse them once Put returns. If an
+// earlier write failed, Put returns that error instead.
+func (aw *AsyncWriter) Put(key, value []byte) error {
+	op := asyncOp{
+		key:   append([]byte(nil), key...),
+		value: append([]byte(nil), value...),
+	}
+
+	return aw.enqueue(op)
+}
+
+func (aw *AsyncWriter) enqueue(op asyncOp) error {
+	aw.mu.RLock()
+	defer aw.mu.RUnlock()
+
+	if err := aw.failed(); err != nil {
+		return err
+	} else if aw.closed {
+		return ErrWriterClosed
+	}
+
+	aw.queue <- op
+	return nil
+}
+
+// Errors returns a channel that receives the first error encountered by the
+// background goroutine, if there is one. It's never closed.
+func (aw *AsyncWriter) Errors() <-chan error {
+	return aw.errs
+}
+
+// Flush waits for every record queued so far to be written to the underlying
+// stream, then syncs it as Writer.Sync does, and returns the first error
+// encountered, if any.
+func (aw *AsyncWriter) Flush() error {
+	flushed := make(chan error, 1)
+	err := aw.enqueue(asyncOp{flushed: flushed})
+	if err != nil {
+		return err
+	}
+
+	return <-flushed
+}
+
+// Close waits for the queued records to be written, then closes the
+// underlying Writer, finalizing the database. It returns the first error
+// encountered by the background goroutine, or else the error from closing the
+// Writer. If a write failed, the Writer is left open, and the database is not
+// finalized.
+func (aw *AsyncWriter) Close() error {
+	aw.mu.Lock()
+	if aw.closed {
+		aw.mu.Unlock()
+		return ErrWriterClosed
+	}
+
+	aw.closed = true
+	close(aw.queue)
+	aw.mu.Unlock()
+
+	<-aw.done
+	if err := aw.failed(); err != nil {
+		return err
+	}
+
+	return aw.writer.Close()
+}
@@ -0,0 +1,70 @@
+package cdb_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "async.cdb")
+	writer, err := cdb.Create(path)
+	require.NoError(t, err)
+
+	aw := cdb.NewAsyncWriter(writer, 16)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				key := []byte(fmt.Sprintf("key%d-%d", g, i))
+				assert.NoError(t, aw.Put(key, key))
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	require.NoError(t, aw.Flush())
+	require.NoError(t, aw.Close())
+	assert.Equal(t, cdb.ErrWriterClosed, aw.Put([]byte("foo"), nil))
+
+	db, err := cdb.Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 800, n)
+
+	value, err := db.Get([]byte("key3-42"))
+	require.NoError(t, err)
+	assert.Equal(t, "key3-42", string(value))
+}
+
+func TestAsyncWriterError(t *testing.T) {
+	errBad := errors.New("bad key")
+	writer := cdb.NewMem(cdb.WithValidator(func(key, value []byte) error {
+		if string(key) == "bad" {
+			return errBad
+		}
+
+		return nil
+	}))
+
+	aw := cdb.NewAsyncWriter(writer, 1)
+	require.NoError(t, aw.Put([]byte("good"), nil))
+	require.NoError(t, aw.Put([]byte("bad"), nil))
+
+	assert.Equal(t, errBad, <-aw.Errors())
+	assert.Equal(t, errBad, aw.Put([]byte("good"), nil))
+	assert.Equal(t, errBad, aw.Flush())
+	assert.Equal(t, errBad, aw.Close())
+}
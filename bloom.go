@@ -0,0 +1,112 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The most hash functions a bloom filter will use.
+const maxBloomHashes = 30
+
+// WithBloomFilter causes a Writer to build a bloom filter over every key when
+// the database is finalized, using bitsPerKey bits of space per record;
+// values around 10 give a false positive rate of about 1%. The filter is
+// stored after the hash tables, where other cdb implementations ignore it.
+//
+// When a database with a bloom filter is opened, the filter is read into
+// memory and checked before each lookup, so most lookups for missing keys
+// don't read from the file at all. This requires the size of the underlying
+// io.ReaderAt to be known, which it is for files and anything with a Size
+// method; otherwise, the filter is ignored.
+//
+// The filter is built from the hashes of the keys rather than the keys
+// themselves, so it adds no false negatives, and only negligibly to the false
+// positive rate, as long as the hash function is reasonably good.
+func WithBloomFilter(bitsPerKey int) Option {
+	return func(o *options) {
+		o.bloomBitsPerKey = bitsPerKey
+	}
+}
+
+// bloomFilter is a bloom filter over key hashes. It's stored as the number of
+// hash functions, as a little-endian uint32, followed by the bits.
+type bloomFilter struct {
+	hashes uint32
+	bits   []byte
+}
+
+// buildBloom returns a serialized bloom filter over the hashes of all the
+// records.
+func (cdb *Writer) buildBloom() []byte {
+	bitsPerKey := cdb.opts.bloomBitsPerKey
+
+	hashes := uint32(math.Round(float64(bitsPerKey) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	} else if hashes > maxBloomHashes {
+		hashes = maxBloomHashes
+	}
+
+	data := make([]byte, bloomSize(cdb.records, bitsPerKey))
+	binary.LittleEndian.PutUint32(data, hashes)
+	filter := bloomFilter{hashes: hashes, bits: data[4:]}
+	for _, entries := range cdb.entries {
+		for _, entry := range entries {
+			filter.add(entry.hash)
+		}
+	}
+
+	return data
+}
+
+// bloomSize returns the size of a serialized bloom filter.
+func bloomSize(records int64, bitsPerKey int) int64 {
+	nbits := records * int64(bitsPerKey)
+	if nbits < 64 {
+		nbits = 64
+	}
+
+	return 4 + (nbits+7)/8
+}
+
+func parseBloom(data []byte) (*bloomFilter, error) {
+	if len(data) < 5 {
+		return nil, ErrCorrupt
+	}
+
+	hashes := binary.LittleEndian.Uint32(data)
+	if hashes < 1 || hashes > maxBloomHashes {
+		return nil, ErrCorrupt
+	}
+
+	return &bloomFilter{hashes: hashes, bits: data[4:]}, nil
+}
+
+// positions calls fn with each of the bits for a hash, using double hashing.
+func (bf *bloomFilter) positions(hash uint32, fn func(bit uint64) bool) {
+	h1, h2 := uint64(fmix32(hash)), uint64(fmix32(hash^0x9e3779b9)|1)
+	nbits := uint64(len(bf.bits)) * 8
+	for i := uint64(0); i < uint64(bf.hashes); i++ {
+		if !fn((h1 + i*h2) % nbits) {
+			return
+		}
+	}
+}
+
+func (bf *bloomFilter) add(hash uint32) {
+	bf.positions(hash, func(bit uint64) bool {
+		bf.bits[bit/8] |= 1 << (bit % 8)
+		return true
+	})
+}
+
+// mayContain returns false if no key with the given hash was added.
+func (bf *bloomFilter) mayContain(hash uint32) bool {
+	found := true
+	bf.positions(hash, func(bit uint64) bool {
+		found = bf.bits[bit/8]&(1<<(bit%8)) != 0
+		return found
+	})
+
+	return found
+}
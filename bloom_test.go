@@ -0,0 +1,81 @@
+package cdb_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bloom.cdb")
+	writer, err := cdb.Create(path, cdb.WithBloomFilter(10))
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, writer.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i))))
+	}
+
+	require.NoError(t, writer.Close())
+
+	m := &testMetrics{}
+	db, err := cdb.Open(path, cdb.WithMetrics(m))
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 1000; i++ {
+		value, err := db.Get([]byte(fmt.Sprintf("key%d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("value%d", i), string(value))
+	}
+
+	m.probes, m.misses = 0, 0
+	for i := 0; i < 1000; i++ {
+		value, err := db.Get([]byte(fmt.Sprintf("missing%d", i)))
+		require.NoError(t, err)
+		assert.Nil(t, value)
+	}
+
+	assert.Equal(t, 1000, m.misses)
+	assert.True(t, m.probes < 50, "expected most misses to skip probing, got %d probes", m.probes)
+
+	buf := make([]byte, 16)
+	_, found, err := db.GetInto([]byte("missing"), buf)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	n, found, err := db.GetInto([]byte("key1"), buf)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value1", string(buf[:n]))
+}
+
+func TestBloomFilterFreeze(t *testing.T) {
+	writer := cdb.NewMem(cdb.WithBloomFilter(10))
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, writer.Put(record[0], record[1]))
+		}
+	}
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	iter := db.Iter()
+	count := 0
+	for iter.Next() {
+		count++
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, len(expectedRecords)-1, count)
+}
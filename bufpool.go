@@ -0,0 +1,81 @@
+package cdb
+
+import (
+	"sync"
+)
+
+// A BufferPool supplies the scratch buffers used for reading hash table slots,
+// record headers, and keys during lookups. Buffers are passed by pointer so
+// that they can be pooled without allocating; a buffer returned by Get may be
+// any length, and if it's too short, it's replaced with a larger one before
+// being returned to Put. Buffers aren't used after they're returned to Put,
+// and are never returned to the caller of Get or GetInto.
+//
+// A BufferPool must be safe for concurrent use.
+type BufferPool interface {
+	Get() *[]byte
+	Put(*[]byte)
+}
+
+// defaultBufferPool is the BufferPool used if one isn't set with
+// WithBufferPool.
+var defaultBufferPool BufferPool = syncBufferPool{&sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 256)
+		return &buf
+	},
+}}
+
+type syncBufferPool struct {
+	pool *sync.Pool
+}
+
+func (p syncBufferPool) Get() *[]byte {
+	return p.pool.Get().(*[]byte)
+}
+
+func (p syncBufferPool) Put(buf *[]byte) {
+	p.pool.Put(buf)
+}
+
+// WithBufferPool sets the pool that scratch buffers for lookups are taken
+// from, for callers that manage their own memory. By default, buffers come
+// from a pool shared by every database in the process, backed by a
+// sync.Pool.
+//
+// Values returned by Get and GetAll are always allocated separately, since
+// they belong to the caller; GetInto avoids that allocation as well.
+func WithBufferPool(p BufferPool) Option {
+	return func(o *options) {
+		o.bufferPool = p
+	}
+}
+
+// getScratch returns a buffer from the pool that's large enough to read a
+// header or slot into.
+func (o *options) getScratch() *[]byte {
+	pool := o.bufferPool
+	if pool == nil {
+		pool = defaultBufferPool
+	}
+
+	buf := pool.Get()
+	if buf == nil {
+		buf = new([]byte)
+	}
+
+	if cap(*buf) < 8 {
+		*buf = make([]byte, 256)
+	}
+
+	*buf = (*buf)[:cap(*buf)]
+	return buf
+}
+
+func (o *options) putScratch(buf *[]byte) {
+	if o.bufferPool != nil {
+		o.bufferPool.Put(buf)
+	} else {
+		defaultBufferPool.Put(buf)
+	}
+}
@@ -0,0 +1,98 @@
+package cdb_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingPool is a BufferPool that keeps track of its buffers.
+type countingPool struct {
+	mu   sync.Mutex
+	free []*[]byte
+	gets int
+	puts int
+}
+
+func (p *countingPool) Get() *[]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.gets++
+	if len(p.free) == 0 {
+		return new([]byte)
+	}
+
+	buf := p.free[len(p.free)-1]
+	p.free = p.free[:len(p.free)-1]
+	return buf
+}
+
+func (p *countingPool) Put(buf *[]byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.puts++
+	p.free = append(p.free, buf)
+}
+
+func TestBufferPool(t *testing.T) {
+	pool := &countingPool{}
+	db, err := cdb.Open("./test/test.cdb", cdb.WithBufferPool(pool))
+	require.NoError(t, err)
+	defer db.Close()
+
+	buf := make([]byte, 64)
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, record[1], value)
+
+		n, _, err := db.GetInto(record[0], buf)
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(buf[:n]))
+	}
+
+	values, err := db.GetAll([]byte("foo"))
+	require.NoError(t, err)
+	assert.Len(t, values, 1)
+
+	assert.Equal(t, 2*len(expectedRecords)+1, pool.gets)
+	assert.Equal(t, pool.gets, pool.puts)
+	assert.Len(t, pool.free, 1)
+}
+
+func TestGetAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations aren't predictable with the race detector")
+	}
+
+	data, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	db, err := cdb.New(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+
+	// Only the value itself should be allocated.
+	key := []byte("foo")
+	allocs := testing.AllocsPerRun(100, func() {
+		db.Get(key)
+	})
+
+	assert.Equal(t, 1.0, allocs)
+}
+
+func BenchmarkGetBufferPool(b *testing.B) {
+	db, _ := cdb.Open("./test/test.cdb", cdb.WithBufferPool(&countingPool{}))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		db.Get(expectedRecords[i%len(expectedRecords)][0])
+	}
+}
@@ -0,0 +1,60 @@
+package cdb
+
+import (
+	"encoding/binary"
+)
+
+// WithByteOrder sets the byte order of the integers in the index, hash tables,
+// and record headers. The cdb format is little-endian, which is the default,
+// but some ports write big-endian files instead; those can be read and
+// written by passing binary.BigEndian. Extensions like WithHeader and
+// WithExpiry are always stored little-endian.
+func WithByteOrder(order binary.ByteOrder) Option {
+	return func(o *options) {
+		o.byteOrder = order
+		o.detectByteOrder = false
+	}
+}
+
+// WithDetectedByteOrder causes the byte order of a database to be detected
+// when it's opened, by checking whether the index makes sense when read
+// either way. If it does both ways, which is only likely for very small
+// databases, little-endian is assumed. This option is ignored when writing.
+func WithDetectedByteOrder() Option {
+	return func(o *options) {
+		o.detectByteOrder = true
+	}
+}
+
+func (o *options) order() binary.ByteOrder {
+	if o.byteOrder == nil {
+		return binary.LittleEndian
+	}
+
+	return o.byteOrder
+}
+
+// detectByteOrder returns the byte order that the index in buf, and the
+// first record header, are valid in, given the size of the file if it's
+// known.
+func (cdb *CDB) detectByteOrder(buf []byte, size int64, sizeKnown bool) binary.ByteOrder {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		candidate := *cdb
+		candidate.index = parseIndex(buf, order)
+		candidate.opts.byteOrder = order
+		if candidate.checkIndex(size, sizeKnown) != nil {
+			continue
+		}
+
+		if candidate.index[0].offset > indexSize {
+			_, _, err := candidate.readHeader(indexSize, nil)
+			if err != nil {
+				continue
+			}
+		}
+
+		return order
+	}
+
+	return binary.LittleEndian
+}
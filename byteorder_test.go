@@ -0,0 +1,57 @@
+package cdb_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigEndian(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.cdb")
+	writer, err := cdb.Create(path, cdb.WithByteOrder(binary.BigEndian))
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, writer.Put(record[0], record[1]))
+		}
+	}
+
+	require.NoError(t, writer.Close())
+
+	for _, opt := range []cdb.Option{cdb.WithByteOrder(binary.BigEndian), cdb.WithDetectedByteOrder()} {
+		db, err := cdb.Open(path, opt)
+		require.NoError(t, err)
+
+		for _, record := range expectedRecords {
+			value, err := db.Get(record[0])
+			require.NoError(t, err)
+			assert.Equal(t, string(record[1]), string(value))
+		}
+
+		n, err := db.Len()
+		require.NoError(t, err)
+		assert.Equal(t, len(expectedRecords)-1, n)
+		require.NoError(t, db.Close())
+	}
+
+	_, err = cdb.Open(path)
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+}
+
+func TestDetectedByteOrderLittleEndian(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb", cdb.WithDetectedByteOrder())
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+}
@@ -9,20 +9,55 @@ package cdb
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"io"
-	"os"
 )
 
 const indexSize = 256 * 8
 
+// ErrNotFound is returned by GetStrict if the key can't be found.
+var ErrNotFound = errors.New("key not found")
+
 type index [256]table
 
 // CDB represents an open CDB database. It can only be used for reads; to
 // create a database, use Writer.
+//
+// A CDB is safe for concurrent use by any number of goroutines, as long as the
+// underlying io.ReaderAt is (*os.File is). It has no mutable state once it's
+// been opened, apart from counters tracking in-flight operations for
+// CloseContext: every Get and every Iterator uses its own buffers, so sharing a
+// single CDB across goroutines doesn't require any locking, and there's no
+// need to clone it per goroutine.
 type CDB struct {
 	reader io.ReaderAt
 	hash   func([]byte) uint32
 	index  index
+	opts   options
+
+	closer    io.Closer
+	lifecycle *lifecycle
+	tuning    *probeTuning
+	filter    func(key []byte) bool
+	bloom     *bloomFilter
+	header    *Header
+	records   *recordIndex
+	metadata  map[string]string
+
+	// tracing is set on the copy of a database made for a traced lookup.
+	tracing *TraceEvent
+
+	// customHash is set if the database was opened with a hash function
+	// other than the default.
+	customHash bool
+
+	// vectored is set if the underlying reader is a ReaderAtv.
+	vectored bool
+
+	// If the hash tables are pinned, tables holds the region of the file
+	// containing them, starting at tablesOffset.
+	tables       []byte
+	tablesOffset uint32
 }
 
 type table struct {
@@ -31,13 +66,19 @@ type table struct {
 }
 
 // Open opens an existing CDB database at the given path.
-func Open(path string) (*CDB, error) {
-	f, err := os.Open(path)
+func Open(path string, opts ...Option) (*CDB, error) {
+	f, err := openReadFile(path, buildOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := New(f, nil, opts...)
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
 
-	return New(f, nil)
+	return db, nil
 }
 
 // New opens a new CDB instance for the given io.ReaderAt. It can only be used
@@ -47,13 +88,14 @@ func Open(path string) (*CDB, error) {
 // If hash is nil, it will default to the CDB hash function. If a database
 // was created with a particular hash function, that same hash function must be
 // passed to New, or the database will return incorrect results.
-func New(reader io.ReaderAt, hash func([]byte) uint32) (*CDB, error) {
+func New(reader io.ReaderAt, hash func([]byte) uint32, opts ...Option) (*CDB, error) {
+	customHash := hash != nil
 	if hash == nil {
 		hash = cdbHash
 	}
 
-	cdb := &CDB{reader: reader, hash: hash}
-	err := cdb.readIndex()
+	cdb := &CDB{reader: reader, hash: hash, opts: buildOptions(opts), customHash: customHash}
+	err := cdb.init(nil)
 	if err != nil {
 		return nil, err
 	}
@@ -61,76 +103,353 @@ func New(reader io.ReaderAt, hash func([]byte) uint32) (*CDB, error) {
 	return cdb, nil
 }
 
+// init finishes setting up a database, and reads the index from the file if
+// one isn't provided.
+func (cdb *CDB) init(index *index) error {
+	cdb.lifecycle = &lifecycle{}
+	cdb.records = &recordIndex{}
+	size, sizeKnown := readerSize(cdb.reader)
+	if closer, ok := cdb.reader.(io.Closer); ok {
+		cdb.closer = closer
+	}
+
+	_, cdb.vectored = cdb.reader.(ReaderAtv)
+	if cdb.opts.slowRead != nil {
+		cdb.reader = slowReader{cdb.reader, cdb.opts.slowReadThreshold, cdb.opts.slowRead}
+	}
+
+	if cdb.opts.metrics != nil {
+		cdb.reader = metricsReader{cdb.reader, cdb.opts.metrics}
+	}
+
+	if cdb.opts.probeWindow == AutoProbeWindow {
+		cdb.tuning = &probeTuning{}
+	}
+
+	if index != nil {
+		cdb.index = *index
+	} else {
+		err := cdb.readIndex(size, sizeKnown)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := cdb.checkIndex(size, sizeKnown)
+	if err != nil {
+		return err
+	}
+
+	err = cdb.readExtensions(size, sizeKnown)
+	if err != nil {
+		return err
+	}
+
+	if cdb.opts.recordIndex {
+		_, err := cdb.recordOffsets()
+		if err != nil {
+			return err
+		}
+	}
+
+	if cdb.opts.pinTables {
+		err := cdb.pinTables()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Get returns the value for a given key, or nil if it can't be found.
 func (cdb *CDB) Get(key []byte) ([]byte, error) {
-	hash := cdb.hash(key)
+	key = cdb.opts.normalizeKey(key)
+	return cdb.get(cdb.hash(key), key)
+}
+
+// GetHashed is like Get, but uses a hash for the key computed in advance,
+// for callers that already have it, such as from routing the key to a shard.
+// The hash must be the one the database's hash function returns for the key,
+// after any normalization applied by WithKeyNormalizer; otherwise, the key
+// won't be found.
+func (cdb *CDB) GetHashed(hash uint32, key []byte) ([]byte, error) {
+	return cdb.get(hash, cdb.opts.normalizeKey(key))
+}
+
+func (cdb *CDB) get(hash uint32, key []byte) ([]byte, error) {
+	if cdb.opts.trace != nil && cdb.tracing == nil {
+		var value []byte
+		err := cdb.traceLookup(hash, key, func(db *CDB) (err error) {
+			value, err = db.get(hash, key)
+			return err
+		})
 
-	table := cdb.index[hash&0xff]
-	if table.length == 0 {
+		return value, err
+	} else if cdb.hidden(key) {
 		return nil, nil
 	}
 
-	// Probe the given hash table, starting at the given slot.
-	startingSlot := (hash >> 8) % table.length
-	slot := startingSlot
+	err := cdb.acquire(opGet)
+	if err != nil {
+		return nil, err
+	}
+	defer cdb.release(opGet)
+
+	value, _, err := cdb.lookup(hash, key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	expired := cdb.hasExpired(value)
+	value, err = cdb.decodeValue(key, value)
+	if err == nil && expired {
+		err = ErrExpired
+	}
+
+	return value, err
+}
+
+// GetStrict returns the value for a given key, or ErrNotFound if it can't be
+// found. Unlike Get, this distinguishes a missing key from one with an empty
+// value without a second lookup: the value for a key that exists is never
+// nil.
+func (cdb *CDB) GetStrict(key []byte) ([]byte, error) {
+	value, err := cdb.Get(key)
+	if err == ErrExpired {
+		return value, err
+	} else if err != nil {
+		return nil, err
+	} else if value == nil {
+		return nil, ErrNotFound
+	}
+
+	return value, nil
+}
+
+// GetAll returns every value stored for a given key, in the order they were
+// written, or nil if there aren't any. Databases can hold several records with
+// the same key, but Get only ever returns the first.
+func (cdb *CDB) GetAll(key []byte) ([][]byte, error) {
+	var values [][]byte
+	err := cdb.Scan(key, func(value []byte) (bool, error) {
+		values = append(values, value)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Scan calls visit with every value stored for a given key, in the order they
+// were written, like GetAll, but without holding on to all of them at once.
+// Each value is allocated separately, so visit may keep it. Scan stops early
+// if visit returns true or an error, and returns the error.
+func (cdb *CDB) Scan(key []byte, visit func(value []byte) (stop bool, err error)) error {
+	if cdb.opts.trace != nil && cdb.tracing == nil {
+		original, normalized := key, cdb.opts.normalizeKey(key)
+		return cdb.traceLookup(cdb.hash(normalized), normalized, func(db *CDB) error {
+			return db.Scan(original, visit)
+		})
+	}
+
+	key = cdb.opts.normalizeKey(key)
+	if cdb.hidden(key) {
+		return nil
+	}
+
+	err := cdb.acquire(opGet)
+	if err != nil {
+		return err
+	}
+	defer cdb.release(opGet)
+
+	hash := cdb.hash(key)
+	p := cdb.newProbe(hash)
+	if cdb.bloom != nil && !cdb.bloom.mayContain(hash) {
+		cdb.observeGet(false, &p)
+		return nil
+	}
+
+	scratch := cdb.opts.getScratch()
+	defer cdb.opts.putScratch(scratch)
 
+	p.scratch = *scratch
+	found := false
 	for {
-		slotOffset := table.offset + (8 * slot)
-		slotHash, offset, err := readTuple(cdb.reader, slotOffset)
+		offset, ok, err := p.next()
 		if err != nil {
-			return nil, err
+			return err
+		} else if !ok {
+			break
 		}
 
-		// An empty slot means the key doesn't exist.
-		if slotHash == 0 {
-			break
-		} else if slotHash == hash {
-			value, err := cdb.getValueAt(offset, key)
-			if err != nil {
-				return nil, err
-			} else if value != nil {
-				return value, nil
-			}
+		cdb.traceRecord(offset)
+		value, err := cdb.getValueAt(offset, key, *scratch)
+		if err != nil {
+			return err
+		} else if value == nil || cdb.expired(value) {
+			continue
 		}
 
-		slot = (slot + 1) % table.length
-		if slot == startingSlot {
-			break
+		value, err = cdb.decodeValue(key, value)
+		if err != nil {
+			return err
+		}
+
+		found = true
+		stop, err := visit(value)
+		if err != nil || stop {
+			cdb.observeGet(true, &p)
+			return err
 		}
 	}
 
-	return nil, nil
+	cdb.observeGet(found, &p)
+	return nil
 }
 
-// Close closes the database to further reads.
-func (cdb *CDB) Close() error {
-	if closer, ok := cdb.reader.(io.Closer); ok {
-		return closer.Close()
-	} else {
-		return nil
+// lookup finds the first record for a given key and its hash, and returns its
+// value, as stored, and offset.
+func (cdb *CDB) lookup(hash uint32, key []byte) ([]byte, uint32, error) {
+	p := cdb.newProbe(hash)
+	if cdb.bloom != nil && !cdb.bloom.mayContain(hash) {
+		cdb.observeGet(false, &p)
+		return nil, 0, nil
+	}
+
+	if cdb.vectored {
+		return cdb.lookupVectored(hash, key)
+	}
+
+	scratch := cdb.opts.getScratch()
+	defer cdb.opts.putScratch(scratch)
+
+	p.scratch = *scratch
+	for {
+		offset, ok, err := p.next()
+		if err != nil {
+			return nil, 0, err
+		} else if !ok {
+			break
+		}
+
+		cdb.traceRecord(offset)
+		value, err := cdb.getValueAt(offset, key, *scratch)
+		if err != nil {
+			return nil, 0, err
+		} else if value != nil && !cdb.expired(value) {
+			p.finish()
+			cdb.observeGet(true, &p)
+			return value, offset, nil
+		}
 	}
+
+	cdb.observeGet(false, &p)
+	return nil, 0, nil
 }
 
-func (cdb *CDB) readIndex() error {
+// readIndex reads the index from the start of the file. The size of the
+// file, if it's known, is used to detect the byte order.
+func (cdb *CDB) readIndex(size int64, sizeKnown bool) error {
 	buf := make([]byte, indexSize)
 	_, err := cdb.reader.ReadAt(buf, 0)
 	if err != nil {
-		return err
+		return readError(ErrCorruptIndex, 0, err)
 	}
 
+	if cdb.opts.detectByteOrder {
+		cdb.opts.byteOrder = cdb.detectByteOrder(buf, size, sizeKnown)
+	}
+
+	cdb.index = parseIndex(buf, cdb.opts.order())
+	return nil
+}
+
+func parseIndex(buf []byte, order binary.ByteOrder) index {
+	var index index
 	for i := 0; i < 256; i++ {
 		off := i * 8
-		cdb.index[i] = table{
-			offset: binary.LittleEndian.Uint32(buf[off : off+4]),
-			length: binary.LittleEndian.Uint32(buf[off+4 : off+8]),
+		index[i] = table{
+			offset: order.Uint32(buf[off : off+4]),
+			length: order.Uint32(buf[off+4 : off+8]),
+		}
+	}
+
+	return index
+}
+
+// TablesSize returns the size of the region of the file containing the hash
+// tables, which is also the amount of memory used by WithPinnedTables.
+func (cdb *CDB) TablesSize() int64 {
+	start, end := cdb.tablesRegion()
+	return int64(end) - int64(start)
+}
+
+// tablesRegion returns the start and end offsets of the hash tables.
+func (cdb *CDB) tablesRegion() (uint32, uint32) {
+	start, end := cdb.index[0].offset, cdb.index[0].offset
+	for _, table := range cdb.index {
+		if table.offset < start {
+			start = table.offset
+		}
+
+		if tableEnd := table.offset + (8 * table.length); tableEnd > end {
+			end = tableEnd
 		}
 	}
 
+	return start, end
+}
+
+func (cdb *CDB) pinTables() error {
+	start, end := cdb.tablesRegion()
+	buf := make([]byte, end-start)
+	_, err := cdb.reader.ReadAt(buf, int64(start))
+	if err != nil {
+		return readError(ErrCorruptIndex, int64(start), err)
+	}
+
+	cdb.tables = buf
+	cdb.tablesOffset = start
 	return nil
 }
 
-func (cdb *CDB) getValueAt(offset uint32, expectedKey []byte) ([]byte, error) {
-	keyLength, valueLength, err := readTuple(cdb.reader, offset)
+// readTables fills buf with the hash table data at offset, from memory if the
+// tables are pinned.
+func (cdb *CDB) readTables(buf []byte, offset uint32) error {
+	if cdb.tables != nil {
+		copy(buf, cdb.tables[offset-cdb.tablesOffset:])
+		return nil
+	}
+
+	_, err := cdb.reader.ReadAt(buf, int64(offset))
+	return readError(ErrCorruptIndex, int64(offset), err)
+}
+
+// readSlot reads the hash table slot at offset. If scratch is not nil, it's
+// used as the buffer for the read.
+func (cdb *CDB) readSlot(offset uint32, scratch []byte) (uint32, uint32, error) {
+	if cdb.tables != nil {
+		slot := cdb.tables[offset-cdb.tablesOffset:]
+		order := cdb.opts.order()
+		return order.Uint32(slot), order.Uint32(slot[4:]), nil
+	}
+
+	if scratch == nil {
+		scratch = make([]byte, 8)
+	}
+
+	hash, recordOffset, err := readTupleInto(cdb.reader, cdb.opts.order(), offset, scratch[:8])
+	return hash, recordOffset, readError(ErrCorruptIndex, int64(offset), err)
+}
+
+// getValueAt returns the value of the record at offset, if its key matches.
+// If scratch is not nil, it's used as the buffer for reading the header.
+func (cdb *CDB) getValueAt(offset uint32, expectedKey, scratch []byte) ([]byte, error) {
+	keyLength, valueLength, err := cdb.readHeader(offset, scratch)
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +462,7 @@ func (cdb *CDB) getValueAt(offset uint32, expectedKey []byte) ([]byte, error) {
 	buf := make([]byte, keyLength+valueLength)
 	_, err = cdb.reader.ReadAt(buf, int64(offset+8))
 	if err != nil {
-		return nil, err
+		return nil, readError(ErrCorruptRecord, int64(offset), err)
 	}
 
 	// If they keys don't match, this isn't it.
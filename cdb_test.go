@@ -1,10 +1,13 @@
 package cdb_test
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"math/rand"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -58,8 +61,37 @@ func TestClosesFile(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestConcurrentReads(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb", cdb.WithPinnedTables())
+	require.NoError(t, err)
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				record := expectedRecords[n%len(expectedRecords)]
+				value, err := db.Get(record[0])
+				assert.NoError(t, err)
+				assert.Equal(t, string(record[1]), string(value))
+			}
+
+			iter := db.Iter()
+			for iter.Next() {
+			}
+
+			assert.NoError(t, iter.Err())
+		}()
+	}
+
+	wg.Wait()
+}
+
 func BenchmarkGet(b *testing.B) {
 	db, _ := cdb.Open("./test/test.cdb")
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	rand.Seed(time.Now().UnixNano())
@@ -69,6 +101,20 @@ func BenchmarkGet(b *testing.B) {
 	}
 }
 
+func BenchmarkGetParallel(b *testing.B) {
+	db, _ := cdb.Open("./test/test.cdb")
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			db.Get(expectedRecords[i%len(expectedRecords)][0])
+			i++
+		}
+	})
+}
+
 func Example() {
 	writer, err := cdb.Create("/tmp/example.cdb")
 	if err != nil {
@@ -119,3 +165,121 @@ func shuffle(a [][][]byte) {
 		a[i], a[j] = a[j], a[i]
 	}
 }
+
+func TestGetStrict(t *testing.T) {
+	db := makeDB(t, [][2]string{{"foo", "bar"}, {"empty", ""}})
+
+	value, err := db.GetStrict([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(value))
+
+	value, err = db.GetStrict([]byte("empty"))
+	require.NoError(t, err)
+	assert.NotNil(t, value)
+	assert.Empty(t, value)
+
+	value, err = db.GetStrict([]byte("missing"))
+	assert.Equal(t, cdb.ErrNotFound, err)
+	assert.Nil(t, value)
+}
+
+func TestGetAll(t *testing.T) {
+	writer := cdb.NewMem()
+	require.NoError(t, writer.Put([]byte("foo"), []byte("1")))
+	require.NoError(t, writer.Put([]byte("bar"), []byte("x")))
+	require.NoError(t, writer.Put([]byte("foo"), []byte("2")))
+	require.NoError(t, writer.Put([]byte("foo"), []byte("")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	values, err := db.GetAll([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("1"), []byte("2"), []byte("")}, values)
+
+	values, err = db.GetAll([]byte("missing"))
+	require.NoError(t, err)
+	assert.Nil(t, values)
+}
+
+func TestScan(t *testing.T) {
+	writer := cdb.NewMem()
+	require.NoError(t, writer.Put([]byte("foo"), []byte("1")))
+	require.NoError(t, writer.Put([]byte("bar"), []byte("x")))
+	require.NoError(t, writer.Put([]byte("foo"), []byte("2")))
+	require.NoError(t, writer.Put([]byte("foo"), []byte("3")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	var values []string
+	err = db.Scan([]byte("foo"), func(value []byte) (bool, error) {
+		values = append(values, string(value))
+		return len(values) == 2, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, values)
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = db.Scan([]byte("foo"), func(value []byte) (bool, error) {
+		calls++
+		return false, errStop
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 1, calls)
+
+	err = db.Scan([]byte("missing"), func(value []byte) (bool, error) {
+		t.Fatal("visited a missing key")
+		return false, nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestZeroHash(t *testing.T) {
+	// This key hashes to zero with the default hash function.
+	key := []byte("7bafhhw")
+	require.Equal(t, uint32(0), cdb.HashKey(key))
+
+	writer := cdb.NewMem()
+	require.NoError(t, writer.Put(key, []byte("zero")))
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	value, err := db.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, "zero", string(value))
+
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestZeroHashCollisions(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	zero := func([]byte) uint32 { return 0 }
+	writer, err := cdb.NewWriter(f, zero)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, writer.Put([]byte(fmt.Sprint(i)), []byte(fmt.Sprint(i*i))))
+	}
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		value, err := db.Get([]byte(fmt.Sprint(i)))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(i*i), string(value))
+	}
+
+	value, err := db.Get([]byte("missing"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
@@ -0,0 +1,119 @@
+package cdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned by reads from a database that has been closed.
+var ErrClosed = errors.New("database is closed")
+
+// How often CloseContext checks whether in-flight operations have finished.
+const drainInterval = time.Millisecond
+
+// The kinds of operations tracked for CloseContext.
+const (
+	opGet = iota
+	opScan
+	numOps
+)
+
+// DrainError is returned by CloseContext if the context expired before every
+// in-flight operation finished. The database is closed regardless, and the
+// operations that were still running will fail.
+type DrainError struct {
+	// Gets is the number of lookups that were still in flight.
+	Gets int
+	// Scans is the number of scans, such as calls to Iterator.Next or Stats,
+	// that were still in flight.
+	Scans int
+	// Err is the error from the context.
+	Err error
+}
+
+func (e *DrainError) Error() string {
+	return fmt.Sprintf("closed with %d gets and %d scans in flight: %s", e.Gets, e.Scans, e.Err)
+}
+
+func (e *DrainError) Unwrap() error {
+	return e.Err
+}
+
+// lifecycle tracks in-flight operations on a database, so that it can be
+// closed cleanly. It's shared by every view of the same database.
+type lifecycle struct {
+	closed   int32
+	inflight [numOps]int64
+}
+
+// acquire registers the start of an operation, returning ErrClosed if the
+// database has been closed.
+func (cdb *CDB) acquire(op int) error {
+	// Incrementing before checking closed guarantees that CloseContext either
+	// sees this operation, or this operation sees that the database is closed.
+	atomic.AddInt64(&cdb.lifecycle.inflight[op], 1)
+	if atomic.LoadInt32(&cdb.lifecycle.closed) != 0 {
+		atomic.AddInt64(&cdb.lifecycle.inflight[op], -1)
+		return ErrClosed
+	}
+
+	return nil
+}
+
+func (cdb *CDB) release(op int) {
+	atomic.AddInt64(&cdb.lifecycle.inflight[op], -1)
+}
+
+// Close closes the database to further reads. Operations that are in flight
+// may fail; to wait for them to finish first, use CloseContext.
+func (cdb *CDB) Close() error {
+	atomic.StoreInt32(&cdb.lifecycle.closed, 1)
+	return cdb.closeReader()
+}
+
+// CloseContext closes the database to further reads, after waiting for
+// in-flight lookups and scans to finish. New operations fail with ErrClosed as
+// soon as CloseContext is called.
+//
+// If ctx expires before the in-flight operations finish, the database is
+// closed anyway, and CloseContext returns a *DrainError reporting the
+// operations that were aborted.
+func (cdb *CDB) CloseContext(ctx context.Context) error {
+	atomic.StoreInt32(&cdb.lifecycle.closed, 1)
+
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	var drainErr *DrainError
+	for drainErr == nil {
+		gets := atomic.LoadInt64(&cdb.lifecycle.inflight[opGet])
+		scans := atomic.LoadInt64(&cdb.lifecycle.inflight[opScan])
+		if gets == 0 && scans == 0 {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			drainErr = &DrainError{Gets: int(gets), Scans: int(scans), Err: ctx.Err()}
+		}
+	}
+
+	err := cdb.closeReader()
+	if drainErr != nil {
+		return drainErr
+	}
+
+	return err
+}
+
+func (cdb *CDB) closeReader() error {
+	if cdb.closer != nil {
+		return cdb.closer.Close()
+	}
+
+	return nil
+}
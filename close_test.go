@@ -0,0 +1,110 @@
+package cdb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingReader blocks reads until unblock is closed, once block is set.
+type blockingReader struct {
+	io.ReaderAt
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (br *blockingReader) ReadAt(b []byte, off int64) (int, error) {
+	if off >= cdb.DataOffset {
+		select {
+		case br.started <- struct{}{}:
+		default:
+		}
+
+		<-br.unblock
+	}
+
+	return br.ReaderAt.ReadAt(b, off)
+}
+
+func openBlocking(t *testing.T) (*cdb.CDB, *blockingReader) {
+	f, err := os.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	br := &blockingReader{ReaderAt: f, started: make(chan struct{}, 1), unblock: make(chan struct{})}
+	db, err := cdb.New(br, nil)
+	require.NoError(t, err)
+
+	return db, br
+}
+
+func TestCloseContextDrains(t *testing.T) {
+	db, br := openBlocking(t)
+
+	result := make(chan error)
+	go func() {
+		value, err := db.Get([]byte("foo"))
+		if err == nil && string(value) != "bar" {
+			err = errors.New("wrong value")
+		}
+
+		result <- err
+	}()
+
+	<-br.started
+	closed := make(chan error)
+	go func() {
+		closed <- db.CloseContext(context.Background())
+	}()
+
+	// New operations should fail immediately, while the old one is still in
+	// flight.
+	time.Sleep(10 * time.Millisecond)
+	_, err := db.Get([]byte("foo"))
+	assert.Equal(t, cdb.ErrClosed, err)
+
+	select {
+	case <-closed:
+		t.Fatal("CloseContext returned with a Get in flight")
+	default:
+	}
+
+	close(br.unblock)
+	require.NoError(t, <-result)
+	require.NoError(t, <-closed)
+}
+
+func TestCloseContextTimeout(t *testing.T) {
+	db, br := openBlocking(t)
+	defer close(br.unblock)
+
+	go db.Get([]byte("foo"))
+	<-br.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := db.CloseContext(ctx)
+	require.IsType(t, &cdb.DrainError{}, err)
+	assert.Equal(t, 1, err.(*cdb.DrainError).Gets)
+	assert.Equal(t, 0, err.(*cdb.DrainError).Scans)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClosedIterator(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	iter := db.Iter()
+	require.True(t, iter.Next())
+	require.NoError(t, db.Close())
+
+	assert.False(t, iter.Next())
+	assert.Equal(t, cdb.ErrClosed, iter.Err())
+}
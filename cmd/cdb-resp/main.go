@@ -0,0 +1,83 @@
+// Command cdb-resp serves lookups from one or more cdb databases over the
+// Redis protocol, so that existing Redis clients can read them.
+//
+// Usage:
+//
+//	cdb-resp [flags] <path> [<path> ...]
+//
+// The server is read-only, and supports GET, MGET, EXISTS, and SCAN; see the
+// github.com/colinmarc/cdb/resp package for details. When several databases
+// are given, lookups use the first one that has the key.
+//
+// Sending the process SIGHUP reopens every database, so they can be updated
+// by writing new files elsewhere and renaming them into place.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/colinmarc/cdb"
+	"github.com/colinmarc/cdb/resp"
+)
+
+func main() {
+	addr := flag.String("addr", ":6379", "address to listen on")
+	pinTables := flag.Bool("pin-tables", false, "read the hash tables into memory")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cdb-resp [flags] <path> [<path> ...]")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Args(), *addr, *pinTables); err != nil {
+		fmt.Fprintf(os.Stderr, "cdb-resp: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(paths []string, addr string, pinTables bool) error {
+	var opts []cdb.Option
+	if pinTables {
+		opts = append(opts, cdb.WithPinnedTables())
+	}
+
+	s, err := resp.NewServer(paths, opts...)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.ListenAndServe(addr)
+	}()
+
+	for {
+		select {
+		case err := <-errs:
+			s.Close()
+			return err
+		case sig := <-signals:
+			if sig != syscall.SIGHUP {
+				return s.Close()
+			}
+
+			if err := s.Reload(); err != nil {
+				log.Printf("reloading: %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Command cdb-server serves lookups from a cdb database over HTTP, so that
+// other services can read it without embedding the database themselves.
+//
+// Usage:
+//
+//	cdb-server [flags] <path>
+//
+// The database is reopened whenever the file at the path is replaced or
+// modified, so it can be updated by writing a new file elsewhere and renaming
+// it into place. The endpoints are:
+//
+//	GET /get?key=<key>
+//		The value for the key, as raw bytes, or 404 if it isn't found. With
+//		format=json, a JSON object with "key" and "value" fields.
+//	GET /exists?key=<key>
+//		200 if the key is in the database, and 404 otherwise. With
+//		format=json, a JSON object with "key" and "exists" fields.
+//	GET /each[?prefix=<prefix>]
+//		Every record, or every record whose key starts with the prefix, as a
+//		JSON object with "key" and "value" fields on its own line.
+//
+// Keys in URLs are taken as-is, after unescaping, so binary keys can be
+// written with percent escapes. In JSON, keys and values are strings, encoded
+// as set by the -key-encoding and -value-encoding flags.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/colinmarc/cdb"
+)
+
+var encodings = map[string]cdb.Encoding{
+	"raw":    cdb.Raw,
+	"hex":    cdb.Hex,
+	"base64": cdb.Base64,
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	interval := flag.Duration("reload-interval", 10*time.Second, "how often to check the file for changes, or 0 to never reload")
+	keyEncoding := flag.String("key-encoding", "raw", "encoding for keys in JSON (raw, hex, base64)")
+	valueEncoding := flag.String("value-encoding", "raw", "encoding for values in JSON (raw, hex, base64)")
+	pinTables := flag.Bool("pin-tables", false, "read the hash tables into memory")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cdb-server [flags] <path>")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *addr, *interval, *keyEncoding, *valueEncoding, *pinTables); err != nil {
+		fmt.Fprintf(os.Stderr, "cdb-server: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, addr string, interval time.Duration, keyEncoding, valueEncoding string, pinTables bool) error {
+	s := &server{}
+	var ok bool
+	s.opts.KeyEncoding, ok = encodings[strings.ToLower(keyEncoding)]
+	if !ok {
+		return fmt.Errorf("unknown encoding %q", keyEncoding)
+	}
+
+	s.opts.ValueEncoding, ok = encodings[strings.ToLower(valueEncoding)]
+	if !ok {
+		return fmt.Errorf("unknown encoding %q", valueEncoding)
+	}
+
+	var opts []cdb.Option
+	if pinTables {
+		opts = append(opts, cdb.WithPinnedTables())
+	}
+
+	var err error
+	s.reloader, err = cdb.NewReloader(path, opts...)
+	if err != nil {
+		return err
+	}
+	defer s.reloader.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if interval > 0 {
+		go s.reloader.Watch(ctx, interval, func(err error) {
+			log.Printf("reloading %s: %s", path, err)
+		})
+	}
+
+	httpServer := &http.Server{Addr: addr, Handler: s.handler()}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Let requests in progress finish before the database is closed.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = httpServer.Shutdown(shutdownCtx)
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	return err
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/colinmarc/cdb"
+)
+
+// server handles requests for the database held by a Reloader.
+type server struct {
+	reloader *cdb.Reloader
+	opts     cdb.TextOptions
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get", s.get)
+	mux.HandleFunc("/exists", s.exists)
+	mux.HandleFunc("/each", s.each)
+	return mux
+}
+
+func (s *server) get(w http.ResponseWriter, r *http.Request) {
+	key, ok := requireKey(w, r)
+	if !ok {
+		return
+	}
+
+	value, err := s.reloader.Get(key)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	} else if value == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.FormValue("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"key":   s.opts.KeyEncoding.Encode(key),
+			"value": s.opts.ValueEncoding.Encode(value),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(value)
+}
+
+func (s *server) exists(w http.ResponseWriter, r *http.Request) {
+	key, ok := requireKey(w, r)
+	if !ok {
+		return
+	}
+
+	value, err := s.reloader.Get(key)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if value == nil {
+		status = http.StatusNotFound
+	}
+
+	if r.FormValue("format") != "json" {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"key":    s.opts.KeyEncoding.Encode(key),
+		"exists": value != nil,
+	})
+}
+
+func (s *server) each(w http.ResponseWriter, r *http.Request) {
+	db, release := s.reloader.Acquire()
+	defer release()
+
+	if prefix := []byte(r.FormValue("prefix")); len(prefix) > 0 {
+		db = db.View(func(key []byte) bool {
+			return bytes.HasPrefix(key, prefix)
+		})
+	}
+
+	// Once the response has started, an error can only be logged.
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	err := cdb.ExportJSONLines(w, db, &s.opts)
+	if err != nil {
+		log.Printf("%s %s: %s", r.Method, r.URL, err)
+	}
+}
+
+// requireKey returns the key from the request's query, or responds with an
+// error if it doesn't have one. An empty key is allowed, if it's given.
+func requireKey(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	values, ok := r.URL.Query()["key"]
+	if !ok {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return []byte(values[0]), true
+}
+
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("%s %s: %s", r.Method, r.URL, err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/colinmarc/cdb"
+)
+
+const analyzeUsage = "analyze [flags] <path>"
+
+// analyze prints a report on the layout of a database: a summary, a histogram
+// of value sizes, and the hash tables with the longest probes.
+func analyze(args []string) error {
+	fs := newFlagSet("analyze", analyzeUsage)
+	hashName := fs.String("hash", "cdb", "hash function used by the database (cdb, fnv32a)")
+	top := fs.Int("tables", 10, "number of hash tables to list, slowest first")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected a database path")
+	}
+
+	hash, err := lookupHash(*hashName)
+	if err != nil {
+		return err
+	}
+
+	db, err := openWithHash(fs.Arg(0), hash)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := cdb.Analyze(db)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	fmt.Fprintf(out, "records:           %d\n", report.Records)
+	fmt.Fprintf(out, "data size:         %d\n", report.DataSize)
+	fmt.Fprintf(out, "avg probe length:  %.2f\n", report.AverageProbeLength)
+	fmt.Fprintf(out, "max probe length:  %d\n", report.MaxProbeLength)
+	fmt.Fprintf(out, "shared hashes:     %d (%d records)\n", report.HashGroups, report.HashGroupRecords)
+
+	fmt.Fprintln(out, "\nvalue sizes:")
+	for _, bucket := range report.ValueSizes {
+		fmt.Fprintf(out, "  %10d - %-10d %10d records %12d bytes\n",
+			bucket.Min, bucket.Max, bucket.Records, bucket.Bytes)
+	}
+
+	tables := make([]int, 256)
+	for i := range tables {
+		tables[i] = i
+	}
+
+	sort.SliceStable(tables, func(a, b int) bool {
+		return report.Tables[tables[a]].AverageProbeLength > report.Tables[tables[b]].AverageProbeLength
+	})
+
+	if *top > len(tables) {
+		*top = len(tables)
+	}
+
+	fmt.Fprintln(out, "\ntable   slots  entries  avg probe  max probe  shared hashes")
+	for _, i := range tables[:*top] {
+		t := report.Tables[i]
+		fmt.Fprintf(out, "%5d %7d %8d %10.2f %10d %14d\n",
+			i, t.Slots, t.Entries, t.AverageProbeLength, t.MaxProbeLength, t.HashGroups)
+	}
+
+	return out.Flush()
+}
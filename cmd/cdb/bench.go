@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/colinmarc/cdb"
+)
+
+const benchUsage = "bench [flags]"
+
+// benchConfig holds the parameters of a benchmark run.
+type benchConfig struct {
+	records     int
+	keySize     int
+	valueSize   int
+	valueDist   string
+	reads       int
+	readDist    string
+	concurrency int
+	seed        int64
+}
+
+// bench builds a database of synthetic records, then reads from it, and
+// reports the throughput of each phase and the latency of the reads. Given the
+// same flags, it generates the same database and the same sequence of reads,
+// so runs on different hardware are comparable.
+func bench(args []string) error {
+	fs := newFlagSet("bench", benchUsage)
+	var cfg benchConfig
+	fs.IntVar(&cfg.records, "records", 1000000, "number of records to write")
+	fs.IntVar(&cfg.keySize, "key-size", 16, "size of each key, in bytes")
+	fs.IntVar(&cfg.valueSize, "value-size", 100, "size (or mean size) of each value, in bytes")
+	fs.StringVar(&cfg.valueDist, "value-dist", "fixed", "distribution of value sizes (fixed, uniform, exponential)")
+	fs.IntVar(&cfg.reads, "reads", 1000000, "number of lookups to perform")
+	fs.StringVar(&cfg.readDist, "read-dist", "uniform", "distribution of keys looked up (uniform, zipf)")
+	fs.IntVar(&cfg.concurrency, "concurrency", 1, "number of goroutines performing lookups")
+	fs.Int64Var(&cfg.seed, "seed", 1, "seed for generating records and lookups")
+	path := fs.String("o", "", "path for the database (default: a temporary file, removed afterwards)")
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	} else if cfg.records < 1 || cfg.concurrency < 1 {
+		return errors.New("-records and -concurrency must be at least 1")
+	} else if cfg.readDist != "uniform" && cfg.readDist != "zipf" {
+		return fmt.Errorf("unknown read distribution %q", cfg.readDist)
+	} else if len(fmt.Sprint(cfg.records-1)) > cfg.keySize {
+		return fmt.Errorf("-key-size must be at least %d for %d unique keys", len(fmt.Sprint(cfg.records-1)), cfg.records)
+	}
+
+	valueSize, err := sizeDistribution(cfg.valueDist, cfg.valueSize)
+	if err != nil {
+		return err
+	}
+
+	if *path == "" {
+		dir, err := os.MkdirTemp("", "cdb-bench")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		*path = filepath.Join(dir, "bench.cdb")
+	}
+
+	err = benchWrites(*path, cfg, valueSize)
+	if err != nil {
+		return err
+	}
+
+	return benchReads(*path, cfg)
+}
+
+// sizeDistribution returns a function generating value sizes with the given
+// distribution and mean.
+func sizeDistribution(name string, mean int) (func(r *rand.Rand) int, error) {
+	switch name {
+	case "fixed":
+		return func(r *rand.Rand) int { return mean }, nil
+	case "uniform":
+		return func(r *rand.Rand) int { return r.Intn(2*mean + 1) }, nil
+	case "exponential":
+		return func(r *rand.Rand) int { return int(r.ExpFloat64() * float64(mean)) }, nil
+	default:
+		return nil, fmt.Errorf("unknown value distribution %q", name)
+	}
+}
+
+// benchKey returns the key for the ith record.
+func benchKey(i, size int) []byte {
+	return []byte(fmt.Sprintf("%0*d", size, i))
+}
+
+func benchWrites(path string, cfg benchConfig, valueSize func(r *rand.Rand) int) error {
+	r := rand.New(rand.NewSource(cfg.seed))
+	writer, err := cdb.Create(path)
+	if err != nil {
+		return err
+	}
+
+	var bytes int64
+	value := make([]byte, 0, cfg.valueSize)
+	start := time.Now()
+	for i := 0; i < cfg.records; i++ {
+		n := valueSize(r)
+		if n > cap(value) {
+			value = make([]byte, 0, n)
+		}
+
+		value = value[:n]
+		r.Read(value)
+		err = writer.Put(benchKey(i, cfg.keySize), value)
+		if err != nil {
+			writer.Abort()
+			return err
+		}
+
+		bytes += int64(cfg.keySize + n)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start)
+	fmt.Printf("write: %d records, %.1f MB in %s (%.0f records/s, %.1f MB/s)\n",
+		cfg.records, float64(bytes)/1e6, elapsed.Round(time.Millisecond),
+		float64(cfg.records)/elapsed.Seconds(), float64(bytes)/1e6/elapsed.Seconds())
+	return nil
+}
+
+func benchReads(path string, cfg benchConfig) error {
+	db, err := cdb.Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	latencies := make([][]time.Duration, cfg.concurrency)
+	start := time.Now()
+	for g := 0; g < cfg.concurrency; g++ {
+		n := cfg.reads / cfg.concurrency
+		if g < cfg.reads%cfg.concurrency {
+			n++
+		}
+
+		wg.Add(1)
+		go func(g, n int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(cfg.seed + int64(g) + 1))
+			next := keyDistribution(cfg.readDist, r, cfg.records)
+			latencies[g] = make([]time.Duration, 0, n)
+			for i := 0; i < n; i++ {
+				key := benchKey(next(), cfg.keySize)
+				t := time.Now()
+				value, err := db.Get(key)
+				if err == nil && value == nil {
+					err = fmt.Errorf("key %q not found", key)
+				}
+
+				if err != nil {
+					once.Do(func() { firstErr = err })
+					return
+				}
+
+				latencies[g] = append(latencies[g], time.Since(t))
+			}
+		}(g, n)
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+	if firstErr != nil {
+		return firstErr
+	}
+
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	fmt.Printf("read: %d lookups with %d goroutines in %s (%.0f lookups/s)\n",
+		len(all), cfg.concurrency, elapsed.Round(time.Millisecond), float64(len(all))/elapsed.Seconds())
+	if len(all) > 0 {
+		fmt.Printf("latency: p50 %s, p90 %s, p99 %s, p99.9 %s, max %s\n",
+			percentile(all, 0.5), percentile(all, 0.9), percentile(all, 0.99),
+			percentile(all, 0.999), all[len(all)-1])
+	}
+
+	return nil
+}
+
+// keyDistribution returns a function picking the index of a record to look
+// up, with either a uniform or zipf distribution.
+func keyDistribution(name string, r *rand.Rand, records int) func() int {
+	if name == "zipf" && records > 1 {
+		z := rand.NewZipf(r, 1.1, 1, uint64(records-1))
+		return func() int { return int(z.Uint64()) }
+	}
+
+	return func() int { return r.Intn(records) }
+}
+
+// percentile returns the pth percentile of the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/colinmarc/cdb"
+)
+
+const convertUsage = "convert [flags] <src> <dst>"
+
+func convert(args []string) error {
+	fs := newFlagSet("convert", convertUsage)
+	fromHash := fs.String("from-hash", "cdb", "hash function used by the source database (cdb, fnv32a)")
+	toHash := fs.String("to-hash", "cdb", "hash function for the destination database (cdb, fnv32a)")
+	progress := fs.Bool("progress", false, "report progress on stderr")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected a source and destination path")
+	}
+
+	srcHash, err := lookupHash(*fromHash)
+	if err != nil {
+		return err
+	}
+
+	dstHash, err := lookupHash(*toHash)
+	if err != nil {
+		return err
+	}
+
+	src, err := openWithHash(fs.Arg(0), srcHash)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	dst, err := cdb.NewWriter(out, dstHash)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	var report func(records, bytes int64)
+	if *progress {
+		report = func(records, bytes int64) {
+			if records%100000 == 0 {
+				fmt.Fprintf(os.Stderr, "%d records, %d bytes\n", records, bytes)
+			}
+		}
+	}
+
+	err = cdb.Convert(dst, src, report)
+	if err != nil {
+		dst.Abort()
+		os.Remove(fs.Arg(1))
+		return err
+	}
+
+	return dst.Close()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/colinmarc/cdb"
+)
+
+const diffUsage = "diff [flags] <old> <new>"
+
+// diff prints one line for each key that differs between two databases,
+// starting with '+' for added keys, '-' for removed keys, and '~' for changed
+// keys, followed by the quoted key and values.
+func diff(args []string) error {
+	fs := newFlagSet("diff", diffUsage)
+	hashName := fs.String("hash", "cdb", "hash function used by both databases (cdb, fnv32a)")
+	keysOnly := fs.Bool("keys", false, "only print keys, not values")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected two database paths")
+	}
+
+	hash, err := lookupHash(*hashName)
+	if err != nil {
+		return err
+	}
+
+	a, err := openWithHash(fs.Arg(0), hash)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	b, err := openWithHash(fs.Arg(1), hash)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	out := bufio.NewWriter(os.Stdout)
+	err = cdb.Diff(a, b, func(key, oldValue, newValue []byte) error {
+		var err error
+		switch {
+		case *keysOnly && oldValue == nil:
+			_, err = fmt.Fprintf(out, "+ %q\n", key)
+		case *keysOnly && newValue == nil:
+			_, err = fmt.Fprintf(out, "- %q\n", key)
+		case *keysOnly:
+			_, err = fmt.Fprintf(out, "~ %q\n", key)
+		case oldValue == nil:
+			_, err = fmt.Fprintf(out, "+ %q %q\n", key, newValue)
+		case newValue == nil:
+			_, err = fmt.Fprintf(out, "- %q %q\n", key, oldValue)
+		default:
+			_, err = fmt.Fprintf(out, "~ %q %q %q\n", key, oldValue, newValue)
+		}
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
+
+// openWithHash opens the database at path with the given hash function.
+func openWithHash(path string, hash func([]byte) uint32) (*cdb.CDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := cdb.New(f, hash)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
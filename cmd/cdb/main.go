@@ -0,0 +1,99 @@
+// Command cdb is a tool for inspecting and manipulating cdb databases.
+//
+// Usage:
+//
+//	cdb <command> [flags] [arguments]
+//
+// Run "cdb <command> -h" for help with a particular command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"analyze": {analyzeUsage, analyze},
+	"bench":   {benchUsage, bench},
+	"convert": {convertUsage, convert},
+	"diff":    {diffUsage, diff},
+	"export":  {exportUsage, exportText},
+	"import":  {importUsage, importText},
+}
+
+func main() {
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cdb: unknown command %q\n", flag.Arg(0))
+		printUsage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "cdb %s: %s\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "Usage: cdb <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+}
+
+// newFlagSet returns a flag set for a subcommand.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet("cdb "+name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: cdb %s\n", usage)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// hashFunctions are the hash functions that can be selected by name on the
+// command line. A nil function selects the default cdb hash.
+var hashFunctions = map[string]func([]byte) uint32{
+	"cdb":    nil,
+	"fnv32a": fnv32a,
+}
+
+func lookupHash(name string) (func([]byte) uint32, error) {
+	hash, ok := hashFunctions[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash function %q", name)
+	}
+
+	return hash, nil
+}
+
+func fnv32a(data []byte) uint32 {
+	h := fnv.New32a()
+	h.Write(data)
+	return h.Sum32()
+}
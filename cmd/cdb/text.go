@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/colinmarc/cdb"
+)
+
+const (
+	importUsage = "import [flags] <dst> [input]"
+	exportUsage = "export [flags] <src>"
+)
+
+var encodings = map[string]cdb.Encoding{
+	"raw":    cdb.Raw,
+	"hex":    cdb.Hex,
+	"base64": cdb.Base64,
+}
+
+// textFlags are the flags shared by import and export.
+type textFlags struct {
+	format        *string
+	hash          *string
+	opts          cdb.TextOptions
+	keyEncoding   *string
+	valueEncoding *string
+}
+
+func addTextFlags(fs *flag.FlagSet) *textFlags {
+	tf := &textFlags{}
+	tf.format = fs.String("format", "csv", "text format (csv, jsonl)")
+	tf.hash = fs.String("hash", "cdb", "hash function for the database (cdb, fnv32a)")
+	fs.IntVar(&tf.opts.KeyColumn, "key-column", 0, "CSV column for keys")
+	fs.IntVar(&tf.opts.ValueColumn, "value-column", 1, "CSV column for values")
+	fs.BoolVar(&tf.opts.Header, "header", false, "the CSV has a header row")
+	fs.StringVar(&tf.opts.KeyField, "key-field", "key", "JSON field for keys")
+	fs.StringVar(&tf.opts.ValueField, "value-field", "value", "JSON field for values")
+	tf.keyEncoding = fs.String("key-encoding", "raw", "encoding for keys (raw, hex, base64)")
+	tf.valueEncoding = fs.String("value-encoding", "raw", "encoding for values (raw, hex, base64)")
+	return tf
+}
+
+// options checks the flags, and returns the TextOptions they describe.
+func (tf *textFlags) options() (*cdb.TextOptions, error) {
+	if *tf.format != "csv" && *tf.format != "jsonl" {
+		return nil, fmt.Errorf("unknown format %q", *tf.format)
+	}
+
+	var ok bool
+	tf.opts.KeyEncoding, ok = encodings[strings.ToLower(*tf.keyEncoding)]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", *tf.keyEncoding)
+	}
+
+	tf.opts.ValueEncoding, ok = encodings[strings.ToLower(*tf.valueEncoding)]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", *tf.valueEncoding)
+	}
+
+	return &tf.opts, nil
+}
+
+func importText(args []string) error {
+	fs := newFlagSet("import", importUsage)
+	tf := addTextFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return errors.New("expected a destination path, and optionally an input file")
+	}
+
+	opts, err := tf.options()
+	if err != nil {
+		return err
+	}
+
+	hash, err := lookupHash(*tf.hash)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() == 2 {
+		f, err := os.Open(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		input = f
+	}
+
+	out, err := os.Create(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	dst, err := cdb.NewWriter(out, hash)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	input = bufio.NewReader(input)
+	if *tf.format == "csv" {
+		err = cdb.ImportCSV(dst, input, opts)
+	} else {
+		err = cdb.ImportJSONLines(dst, input, opts)
+	}
+
+	if err != nil {
+		dst.Abort()
+		os.Remove(fs.Arg(0))
+		return err
+	}
+
+	return dst.Close()
+}
+
+func exportText(args []string) error {
+	fs := newFlagSet("export", exportUsage)
+	tf := addTextFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected a source path")
+	}
+
+	opts, err := tf.options()
+	if err != nil {
+		return err
+	}
+
+	hash, err := lookupHash(*tf.hash)
+	if err != nil {
+		return err
+	}
+
+	src, err := openWithHash(fs.Arg(0), hash)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out := bufio.NewWriter(os.Stdout)
+	if *tf.format == "csv" {
+		err = cdb.ExportCSV(out, src, opts)
+	} else {
+		err = cdb.ExportJSONLines(out, src, opts)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,224 @@
+package cdb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// CompatError is returned by VerifyCompat for a file that other cdb
+// implementations wouldn't read the same way this package does.
+type CompatError struct {
+	// Offset is the position in the file of the record or hash table slot
+	// with the problem.
+	Offset int64
+	// Reason describes the problem.
+	Reason string
+}
+
+func (e *CompatError) Error() string {
+	return fmt.Sprintf("incompatible cdb at offset %d: %s", e.Offset, e.Reason)
+}
+
+// VerifyCompat checks that the database read from r is in the original cdb
+// format, as written by djb's cdbmake, tinycdb, and python-cdb, such that any
+// of those implementations would return the same results as this package. In
+// particular:
+//
+//   - The index and every hash table are little-endian, and the records fill
+//     the data section from the end of the index to the first hash table,
+//     with nothing in between.
+//   - Every record is in exactly one hash table slot, under the default cdb
+//     hash of its key, in the table and chain that hash selects.
+//   - Records with the same key appear in their chain in the order they
+//     appear in the file, so that the first one written is the one found
+//     first, and iterating over a key's records returns them in file order.
+//
+// It returns a *CompatError for the first problem it finds, or ErrCorrupt if
+// the file can't be read as a database at all. Anything after the last hash
+// table, such as the metadata written by WithBloomFilter or WithHeader, is
+// ignored by other implementations, and so by VerifyCompat. Since it has no
+// way to tell, VerifyCompat doesn't check whether values were written with
+// WithCompression or WithExpiry, which other implementations won't decode.
+func VerifyCompat(r io.ReaderAt) error {
+	db := &CDB{reader: r, hash: cdbHash, lifecycle: &lifecycle{}}
+	size, sizeKnown := readerSize(r)
+	err := db.readIndex(size, sizeKnown)
+	if err != nil {
+		return err
+	}
+
+	err = db.checkIndex(size, sizeKnown)
+	if err != nil {
+		return err
+	}
+
+	hashes, err := db.compatRecords()
+	if err != nil {
+		return err
+	}
+
+	seen := 0
+	for i, table := range db.index {
+		n, err := db.compatTable(uint32(i), table, hashes)
+		if err != nil {
+			return err
+		}
+
+		seen += n
+	}
+
+	// Every slot points to a distinct record, so if the counts don't match,
+	// some records are missing from the tables.
+	if seen == len(hashes) {
+		return nil
+	}
+
+	missing := uint32(0)
+	for offset, rec := range hashes {
+		if !rec.found && (missing == 0 || offset < missing) {
+			missing = offset
+		}
+	}
+
+	return &CompatError{int64(missing), "record isn't in any hash table"}
+}
+
+// compatHash is the hash of a record's key, and whether a slot has been found
+// for it.
+type compatHash struct {
+	hash  uint32
+	found bool
+}
+
+// compatRecords reads the data section record by record, and returns the
+// hash of the key of each record, by offset.
+func (cdb *CDB) compatRecords() (map[uint32]*compatHash, error) {
+	hashes := make(map[uint32]*compatHash)
+	dataEnd := cdb.index[0].offset
+	for offset := uint32(indexSize); offset < dataEnd; {
+		key, next, _, err := cdb.readKey(offset)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes[offset] = &compatHash{hash: cdbHash(key)}
+		offset = next
+	}
+
+	return hashes, nil
+}
+
+// compatTable checks each slot of a hash table, and returns the number of
+// slots that aren't empty.
+func (cdb *CDB) compatTable(i uint32, table table, hashes map[uint32]*compatHash) (int, error) {
+	if table.length == 0 {
+		return 0, nil
+	}
+
+	buf := make([]byte, 8*table.length)
+	err := cdb.readTables(buf, table.offset)
+	if err != nil {
+		return 0, err
+	}
+
+	slots := make([]entry, table.length)
+	for slot := range slots {
+		slots[slot] = entry{
+			hash:   cdb.opts.order().Uint32(buf[slot*8:]),
+			offset: cdb.opts.order().Uint32(buf[slot*8+4:]),
+		}
+	}
+
+	// chains holds the slots of each hash, in the order they're probed.
+	chains := make(map[uint32][]uint32)
+	n := 0
+	for slot, e := range slots {
+		if e.offset == 0 {
+			continue
+		}
+
+		n++
+		slotOffset := int64(table.offset) + 8*int64(slot)
+		rec, ok := hashes[e.offset]
+		switch {
+		case !ok:
+			return 0, &CompatError{slotOffset, fmt.Sprintf("slot points to %d, which isn't the start of a record", e.offset)}
+		case rec.found:
+			return 0, &CompatError{slotOffset, fmt.Sprintf("record at %d is in more than one slot", e.offset)}
+		case rec.hash != e.hash:
+			return 0, &CompatError{slotOffset, "hash doesn't match the cdb hash of the key"}
+		case e.hash&0xff != i:
+			return 0, &CompatError{slotOffset, "record is in the wrong hash table"}
+		}
+
+		rec.found = true
+
+		// Every slot between the start of the chain and this one must be
+		// full, or lookups would stop before they got here.
+		start := (e.hash >> 8) % table.length
+		for s := start; s != uint32(slot); s = (s + 1) % table.length {
+			if slots[s].offset == 0 {
+				return 0, &CompatError{slotOffset, "record can't be reached from the start of its chain"}
+			}
+		}
+
+		chains[e.hash] = append(chains[e.hash], uint32(slot))
+	}
+
+	for hash, chain := range chains {
+		start := (hash >> 8) % table.length
+		sort.Slice(chain, func(a, b int) bool {
+			return (chain[a]+table.length-start)%table.length < (chain[b]+table.length-start)%table.length
+		})
+
+		err := cdb.compatChainOrder(table, slots, chain)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// compatChainOrder checks that records with the same key appear in a chain in
+// the order they appear in the file. The slots are in probe order, and all
+// have the same hash.
+func (cdb *CDB) compatChainOrder(table table, slots []entry, chain []uint32) error {
+	for a := range chain {
+		for b := a + 1; b < len(chain); b++ {
+			first, second := slots[chain[a]].offset, slots[chain[b]].offset
+			if first < second {
+				continue
+			}
+
+			same, err := cdb.sameKey(first, second)
+			if err != nil {
+				return err
+			} else if same {
+				return &CompatError{
+					int64(table.offset) + 8*int64(chain[b]),
+					fmt.Sprintf("record at %d is found before the record at %d with the same key", first, second),
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// sameKey returns true if the records at the two offsets have the same key.
+func (cdb *CDB) sameKey(a, b uint32) (bool, error) {
+	keyA, _, _, err := cdb.readKey(a)
+	if err != nil {
+		return false, err
+	}
+
+	keyB, _, _, err := cdb.readKey(b)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(keyA, keyB), nil
+}
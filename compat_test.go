@@ -0,0 +1,135 @@
+package cdb_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test/compat.cdb was built with the same algorithm as djb's cdbmake, and
+// includes duplicate keys, an empty key, binary keys, a key that hashes to
+// zero, and colliding keys.
+func readCompatData(t *testing.T) []byte {
+	data, err := ioutil.ReadFile("./test/compat.cdb")
+	require.NoError(t, err)
+	return data
+}
+
+// findSlots returns the offsets in data of the hash table slots with the
+// given hash, in the order they're probed.
+func findSlots(data []byte, hash uint32) []int {
+	i := int(hash&0xff) * 8
+	offset := binary.LittleEndian.Uint32(data[i:])
+	length := binary.LittleEndian.Uint32(data[i+4:])
+
+	var slots []int
+	start := (hash >> 8) % length
+	for n := uint32(0); n < length; n++ {
+		slot := int(offset + 8*((start+n)%length))
+		if binary.LittleEndian.Uint32(data[slot+4:]) == 0 {
+			break
+		} else if binary.LittleEndian.Uint32(data[slot:]) == hash {
+			slots = append(slots, slot)
+		}
+	}
+
+	return slots
+}
+
+func TestVerifyCompat(t *testing.T) {
+	assert.NoError(t, cdb.VerifyCompat(bytes.NewReader(readTestData(t))))
+
+	data := readCompatData(t)
+	require.NoError(t, cdb.VerifyCompat(bytes.NewReader(data)))
+
+	db, err := cdb.New(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+
+	value, err := db.Get([]byte("dup"))
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(value))
+
+	values, err := db.GetAll([]byte("dup"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("first"), []byte("second"), []byte("third")}, values)
+
+	for key, expected := range map[string]string{
+		"":                "empty key",
+		"empty value":     "",
+		"\x00\xff binary": "\x01\x02\x03",
+		"7bafhhw":         "hashes to zero",
+		"playwright":      "collides",
+		"snush":           "with playwright",
+		"key299":          "value299",
+	} {
+		value, err := db.GetStrict([]byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(value), "key %q", key)
+	}
+
+	// Databases written by this package are compatible, as long as they use
+	// the default hash and byte order.
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil, cdb.WithHeader(), cdb.WithBloomFilter(10))
+	require.NoError(t, err)
+
+	iter := db.Iter()
+	for iter.Next() {
+		require.NoError(t, writer.Put(iter.Key(), iter.Value()))
+	}
+
+	require.NoError(t, iter.Err())
+	require.NoError(t, writer.Close())
+
+	f, err = os.Open(f.Name())
+	require.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, cdb.VerifyCompat(f))
+}
+
+func TestVerifyCompatErrors(t *testing.T) {
+	data := readCompatData(t)
+	dups := findSlots(data, cdb.HashKey([]byte("dup")))
+	require.Len(t, dups, 3)
+
+	// Duplicate keys found out of order.
+	corrupted := append([]byte(nil), data...)
+	copy(corrupted[dups[0]+4:dups[0]+8], data[dups[1]+4:dups[1]+8])
+	copy(corrupted[dups[1]+4:dups[1]+8], data[dups[0]+4:dups[0]+8])
+	err := cdb.VerifyCompat(bytes.NewReader(corrupted))
+	assert.IsType(t, &cdb.CompatError{}, err)
+
+	// A record missing from the tables.
+	corrupted = append([]byte(nil), data...)
+	copy(corrupted[dups[2]:dups[2]+8], make([]byte, 8))
+	err = cdb.VerifyCompat(bytes.NewReader(corrupted))
+	assert.IsType(t, &cdb.CompatError{}, err)
+
+	// A slot with the wrong hash.
+	corrupted = append([]byte(nil), data...)
+	corrupted[dups[0]+1]++
+	err = cdb.VerifyCompat(bytes.NewReader(corrupted))
+	assert.IsType(t, &cdb.CompatError{}, err)
+
+	// A database with a big-endian index can't be read at all.
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	writer, err := cdb.NewWriter(f, nil, cdb.WithByteOrder(binary.BigEndian))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	_, err = writer.Freeze()
+	require.NoError(t, err)
+	assert.Error(t, cdb.VerifyCompat(f))
+}
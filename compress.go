@@ -0,0 +1,124 @@
+package cdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+)
+
+// ErrUnknownCompression is returned when reading a value that was compressed
+// with a Compressor other than the one passed to WithCompression.
+var ErrUnknownCompression = errors.New("value was compressed with an unknown compressor")
+
+// A Compressor compresses and decompresses values for WithCompression.
+//
+// The package provides Gzip; other algorithms, such as snappy or zstd, can be
+// used by wrapping them in a Compressor.
+type Compressor interface {
+	// ID identifies the compressor. It's stored alongside each compressed
+	// value, so it must be unique and must never change. 0 is reserved for
+	// values stored uncompressed, and IDs below 16 are reserved for
+	// compressors provided by this package.
+	ID() byte
+	// Compress returns the compressed form of value.
+	Compress(value []byte) ([]byte, error)
+	// Decompress reverses Compress.
+	Decompress(value []byte) ([]byte, error)
+}
+
+// Gzip is a Compressor using gzip, at the default compression level.
+var Gzip Compressor = gzipCompressor{}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) ID() byte {
+	return 1
+}
+
+func (gzipCompressor) Compress(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(value)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(value []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
+
+// WithCompression causes values to be compressed with c when writing, and
+// decompressed when reading. Compression is transparent: Get, GetInto and
+// Iterator all return the original values. Values that don't get any smaller
+// when compressed, and values written with PutReader, are stored as-is.
+//
+// Each value is prefixed with a byte identifying how it was stored, so a
+// database written with this option must also be read with it, using the
+// same Compressor. Since keys are never compressed, lookups cost the same as
+// they would otherwise, plus the time to decompress the value.
+func WithCompression(c Compressor) Option {
+	return func(o *options) {
+		o.compressor = c
+	}
+}
+
+// compressValue compresses value if there's a compressor, and returns it
+// behind the header byte saying how it was stored.
+func (o *options) compressValue(value []byte) ([]byte, error) {
+	c := o.compressor
+	if c == nil {
+		return value, nil
+	}
+
+	compressed, err := c.Compress(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(compressed) < len(value) {
+		return append([]byte{c.ID()}, compressed...), nil
+	}
+
+	return append([]byte{0}, value...), nil
+}
+
+// decompressValue reverses compressValue.
+func (o *options) decompressValue(value []byte) ([]byte, error) {
+	c := o.compressor
+	if c == nil {
+		return value, nil
+	}
+
+	if len(value) == 0 {
+		return nil, ErrUnknownCompression
+	}
+
+	switch value[0] {
+	case 0:
+		return value[1:], nil
+	case c.ID():
+		decompressed, err := c.Decompress(value[1:])
+		if err == nil && decompressed == nil {
+			// Get distinguishes empty values from missing ones.
+			decompressed = []byte{}
+		}
+
+		return decompressed, err
+	default:
+		return nil, ErrUnknownCompression
+	}
+}
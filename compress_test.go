@@ -0,0 +1,102 @@
+package cdb_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil, cdb.WithCompression(cdb.Gzip))
+	require.NoError(t, err)
+
+	big := strings.Repeat(`{"name": "foo", "value": "bar"}`, 100)
+	records := map[string]string{
+		"big":   big,
+		"small": "x",
+		"empty": "",
+	}
+
+	for k, v := range records {
+		require.NoError(t, writer.Put([]byte(k), []byte(v)))
+	}
+
+	require.NoError(t, writer.PutReader([]byte("streamed"), 3, strings.NewReader("baz")))
+	records["streamed"] = "baz"
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	assert.True(t, db.DataSize() < int64(len(big)), "the data should be compressed")
+
+	for k, v := range records {
+		value, err := db.Get([]byte(k))
+		require.NoError(t, err, k)
+		assert.Equal(t, v, string(value), k)
+
+		buf := make([]byte, len(v))
+		n, found, err := db.GetInto([]byte(k), buf)
+		require.NoError(t, err, k)
+		assert.True(t, found, k)
+		assert.Equal(t, v, string(buf[:n]), k)
+	}
+
+	value, err := db.Get([]byte("missing"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	iter := db.Iter()
+	for iter.Next() {
+		assert.Equal(t, records[string(iter.Key())], string(iter.Value()))
+	}
+
+	require.NoError(t, iter.Err())
+}
+
+func TestCompressionUnknownCompressor(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil, cdb.WithCompression(cdb.Gzip))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), bytes.Repeat([]byte("bar"), 100)))
+	require.NoError(t, writer.Close())
+
+	db, err := cdb.Open(f.Name(), cdb.WithCompression(reverseCompressor{}))
+	require.NoError(t, err)
+
+	_, err = db.Get([]byte("foo"))
+	assert.Equal(t, cdb.ErrUnknownCompression, err)
+}
+
+// reverseCompressor is a toy compressor for testing.
+type reverseCompressor struct{}
+
+func (reverseCompressor) ID() byte { return 200 }
+
+func (reverseCompressor) Compress(value []byte) ([]byte, error) {
+	return reverse(value), nil
+}
+
+func (reverseCompressor) Decompress(value []byte) ([]byte, error) {
+	return reverse(value), nil
+}
+
+func reverse(b []byte) []byte {
+	res := make([]byte, len(b))
+	for i := range b {
+		res[len(b)-1-i] = b[i]
+	}
+
+	return res
+}
@@ -0,0 +1,34 @@
+package cdb
+
+// Convert copies every record in src to dst, in the order they appear in src.
+// Since the records are streamed one at a time, memory usage is bounded by the
+// size of the largest record (plus the bookkeeping dst needs for its hash
+// tables). This can be used to migrate a database between dialects, for
+// example by re-hashing it with a different hash function.
+//
+// Records keep their expiration times, if src and dst both use WithExpiry.
+//
+// If progress is not nil, it is called after each record is copied with the
+// number of records and bytes copied so far.
+//
+// Convert does not finalize dst; the caller must call Close or Freeze once it
+// returns.
+func Convert(dst *Writer, src *CDB, progress func(records, bytes int64)) error {
+	var records, bytes int64
+
+	iter := src.Iter()
+	for iter.Next() {
+		err := dst.PutWithExpiry(iter.Key(), iter.Value(), iter.expires)
+		if err != nil {
+			return err
+		}
+
+		records++
+		bytes += int64(8 + len(iter.Key()) + len(iter.Value()))
+		if progress != nil {
+			progress(records, bytes)
+		}
+	}
+
+	return iter.Err()
+}
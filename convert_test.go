@@ -0,0 +1,56 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	src, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	dst, err := cdb.NewWriter(f, fnvHash)
+	require.NoError(t, err)
+
+	var lastRecords, lastBytes int64
+	err = cdb.Convert(dst, src, func(records, bytes int64) {
+		assert.Equal(t, lastRecords+1, records)
+		assert.True(t, bytes > lastBytes)
+		lastRecords, lastBytes = records, bytes
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, len(expectedRecords)-1, lastRecords)
+	assert.Equal(t, src.DataSize(), lastBytes)
+
+	db, err := dst.Freeze()
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		msg := "while fetching " + string(record[0])
+
+		value, err := db.Get(record[0])
+		require.NoError(t, err, msg)
+		assert.Equal(t, string(record[1]), string(value), msg)
+	}
+}
+
+func TestConvertKeepsExpiry(t *testing.T) {
+	src, expires := newExpiringDB(t)
+	dst := cdb.NewMem(cdb.WithExpiry())
+	require.NoError(t, cdb.Convert(dst, src, nil))
+
+	db, err := dst.Freeze()
+	require.NoError(t, err)
+	assertExpiry(t, db, "forever", time.Time{})
+	assertExpiry(t, db, "expiring", expires)
+}
@@ -0,0 +1,160 @@
+package cdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrCorrupt is returned when a database is malformed: for example, if the
+// index points outside the file, or a record runs past the end of the data
+// section. Databases are checked as they're read, so a corrupt or malicious
+// file causes an error instead of a panic, runaway allocation, or loop.
+//
+// Errors for corrupt databases are usually a *CorruptError, which says where
+// the problem is, so they should be checked with errors.Is.
+var ErrCorrupt = errors.New("database is corrupt")
+
+var (
+	// ErrCorruptIndex is the kind of error for a problem with the index, the
+	// hash tables, or the metadata stored after them. It matches ErrCorrupt,
+	// with errors.Is.
+	ErrCorruptIndex = fmt.Errorf("%w: invalid index or hash table", ErrCorrupt)
+	// ErrCorruptRecord is the kind of error for a problem with a record in
+	// the data section. It matches ErrCorrupt, with errors.Is.
+	ErrCorruptRecord = fmt.Errorf("%w: invalid record", ErrCorrupt)
+)
+
+// CorruptError describes where a database is malformed. It matches its Kind
+// with errors.Is, and so ErrCorrupt, as well as the error from the
+// underlying reader that revealed the problem, if there was one.
+type CorruptError struct {
+	// Kind is ErrCorruptIndex or ErrCorruptRecord.
+	Kind error
+	// Offset is the position in the file of the data that's malformed.
+	Offset int64
+	// Err is the error from the underlying reader, such as
+	// io.ErrUnexpectedEOF if the file is truncated, or nil.
+	Err error
+}
+
+func (e *CorruptError) Error() string {
+	msg := fmt.Sprintf("%s at offset %d", e.Kind, e.Offset)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *CorruptError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+
+	return []error{e.Kind, e.Err}
+}
+
+// corruptAt returns a *CorruptError of the given kind for the data at offset.
+func corruptAt(kind error, offset int64) error {
+	return &CorruptError{Kind: kind, Offset: offset}
+}
+
+// readError adds context to an error from reading the data at offset. Reading
+// past the end of the file means the file was truncated or the index or tables
+// are wrong, so that's reported as a *CorruptError of the given kind; other
+// errors are wrapped with the offset.
+func readError(kind error, offset int64, err error) error {
+	if err == nil {
+		return nil
+	} else if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &CorruptError{Kind: kind, Offset: offset, Err: err}
+	}
+
+	return fmt.Errorf("cdb: reading at offset %d: %w", offset, err)
+}
+
+// WithMaxRecordSize makes reads treat any record larger than n bytes,
+// including its header, as corrupt, so that Get, Iter, EachRecord, and the
+// rest return ErrCorrupt instead of allocating space for it. Records are
+// already checked against the size of the data section, but that's no help
+// if the index itself is wrong and the size of the file isn't known, as with
+// a reader that doesn't have a Size method.
+func WithMaxRecordSize(n int64) Option {
+	return func(o *options) {
+		o.maxRecordSize = n
+	}
+}
+
+// readerSize returns the size of r, if it can be determined.
+func readerSize(r io.ReaderAt) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), true
+	case interface{ Size() (int64, error) }:
+		size, err := r.Size()
+		return size, err == nil
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+
+		return info.Size(), true
+	}
+
+	return 0, false
+}
+
+// checkIndex checks that the hash tables lie after the data section, and
+// within the file, if its size is known.
+func (cdb *CDB) checkIndex(size int64, sizeKnown bool) error {
+	dataEnd := cdb.index[0].offset
+	if dataEnd < indexSize || (sizeKnown && int64(dataEnd) > size) {
+		return corruptAt(ErrCorruptIndex, 0)
+	}
+
+	for i, table := range cdb.index {
+		end := int64(table.offset) + 8*int64(table.length)
+		if table.offset < dataEnd || end > 0xffffffff || (sizeKnown && end > size) {
+			return corruptAt(ErrCorruptIndex, int64(i)*8)
+		}
+	}
+
+	return nil
+}
+
+// readHeader reads the header of the record at offset, using scratch as the
+// buffer if it isn't nil, and checks that the record lies within the data
+// section.
+func (cdb *CDB) readHeader(offset uint32, scratch []byte) (uint32, uint32, error) {
+	dataEnd := cdb.index[0].offset
+	if offset < indexSize || int64(offset)+8 > int64(dataEnd) {
+		return 0, 0, corruptAt(ErrCorruptRecord, int64(offset))
+	}
+
+	if scratch == nil {
+		scratch = make([]byte, 8)
+	}
+
+	keyLength, valueLength, err := readTupleInto(cdb.reader, cdb.opts.order(), offset, scratch[:8])
+	if err != nil {
+		return 0, 0, readError(ErrCorruptRecord, int64(offset), err)
+	}
+
+	return keyLength, valueLength, cdb.checkRecord(offset, keyLength, valueLength)
+}
+
+// checkRecord checks that a record with the given header lies within the data
+// section, and isn't larger than WithMaxRecordSize allows.
+func (cdb *CDB) checkRecord(offset, keyLength, valueLength uint32) error {
+	length := 8 + int64(keyLength) + int64(valueLength)
+	if int64(offset)+length > int64(cdb.index[0].offset) {
+		return corruptAt(ErrCorruptRecord, int64(offset))
+	} else if max := cdb.opts.maxRecordSize; max > 0 && length > max {
+		return corruptAt(ErrCorruptRecord, int64(offset))
+	}
+
+	return nil
+}
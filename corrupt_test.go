@@ -0,0 +1,191 @@
+package cdb_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTestData(t testing.TB) []byte {
+	data, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+	return data
+}
+
+func TestCorruptIndex(t *testing.T) {
+	data := readTestData(t)
+
+	_, err := cdb.New(bytes.NewReader(data[:1000]), nil)
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+
+	// A table that runs past the end of the file.
+	corrupted := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(corrupted[12:], 0xffffff)
+	_, err = cdb.New(bytes.NewReader(corrupted), nil)
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+
+	// A table that overlaps the data section.
+	corrupted = append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(corrupted[8:], 100)
+	_, err = cdb.New(bytes.NewReader(corrupted), nil)
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+}
+
+func TestCorruptRecord(t *testing.T) {
+	data := readTestData(t)
+
+	// Make the first value run into the hash tables.
+	corrupted := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(corrupted[cdb.DataOffset+4:], 0xfffffff0)
+	db, err := cdb.New(bytes.NewReader(corrupted), nil)
+	require.NoError(t, err)
+
+	_, err = db.Get(expectedRecords[0][0])
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+
+	_, _, err = db.GetInto(expectedRecords[0][0], make([]byte, 64))
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+
+	iter := db.Iter()
+	assert.False(t, iter.Next())
+	assert.True(t, errors.Is(iter.Err(), cdb.ErrCorrupt))
+
+	err = db.EachRecord(func(rec cdb.Record) error { return nil })
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+}
+
+func TestCorruptError(t *testing.T) {
+	data := readTestData(t)
+
+	// A truncated file is missing some of the hash tables.
+	_, err := cdb.New(bytes.NewReader(data[:len(data)-10]), nil)
+	var corruptErr *cdb.CorruptError
+	require.True(t, errors.As(err, &corruptErr))
+	assert.True(t, errors.Is(err, cdb.ErrCorruptIndex))
+	assert.False(t, errors.Is(err, cdb.ErrCorruptRecord))
+
+	corrupted := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(corrupted[cdb.DataOffset+4:], 0xfffffff0)
+	db, err := cdb.New(bytes.NewReader(corrupted), nil)
+	require.NoError(t, err)
+
+	_, err = db.Get(expectedRecords[0][0])
+	require.True(t, errors.As(err, &corruptErr))
+	assert.True(t, errors.Is(err, cdb.ErrCorruptRecord))
+	assert.Equal(t, int64(cdb.DataOffset), corruptErr.Offset)
+	assert.Contains(t, err.Error(), "at offset 2048")
+}
+
+// failingReader returns err for reads at or past offset.
+type failingReader struct {
+	r      io.ReaderAt
+	offset int64
+	err    error
+}
+
+func (f failingReader) ReadAt(b []byte, off int64) (int, error) {
+	if off+int64(len(b)) > f.offset {
+		return 0, f.err
+	}
+
+	return f.r.ReadAt(b, off)
+}
+
+func TestReadErrorWrapped(t *testing.T) {
+	data := readTestData(t)
+	errDisk := errors.New("disk on fire")
+
+	// Fail every read after the index.
+	r := failingReader{bytes.NewReader(data), cdb.DataOffset, errDisk}
+	db, err := cdb.New(r, nil)
+	require.NoError(t, err)
+
+	_, err = db.Get(expectedRecords[0][0])
+	assert.True(t, errors.Is(err, errDisk))
+	assert.False(t, errors.Is(err, cdb.ErrCorrupt))
+	assert.Contains(t, err.Error(), "offset")
+}
+
+func FuzzNew(f *testing.F) {
+	data := readTestData(f)
+	f.Add(data)
+	f.Add(data[:cdb.DataOffset+20])
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		db, err := cdb.New(bytes.NewReader(data), nil)
+		if err != nil {
+			return
+		}
+
+		for _, record := range expectedRecords {
+			db.Get(record[0])
+		}
+
+		iter := db.Iter()
+		for iter.Next() {
+		}
+
+		db.EachRecord(func(rec cdb.Record) error {
+			_, err := rec.Value()
+			return err
+		})
+
+		db.Len()
+		db.Stats()
+	})
+}
+
+func TestMaxRecordSize(t *testing.T) {
+	data := readTestData(t)
+
+	// Point every table at the very end of the address space, so the data
+	// section seems to be almost 4GB, then make the first value huge. Since
+	// the reader doesn't have a Size, that can't be caught up front.
+	corrupted := append([]byte(nil), data[:cdb.DataOffset+64]...)
+	for i := 0; i < 256; i++ {
+		binary.LittleEndian.PutUint32(corrupted[i*8:], 0xfffffff0)
+		binary.LittleEndian.PutUint32(corrupted[i*8+4:], 0)
+	}
+
+	binary.LittleEndian.PutUint32(corrupted[cdb.DataOffset+4:], 0x40000000)
+	reader := struct{ io.ReaderAt }{bytes.NewReader(corrupted)}
+	db, err := cdb.New(reader, nil, cdb.WithMaxRecordSize(1<<20))
+	require.NoError(t, err)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	iter := db.Iter()
+	assert.False(t, iter.Next())
+	assert.True(t, errors.Is(iter.Err(), cdb.ErrCorrupt))
+
+	err = db.EachRecord(func(rec cdb.Record) error { return nil })
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+	runtime.ReadMemStats(&after)
+	assert.True(t, after.TotalAlloc-before.TotalAlloc < 1<<20)
+
+	// Records within the limit are fine.
+	db, err = cdb.Open("./test/test.cdb", cdb.WithMaxRecordSize(64))
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	db, err = cdb.Open("./test/test.cdb", cdb.WithMaxRecordSize(16))
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.EachRecord(func(rec cdb.Record) error { return nil })
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+}
@@ -0,0 +1,62 @@
+package cdb
+
+import "errors"
+
+// ErrStop can be returned by the function passed to EachFrom to end the scan
+// early, after the current record.
+var ErrStop = errors.New("stop scanning")
+
+// EachFrom calls fn for each record in the database, in the order they're
+// stored, starting at cursor, which is either zero to start from the
+// beginning, or a cursor returned by an earlier call. Cursors are offsets into
+// the file, so they can be saved and used to resume a scan after the
+// database is reopened, as long as the file hasn't changed.
+//
+// If fn returns ErrStop, EachFrom returns a cursor for the records after the
+// current one, and a nil error. If fn returns any other error, EachFrom
+// returns it, along with a cursor that starts at the current record, so that
+// it's visited again when the scan is resumed. Once every record has been
+// visited, the cursor returned is zero.
+//
+// EachFrom returns ErrOffsetOutOfRange if cursor is outside the data section.
+// Like GetAt, it can't otherwise check that a record starts there.
+func (cdb *CDB) EachFrom(cursor uint32, fn func(key, value []byte) error) (uint32, error) {
+	end := cdb.index[0].offset
+	if cursor == 0 {
+		cursor = cdb.firstRecord()
+	} else if cursor < DataOffset || cursor > end {
+		return cursor, ErrOffsetOutOfRange
+	}
+
+	iter := cdb.Iter()
+	iter.pos = cursor
+	for {
+		start := iter.pos
+		if !iter.Next() {
+			break
+		}
+
+		err := fn(iter.Key(), iter.Value())
+		if err == ErrStop {
+			return iter.cursor(), nil
+		} else if err != nil {
+			return start, err
+		}
+	}
+
+	if iter.Err() != nil {
+		return iter.pos, iter.Err()
+	}
+
+	return 0, nil
+}
+
+// cursor returns a cursor for the records the iterator hasn't visited yet, or
+// zero if there aren't any.
+func (iter *Iterator) cursor() uint32 {
+	if iter.pos >= iter.endPos {
+		return 0
+	}
+
+	return iter.pos
+}
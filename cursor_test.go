@@ -0,0 +1,82 @@
+package cdb_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEachFrom(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var expected []string
+	iter := db.Iter()
+	for iter.Next() {
+		expected = append(expected, string(iter.Key()))
+	}
+	require.NoError(t, iter.Err())
+
+	// Scan two records at a time, resuming from the cursor each time.
+	var keys []string
+	cursor, batches := uint32(0), 0
+	for {
+		n := 0
+		cursor, err = db.EachFrom(cursor, func(key, value []byte) error {
+			keys = append(keys, string(key))
+			n++
+			if n == 2 {
+				return cdb.ErrStop
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		batches++
+		if cursor == 0 {
+			break
+		}
+	}
+
+	assert.Equal(t, expected, keys)
+	assert.Equal(t, (len(expected)+2)/2, batches)
+}
+
+func TestEachFromError(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer db.Close()
+
+	failure := errors.New("failed")
+	var first []byte
+	cursor, err := db.EachFrom(0, func(key, value []byte) error {
+		if first == nil {
+			first = key
+			return nil
+		}
+
+		return failure
+	})
+	assert.Equal(t, failure, err)
+
+	// The record that failed is visited again.
+	rec, err := db.GetAt(cursor)
+	require.NoError(t, err)
+	assert.True(t, rec.Offset() > cdb.DataOffset)
+
+	var second []byte
+	_, err = db.EachFrom(cursor, func(key, value []byte) error {
+		second = key
+		return cdb.ErrStop
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	_, err = db.EachFrom(1, func(key, value []byte) error { return nil })
+	assert.Equal(t, cdb.ErrOffsetOutOfRange, err)
+}
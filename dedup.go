@@ -0,0 +1,225 @@
+package cdb
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrDuplicateKey is returned by DedupWriter.Put for a key that was already
+// added, with the RejectDuplicates policy.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// A DuplicatePolicy tells a DedupWriter what to do with duplicate keys.
+type DuplicatePolicy int
+
+const (
+	// SkipDuplicates silently drops records with a key that was already
+	// added, so the first value wins.
+	SkipDuplicates DuplicatePolicy = iota
+	// RejectDuplicates causes Put to return ErrDuplicateKey for records with
+	// a key that was already added.
+	RejectDuplicates
+)
+
+// DedupWriter wraps a Writer to ensure that each key is only added once. CDB
+// allows several records with the same key, but Get only returns the first,
+// so duplicates are usually a mistake.
+//
+// If the Writer's stream is an io.ReaderAt, the in-memory hash tables the
+// Writer already keeps are used to find candidate duplicates, which are then
+// verified by reading back their keys, so deduplication costs no extra
+// memory. Otherwise, DedupWriter keeps a copy of every key in memory.
+//
+// Only the methods of DedupWriter check for duplicates, so records shouldn't
+// be added to the wrapped Writer directly.
+type DedupWriter struct {
+	writer *Writer
+	policy DuplicatePolicy
+	seen   map[string]struct{}
+}
+
+// NewDedupWriter wraps w in a DedupWriter with the given policy. Any records
+// already added to w are taken into account only if its stream is an
+// io.ReaderAt.
+func NewDedupWriter(w *Writer, policy DuplicatePolicy) *DedupWriter {
+	dw := &DedupWriter{writer: w, policy: policy}
+	if _, ok := w.writer.(io.ReaderAt); !ok {
+		dw.seen = make(map[string]struct{})
+	}
+
+	return dw
+}
+
+// Put adds a key/value pair to the database, unless the key was already
+// added, in which case it follows the DuplicatePolicy.
+func (dw *DedupWriter) Put(key, value []byte) error {
+	return dw.add(key, func() error {
+		return dw.writer.Put(key, value)
+	})
+}
+
+// PutWithExpiry is like Writer.PutWithExpiry, but follows the DuplicatePolicy
+// for keys that were already added.
+func (dw *DedupWriter) PutWithExpiry(key, value []byte, expires time.Time) error {
+	return dw.add(key, func() error {
+		return dw.writer.PutWithExpiry(key, value, expires)
+	})
+}
+
+// PutString is like Put, but with a string key and value.
+func (dw *DedupWriter) PutString(key, value string) error {
+	return dw.Put(stringBytes(key), stringBytes(value))
+}
+
+// PutReader is like Writer.PutReader, but follows the DuplicatePolicy for keys
+// that were already added. Skipped values aren't read from r.
+func (dw *DedupWriter) PutReader(key []byte, valueLength uint32, r io.Reader) error {
+	return dw.add(key, func() error {
+		return dw.writer.PutReader(key, valueLength, r)
+	})
+}
+
+// PutHashed is like Writer.PutHashed, but follows the DuplicatePolicy for keys
+// that were already added.
+func (dw *DedupWriter) PutHashed(hash uint32, key, value []byte) error {
+	return dw.add(key, func() error {
+		return dw.writer.PutHashed(hash, key, value)
+	})
+}
+
+// Delete is like Writer.Delete. Once a key is deleted, it can be added again.
+func (dw *DedupWriter) Delete(key []byte) error {
+	err := dw.writer.Delete(key)
+	if err == nil && dw.seen != nil {
+		delete(dw.seen, string(dw.writer.opts.normalizeKey(key)))
+	}
+
+	return err
+}
+
+// SetMetadata sets a metadata entry, like Writer.SetMetadata.
+func (dw *DedupWriter) SetMetadata(key, value string) error {
+	return dw.writer.SetMetadata(key, value)
+}
+
+// EntryCount returns the number of records written so far, like
+// Writer.EntryCount.
+func (dw *DedupWriter) EntryCount() int64 {
+	return dw.writer.EntryCount()
+}
+
+// EstimatedSize returns the size the database would be if it were finalized
+// now, like Writer.EstimatedSize.
+func (dw *DedupWriter) EstimatedSize() int64 {
+	return dw.writer.EstimatedSize()
+}
+
+// Sync writes any buffered records to the underlying stream, like
+// Writer.Sync.
+func (dw *DedupWriter) Sync() error {
+	return dw.writer.Sync()
+}
+
+// Snapshot returns a read-only view of the records written so far, like
+// Writer.Snapshot.
+func (dw *DedupWriter) Snapshot() (*CDB, error) {
+	return dw.writer.Snapshot()
+}
+
+// Close finalizes the database and closes the underlying stream, like
+// Writer.Close.
+func (dw *DedupWriter) Close() error {
+	return dw.writer.Close()
+}
+
+// Freeze finalizes the database and opens it for reads, like Writer.Freeze.
+func (dw *DedupWriter) Freeze() (*CDB, error) {
+	return dw.writer.Freeze()
+}
+
+// Abort discards the database being written, like Writer.Abort.
+func (dw *DedupWriter) Abort() error {
+	return dw.writer.Abort()
+}
+
+// add calls put to write a record for key, unless it was already added, in
+// which case it follows the DuplicatePolicy.
+func (dw *DedupWriter) add(key []byte, put func() error) error {
+	dup, err := dw.duplicate(key)
+	if err != nil || dup {
+		return err
+	}
+
+	err = put()
+	if err == nil {
+		dw.remember(key)
+	}
+
+	return err
+}
+
+// duplicate checks whether key was already added, returning ErrDuplicateKey
+// if it was and the policy is RejectDuplicates. It fails if the Writer can no
+// longer be written to, since looking for the key would read its buffers.
+func (dw *DedupWriter) duplicate(key []byte) (bool, error) {
+	err := dw.writer.checkWritable()
+	if err != nil {
+		return false, err
+	}
+
+	key = dw.writer.opts.normalizeKey(key)
+
+	var dup bool
+	if dw.seen != nil {
+		_, dup = dw.seen[string(key)]
+	} else {
+		var err error
+		dup, err = dw.writer.contains(key)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if dup && dw.policy == RejectDuplicates {
+		return true, ErrDuplicateKey
+	}
+
+	return dup, nil
+}
+
+func (dw *DedupWriter) remember(key []byte) {
+	if dw.seen != nil {
+		dw.seen[string(dw.writer.opts.normalizeKey(key))] = struct{}{}
+	}
+}
+
+// contains checks whether a record with the given key has been written, by
+// reading back the keys of any records with the same hash. The stream must be
+// an io.ReaderAt.
+func (cdb *Writer) contains(key []byte) (bool, error) {
+	hash := cdb.hash(key)
+	flushed := false
+	for _, entry := range cdb.entries[hash&0xff] {
+		if entry.hash != hash {
+			continue
+		}
+
+		// The record may still be buffered.
+		if !flushed {
+			err := cdb.bufferedWriter.Flush()
+			if err != nil {
+				return false, err
+			}
+
+			flushed = true
+		}
+
+		_, match, err := matchKeyAt(cdb.writer.(io.ReaderAt), cdb.opts.order(), entry.offset, key)
+		if err != nil || match {
+			return match, err
+		}
+	}
+
+	return false, nil
+}
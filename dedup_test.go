@@ -0,0 +1,135 @@
+package cdb_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSeeker hides every method of a file other than Write and Seek.
+type writeSeeker struct {
+	f *os.File
+}
+
+func (ws writeSeeker) Write(b []byte) (int, error) {
+	return ws.f.Write(b)
+}
+
+func (ws writeSeeker) Seek(offset int64, whence int) (int64, error) {
+	return ws.f.Seek(offset, whence)
+}
+
+func testDedupWriter(t *testing.T, readable bool) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	var stream io.Writer = f
+	if !readable {
+		stream = writeSeeker{f}
+	}
+
+	writer, err := cdb.NewWriter(stream, fnvHash)
+	require.NoError(t, err)
+
+	dw := cdb.NewDedupWriter(writer, cdb.SkipDuplicates)
+	require.NoError(t, dw.Put([]byte("foo"), []byte("1")))
+	require.NoError(t, dw.Put([]byte("bar"), []byte("2")))
+	require.NoError(t, dw.Put([]byte("foo"), []byte("3")))
+	require.NoError(t, dw.PutReader([]byte("bar"), 1, strings.NewReader("4")))
+
+	// Keys that collide under the hash should still both be added.
+	require.NoError(t, dw.Put([]byte("costarring"), []byte("5")))
+	require.NoError(t, dw.Put([]byte("liquid"), []byte("6")))
+	require.NoError(t, dw.Put([]byte("liquid"), []byte("7")))
+
+	require.NoError(t, dw.Close())
+
+	f, err = os.Open(f.Name())
+	require.NoError(t, err)
+	defer f.Close()
+
+	db, err := cdb.New(f, fnvHash)
+	require.NoError(t, err)
+	assertRecords(t, db, [][2]string{{"foo", "1"}, {"bar", "2"}, {"costarring", "5"}, {"liquid", "6"}})
+}
+
+func TestDedupWriter(t *testing.T) {
+	testDedupWriter(t, true)
+}
+
+func TestDedupWriterInMemory(t *testing.T) {
+	testDedupWriter(t, false)
+}
+
+func TestDedupWriterDelete(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+
+	// Once a key is deleted, it can be added again.
+	dw := cdb.NewDedupWriter(writer, cdb.RejectDuplicates)
+	require.NoError(t, dw.Put([]byte("foo"), []byte("1")))
+	require.NoError(t, dw.Delete([]byte("foo")))
+	require.NoError(t, dw.Put([]byte("foo"), []byte("2")))
+
+	db, err := dw.Freeze()
+	require.NoError(t, err)
+	assertRecords(t, db, [][2]string{{"foo", "2"}})
+}
+
+func TestDedupWriterReject(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+
+	dw := cdb.NewDedupWriter(writer, cdb.RejectDuplicates)
+	require.NoError(t, dw.Put([]byte("foo"), []byte("1")))
+	assert.Equal(t, cdb.ErrDuplicateKey, dw.Put([]byte("foo"), []byte("2")))
+	require.NoError(t, dw.Close())
+}
+
+func TestDedupWriterPutAfterClose(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+
+	dw := cdb.NewDedupWriter(writer, cdb.SkipDuplicates)
+	require.NoError(t, dw.Put([]byte("foo"), []byte("1")))
+	require.NoError(t, dw.Close())
+
+	assert.Equal(t, cdb.ErrFinalized, dw.Put([]byte("foo"), []byte("2")))
+	assert.Equal(t, cdb.ErrFinalized, dw.PutReader([]byte("bar"), 1, strings.NewReader("x")))
+}
+
+func TestDedupWriterOtherPuts(t *testing.T) {
+	dw := cdb.NewDedupWriter(cdb.NewMem(cdb.WithExpiry()), cdb.RejectDuplicates)
+	require.NoError(t, dw.PutWithExpiry([]byte("foo"), []byte("1"), time.Now().Add(time.Hour)))
+	require.NoError(t, dw.PutString("bar", "2"))
+
+	assert.Equal(t, cdb.ErrDuplicateKey, dw.PutString("foo", "3"))
+	assert.Equal(t, cdb.ErrDuplicateKey, dw.PutWithExpiry([]byte("bar"), []byte("4"), time.Time{}))
+
+	db, err := dw.Freeze()
+	require.NoError(t, err)
+
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
@@ -0,0 +1,173 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"sort"
+)
+
+// deadRecord is a record that was written to the file, but later deleted.
+type deadRecord struct {
+	offset uint32
+	length uint32
+}
+
+// Delete removes any records previously added with the given key, so that
+// they won't appear in the finalized database. Records added with the same key
+// after Delete are kept, so replaying a log of Puts and Deletes yields
+// last-write-wins semantics.
+//
+// Deleted records are removed from the data section when the database is
+// finalized, which requires rewriting the records that follow them. Because
+// Delete needs to read back keys that were already written, the underlying
+// stream must also be an io.ReaderAt; if it isn't, Delete returns
+// os.ErrInvalid.
+func (cdb *Writer) Delete(key []byte) error {
+	err := cdb.checkWritable()
+	if err != nil {
+		return err
+	}
+
+	readerAt, ok := cdb.writer.(io.ReaderAt)
+	if !ok {
+		return os.ErrInvalid
+	}
+
+	key = cdb.opts.normalizeKey(key)
+	hash := cdb.hash(key)
+	table := hash & 0xff
+
+	// The records we need to compare against may still be buffered.
+	err = cdb.bufferedWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	entries := cdb.entries[table]
+	kept := entries[:0]
+	for _, entry := range entries {
+		if entry.hash == hash {
+			length, match, err := matchKeyAt(readerAt, cdb.opts.order(), entry.offset, key)
+			if err != nil {
+				return err
+			}
+
+			if match {
+				// Any padding before the next record goes with this one, so
+				// the records after it stay aligned.
+				length = uint32(cdb.opts.align(int64(entry.offset+length)) - int64(entry.offset))
+				cdb.dead = append(cdb.dead, deadRecord{offset: entry.offset, length: length})
+				cdb.estimatedFooterSize -= cdb.footerSizePerEntry()
+				cdb.records--
+				continue
+			}
+		}
+
+		kept = append(kept, entry)
+	}
+
+	cdb.entries[table] = kept
+	return nil
+}
+
+// matchKeyAt reads the record at offset, and checks whether its key is equal
+// to key. It returns the total length of the record.
+func matchKeyAt(r io.ReaderAt, order binary.ByteOrder, offset uint32, key []byte) (uint32, bool, error) {
+	keyLength, valueLength, err := readTuple(r, order, offset)
+	if err != nil {
+		return 0, false, err
+	}
+
+	length := 8 + keyLength + valueLength
+	if int(keyLength) != len(key) {
+		return length, false, nil
+	}
+
+	buf := make([]byte, keyLength)
+	_, err = r.ReadAt(buf, int64(offset+8))
+	if err != nil {
+		return 0, false, err
+	}
+
+	return length, bytes.Equal(buf, key), nil
+}
+
+// compact removes deleted records from the data section by shifting the
+// records after them towards the start of the file. The write position is
+// left at the new end of the data section.
+func (cdb *Writer) compact() error {
+	err := cdb.bufferedWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(cdb.dead, func(i, j int) bool {
+		return cdb.dead[i].offset < cdb.dead[j].offset
+	})
+
+	// Copy each run of live records down over the gap left by the dead ones.
+	// Since we're always copying towards the start of the file, we never
+	// overwrite data we haven't read yet.
+	readerAt := cdb.writer.(io.ReaderAt)
+	buf := make([]byte, 65536)
+	dst := int64(cdb.dead[0].offset)
+	for i, dead := range cdb.dead {
+		src := int64(dead.offset + dead.length)
+		end := cdb.bufferedOffset
+		if i+1 < len(cdb.dead) {
+			end = int64(cdb.dead[i+1].offset)
+		}
+
+		for src < end {
+			chunk := buf
+			if end-src < int64(len(chunk)) {
+				chunk = chunk[:end-src]
+			}
+
+			_, err := readerAt.ReadAt(chunk, src)
+			if err != nil {
+				return err
+			}
+
+			err = cdb.writeAt(chunk, dst)
+			if err != nil {
+				return err
+			}
+
+			src += int64(len(chunk))
+			dst += int64(len(chunk))
+		}
+	}
+
+	// Fix up the offsets in the hash tables to account for the shifted data.
+	shifts := make([]uint32, len(cdb.dead))
+	var total uint32
+	for i, dead := range cdb.dead {
+		total += dead.length
+		shifts[i] = total
+	}
+
+	for _, entries := range cdb.entries {
+		for i, entry := range entries {
+			n := sort.Search(len(cdb.dead), func(j int) bool {
+				return cdb.dead[j].offset > entry.offset
+			})
+
+			if n > 0 {
+				entries[i].offset -= shifts[n-1]
+			}
+		}
+	}
+
+	if truncater, ok := cdb.writer.(interface{ Truncate(int64) error }); ok {
+		err = truncater.Truncate(dst)
+		if err != nil {
+			return err
+		}
+	}
+
+	cdb.dead = nil
+	return cdb.resetBuffer(dst)
+}
@@ -0,0 +1,101 @@
+package cdb_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelete(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+
+	// Replay a changelog: every third key is deleted, and every sixth key is
+	// put back with a new value after being deleted.
+	expected := make(map[string]string)
+	var order []string
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		require.NoError(t, writer.Put([]byte(key), []byte("first")))
+		require.NoError(t, writer.Put([]byte(key), []byte("duplicate")))
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		if i%3 == 0 {
+			require.NoError(t, writer.Delete([]byte(key)))
+		}
+
+		if i%6 == 0 {
+			require.NoError(t, writer.Put([]byte(key), []byte("second")))
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		if i%3 != 0 {
+			expected[key] = "first"
+			order = append(order, key, key)
+		}
+	}
+
+	for i := 0; i < 1000; i += 6 {
+		key := strconv.Itoa(i)
+		expected[key] = "second"
+		order = append(order, key)
+	}
+
+	require.NoError(t, writer.Delete([]byte("not in the table")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		value, err := db.Get([]byte(key))
+		require.NoError(t, err)
+
+		if v, ok := expected[key]; ok {
+			assert.Equal(t, v, string(value), "while fetching "+key)
+		} else {
+			assert.Nil(t, value, "while fetching "+key)
+		}
+	}
+
+	n := 0
+	iter := db.Iter()
+	for iter.Next() {
+		require.True(t, n < len(order))
+		assert.Equal(t, order[n], string(iter.Key()))
+		n++
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, len(order), n)
+
+	count, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, len(order), count)
+}
+
+func TestDeleteRequiresReaderAt(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(struct{ io.WriteSeeker }{f}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	assert.Equal(t, os.ErrInvalid, writer.Delete([]byte("foo")))
+}
@@ -0,0 +1,76 @@
+package cdb
+
+import (
+	"bytes"
+)
+
+// Diff compares two databases, and calls fn for each key that was added,
+// removed, or changed between a and b. For a key that was added, oldValue is
+// nil; for a key that was removed, newValue is nil. If fn returns an error,
+// Diff stops and returns it.
+//
+// Keys are compared by the value Get would return, so only the first record
+// for each key is considered. Removed and changed keys are reported in the
+// order they're stored in a, followed by added keys in the order they're
+// stored in b.
+//
+// Diff streams through both databases, looking up each key in the other, so
+// its memory usage doesn't depend on the size of either database. Keys are
+// looked up as they're stored, without normalizing them again, so the two
+// databases should have been written with the same key options, such as
+// WithKeyHMAC.
+func Diff(a, b *CDB, fn func(key, oldValue, newValue []byte) error) error {
+	err := eachFirst(a, func(key, oldValue []byte) error {
+		newValue, err := b.get(b.hash(key), key)
+		if err != nil && err != ErrExpired {
+			return err
+		}
+
+		if newValue == nil || !bytes.Equal(oldValue, newValue) {
+			return fn(key, oldValue, newValue)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return eachFirst(b, func(key, newValue []byte) error {
+		oldValue, err := a.get(a.hash(key), key)
+		if err != nil && err != ErrExpired {
+			return err
+		}
+
+		if oldValue == nil {
+			return fn(key, nil, newValue)
+		}
+
+		return nil
+	})
+}
+
+// eachFirst calls fn with the key and value of each record that is the first
+// one for its key.
+func eachFirst(db *CDB, fn func(key, value []byte) error) error {
+	return db.EachRecord(func(rec Record) error {
+		key, err := rec.Key()
+		if err != nil {
+			return err
+		}
+
+		stored, offset, err := db.lookup(db.hash(key), key)
+		if err != nil {
+			return err
+		} else if stored == nil || offset != rec.Offset() {
+			return nil
+		}
+
+		value, err := db.decodeValue(key, stored)
+		if err != nil {
+			return err
+		}
+
+		return fn(key, value)
+	})
+}
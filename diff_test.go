@@ -0,0 +1,69 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeDB(t *testing.T, records [][2]string) *cdb.CDB {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+
+	for _, record := range records {
+		require.NoError(t, writer.Put([]byte(record[0]), []byte(record[1])))
+	}
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	return db
+}
+
+func TestDiff(t *testing.T) {
+	a := makeDB(t, [][2]string{
+		{"same", "1"},
+		{"changed", "old"},
+		{"removed", "x"},
+		{"dup", "first"},
+		{"dup", "second"},
+		{"empty", ""},
+	})
+
+	b := makeDB(t, [][2]string{
+		{"added", "y"},
+		{"dup", "first"},
+		{"changed", "new"},
+		{"same", "1"},
+		{"empty", ""},
+	})
+
+	var diffs [][3]string
+	err := cdb.Diff(a, b, func(key, oldValue, newValue []byte) error {
+		diff := [3]string{string(key), string(oldValue), string(newValue)}
+		if oldValue == nil {
+			diff[1] = "<nil>"
+		}
+
+		if newValue == nil {
+			diff[2] = "<nil>"
+		}
+
+		diffs = append(diffs, diff)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][3]string{
+		{"changed", "old", "new"},
+		{"removed", "x", "<nil>"},
+		{"added", "<nil>", "y"},
+	}, diffs)
+}
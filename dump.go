@@ -0,0 +1,109 @@
+package cdb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The width of the occupancy map printed for each table by DumpIndex.
+const dumpWidth = 64
+
+// Table describes one of the 256 hash tables in a database.
+type Table struct {
+	// Offset is the position of the table in the file.
+	Offset uint32
+	// Length is the number of slots in the table.
+	Length uint32
+}
+
+// Index is a copy of the index at the head of a database, which locates each
+// of the hash tables.
+type Index [256]Table
+
+// String returns a listing of the tables, one per line.
+func (idx Index) String() string {
+	var sb strings.Builder
+	for i, table := range idx {
+		fmt.Fprintf(&sb, "table %3d: offset %d, %d slots\n", i, table.Offset, table.Length)
+	}
+
+	return sb.String()
+}
+
+// Index returns a copy of the database's index.
+func (cdb *CDB) Index() Index {
+	var idx Index
+	for i, table := range cdb.index {
+		idx[i] = Table{Offset: table.offset, Length: table.length}
+	}
+
+	return idx
+}
+
+// DumpIndex writes a human-readable description of the hash tables to w, for
+// debugging. For each table, it prints the offset, the number of slots and
+// entries, the longest probe, and a map of which slots are in use: each
+// character of the map covers an equal share of the table, and is ' ' if the
+// slots are all empty, '#' if they're all full, and '.' otherwise. Long runs of
+// '#' mean long probe chains.
+func (cdb *CDB) DumpIndex(w io.Writer) error {
+	stats, err := cdb.Stats()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	for i, table := range cdb.index {
+		ts := stats.Tables[i]
+		fmt.Fprintf(bw, "table %3d: offset %d, %d/%d slots used, longest probe %d\n",
+			i, table.offset, ts.Entries, ts.Slots, ts.MaxProbeLength)
+		if table.length == 0 {
+			continue
+		}
+
+		occupancy, err := cdb.occupancy(table)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(bw, "  [%s]\n", occupancy)
+	}
+
+	return bw.Flush()
+}
+
+// occupancy returns the occupancy map for a table, as described by DumpIndex.
+func (cdb *CDB) occupancy(table table) (string, error) {
+	width := uint32(dumpWidth)
+	if table.length < width {
+		width = table.length
+	}
+
+	used := make([]uint32, width)
+	err := cdb.scanTable(table, func(slot, hash, offset uint32) {
+		used[uint64(slot)*uint64(width)/uint64(table.length)]++
+	})
+	if err != nil {
+		return "", err
+	}
+
+	cells := make([]byte, width)
+	for i := range cells {
+		// The number of slots covered by this cell.
+		start := (uint64(i)*uint64(table.length) + uint64(width) - 1) / uint64(width)
+		end := (uint64(i+1)*uint64(table.length) + uint64(width) - 1) / uint64(width)
+
+		switch uint64(used[i]) {
+		case 0:
+			cells[i] = ' '
+		case end - start:
+			cells[i] = '#'
+		default:
+			cells[i] = '.'
+		}
+	}
+
+	return string(cells), nil
+}
@@ -0,0 +1,47 @@
+package cdb_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	idx := db.Index()
+	slots := 0
+	for _, table := range idx {
+		assert.True(t, int64(table.Offset) >= cdb.DataOffset+db.DataSize())
+		slots += int(table.Length)
+	}
+
+	assert.EqualValues(t, db.TablesSize(), 8*slots)
+	assert.Equal(t, 256, strings.Count(idx.String(), "\n"))
+}
+
+func TestDumpIndex(t *testing.T) {
+	db := makeDB(t, [][2]string{{"foo", "bar"}})
+
+	var buf bytes.Buffer
+	require.NoError(t, db.DumpIndex(&buf))
+
+	// The table containing the key has two slots, one of which is used.
+	table := cdb.HashKey([]byte("foo")) & 0xff
+	expected := fmt.Sprintf("table %3d: offset %d, 1/2 slots used, longest probe 1\n", table, db.Index()[table].Offset)
+	assert.Contains(t, buf.String(), expected)
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, 256+1+1, len(lines))
+	for i, line := range lines {
+		if line+"\n" == expected {
+			assert.Contains(t, []string{"  [# ]", "  [ #]"}, lines[i+1])
+		}
+	}
+}
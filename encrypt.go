@@ -0,0 +1,114 @@
+package cdb
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+// ErrDecryption is returned when reading a value from a database opened
+// WithEncryption, if the value can't be decrypted and authenticated: for
+// example, because the wrong key was used, or the file was tampered with.
+var ErrDecryption = errors.New("value could not be decrypted")
+
+// ErrEncrypted is returned when opening a database without WithEncryption, if
+// its header says it was written with encryption.
+var ErrEncrypted = errors.New("database is encrypted")
+
+// WithEncryption causes values to be encrypted with aead when writing, and
+// decrypted when reading. Like compression, encryption is transparent: Get,
+// GetInto and Iterator all return the original values. Any cipher.AEAD can be
+// used, such as AES-GCM from crypto/cipher, or XChaCha20-Poly1305 from
+// golang.org/x/crypto; the key material stays with the caller.
+//
+// Each value is sealed with a random nonce, which is stored alongside it, and
+// with the key as additional data, so that values can't be moved between
+// records without being detected. Values that fail to decrypt cause
+// ErrDecryption. AES-GCM's 96-bit nonces are only safe to choose at random for
+// up to about 2^32 values per key, so a single key shouldn't be used for more
+// than that many records; XChaCha20-Poly1305 has no such limit.
+//
+// Values are compressed, if WithCompression is also used, before they're
+// encrypted. Expiration times written WithExpiry aren't encrypted, so that
+// expired records can be skipped without decrypting them. Keys are stored in
+// the clear, unless WithKeyHMAC is also used. Since the whole value is needed
+// to encrypt it, PutReader reads the value into memory first.
+func WithEncryption(aead cipher.AEAD) Option {
+	return func(o *options) {
+		o.aead = aead
+	}
+}
+
+// WithKeyHMAC causes every key to be replaced with its HMAC-SHA256 under
+// secret before it's used, after any normalizer registered with
+// WithKeyNormalizer, so that the keys in the file don't reveal anything about
+// the original keys. Lookups with the same secret work as usual, but keys read
+// back from the database, by Iter for example, are the HMACs, which can't be
+// looked up again.
+func WithKeyHMAC(secret []byte) Option {
+	return func(o *options) {
+		o.keyHMAC = secret
+	}
+}
+
+// hashKey replaces key with its HMAC, if WithKeyHMAC is set.
+func (o *options) hashKey(key []byte) []byte {
+	if o.keyHMAC == nil {
+		return key
+	}
+
+	mac := hmac.New(sha256.New, o.keyHMAC)
+	mac.Write(key)
+	return mac.Sum(nil)
+}
+
+// sealValue encrypts value, if there's a cipher, and returns it behind the
+// nonce.
+func (o *options) sealValue(key, value []byte) ([]byte, error) {
+	if o.aead == nil {
+		return value, nil
+	}
+
+	n := o.aead.NonceSize()
+	nonce := make([]byte, n, n+len(value)+o.aead.Overhead())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.aead.Seal(nonce, nonce, value, key), nil
+}
+
+// sealedSize returns the size of a value of n bytes once it's been encrypted.
+func (o *options) sealedSize(n int) int {
+	if o.aead == nil {
+		return n
+	}
+
+	return o.aead.NonceSize() + n + o.aead.Overhead()
+}
+
+// openValue reverses sealValue.
+func (o *options) openValue(key, value []byte) ([]byte, error) {
+	if o.aead == nil {
+		return value, nil
+	}
+
+	n := o.aead.NonceSize()
+	if len(value) < n {
+		return nil, ErrDecryption
+	}
+
+	plaintext, err := o.aead.Open(nil, value[:n], value[n:], key)
+	if err != nil {
+		return nil, ErrDecryption
+	} else if plaintext == nil {
+		// Get distinguishes empty values from missing ones.
+		plaintext = []byte{}
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,202 @@
+package cdb_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAEAD(t *testing.T, key string) cipher.AEAD {
+	block, err := aes.NewCipher([]byte(key))
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	return aead
+}
+
+func TestEncryption(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	aead := newAEAD(t, "0123456789abcdef")
+	opts := []cdb.Option{cdb.WithEncryption(aead), cdb.WithHeader()}
+	writer, err := cdb.NewWriter(f, nil, append(opts, cdb.WithCompression(cdb.Gzip), cdb.WithExpiry())...)
+	require.NoError(t, err)
+
+	value := strings.Repeat("secret value ", 10)
+	require.NoError(t, writer.Put([]byte("foo"), []byte(value)))
+	require.NoError(t, writer.PutReader([]byte("streamed"), 6, strings.NewReader("secret")))
+	require.NoError(t, writer.PutWithExpiry([]byte("expired"), []byte("secret"), time.Now().Add(-time.Hour)))
+	require.NoError(t, writer.Close())
+
+	data, err := ioutil.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(data, []byte("secret")))
+
+	db, err := cdb.Open(f.Name(), opts...)
+	require.NoError(t, err)
+	defer db.Close()
+
+	v, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, value, string(v))
+
+	buf := make([]byte, 64)
+	n, found, err := db.GetInto([]byte("streamed"), buf)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "secret", string(buf[:n]))
+
+	v, err = db.Get([]byte("expired"))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	iter := db.Iter()
+	require.True(t, iter.Next())
+	assert.Equal(t, value, string(iter.Value()))
+
+	rec, err := db.Record(1)
+	require.NoError(t, err)
+	v, err = rec.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(v))
+
+	// The header records that the database is encrypted.
+	_, err = cdb.Open(f.Name())
+	assert.Equal(t, cdb.ErrEncrypted, err)
+
+	wrong, err := cdb.Open(f.Name(), cdb.WithEncryption(newAEAD(t, "fedcba9876543210")))
+	require.NoError(t, err)
+	defer wrong.Close()
+
+	_, err = wrong.Get([]byte("foo"))
+	assert.Equal(t, cdb.ErrDecryption, err)
+}
+
+func TestEncryptionEmptyValue(t *testing.T) {
+	writer := cdb.NewMem(cdb.WithEncryption(newAEAD(t, "0123456789abcdef")))
+	require.NoError(t, writer.Put([]byte("empty"), []byte{}))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	v, err := db.Get([]byte("empty"))
+	require.NoError(t, err)
+	assert.NotNil(t, v)
+	assert.Empty(t, v)
+
+	v, err = db.GetStrict([]byte("empty"))
+	require.NoError(t, err)
+	assert.Empty(t, v)
+}
+
+func TestEncryptionBindsKeys(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	aead := newAEAD(t, "0123456789abcdef")
+	writer, err := cdb.NewWriter(f, nil, cdb.WithEncryption(aead))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("aaa"), []byte("one")))
+	require.NoError(t, writer.Put([]byte("bbb"), []byte("two")))
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	require.NoError(t, db.ReplaceInPlace([]byte("bbb"), []byte("new")))
+	v, err := db.Get([]byte("bbb"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(v))
+
+	a, err := db.Record(0)
+	require.NoError(t, err)
+	b, err := db.Record(1)
+	require.NoError(t, err)
+	require.Equal(t, a.ValueLength(), b.ValueLength())
+
+	data, err := ioutil.ReadFile(f.Name())
+	require.NoError(t, err)
+
+	// Swap the encrypted values of the two records.
+	aStart, bStart := a.Offset()+8+a.KeyLength(), b.Offset()+8+b.KeyLength()
+	aValue := append([]byte(nil), data[aStart:aStart+a.ValueLength()]...)
+	copy(data[aStart:], data[bStart:bStart+b.ValueLength()])
+	copy(data[bStart:], aValue)
+
+	swapped, err := cdb.FromBytes(data, cdb.WithEncryption(aead))
+	require.NoError(t, err)
+
+	_, err = swapped.Get([]byte("aaa"))
+	assert.Equal(t, cdb.ErrDecryption, err)
+}
+
+func TestKeyHMAC(t *testing.T) {
+	opts := []cdb.Option{cdb.WithKeyHMAC([]byte("hmac secret")), cdb.WithKeyNormalizer(cdb.LowercaseKeys)}
+	writer := cdb.NewMem(opts...)
+	require.NoError(t, writer.Put([]byte("Private-Key"), []byte("value")))
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	v, err := db.Get([]byte("private-key"))
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(v))
+
+	iter := db.Iter()
+	require.True(t, iter.Next())
+	assert.Len(t, iter.Key(), 32)
+	assert.NotContains(t, string(iter.Key()), "private")
+}
+
+func TestKeyHMACStoredKeys(t *testing.T) {
+	opts := []cdb.Option{cdb.WithKeyHMAC([]byte("hmac secret")), cdb.WithCompression(cdb.Gzip)}
+	build := func(records map[string]string) *cdb.CDB {
+		writer := cdb.NewMem(opts...)
+		for k, v := range records {
+			require.NoError(t, writer.Put([]byte(k), []byte(v)))
+		}
+
+		db, err := writer.Freeze()
+		require.NoError(t, err)
+		return db
+	}
+
+	a := build(map[string]string{"foo": "1", "bar": "2"})
+	b := build(map[string]string{"foo": "1", "bar": "3"})
+
+	buf := make([]byte, 8)
+	n, found, err := a.GetInto([]byte("foo"), buf)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "1", string(buf[:n]))
+
+	// Only bar changed, so only bar is reported.
+	var changed []string
+	require.NoError(t, cdb.Diff(a, b, func(key, oldValue, newValue []byte) error {
+		changed = append(changed, string(oldValue)+"->"+string(newValue))
+		return nil
+	}))
+	assert.Equal(t, []string{"2->3"}, changed)
+
+	overlay := cdb.NewOverlay(a)
+	overlay.Put([]byte("baz"), []byte("4"))
+	writer := cdb.NewMem(opts...)
+	require.NoError(t, overlay.Flatten(writer))
+	flat, err := writer.Freeze()
+	require.NoError(t, err)
+
+	for k, v := range map[string]string{"foo": "1", "bar": "2", "baz": "4"} {
+		value, err := flat.Get([]byte(k))
+		require.NoError(t, err)
+		assert.Equal(t, v, string(value), k)
+	}
+}
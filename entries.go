@@ -0,0 +1,36 @@
+package cdb
+
+import "math"
+
+// WithPreallocatedEntries tells a Writer to expect the database to hold about
+// n records in total, so that it can allocate the space it uses to keep track
+// of them up front. Without it, that space grows as records are added, which
+// for tens of millions of records means a lot of copying, and briefly needs
+// up to twice as much memory as the records themselves. Each record takes
+// eight bytes; if more than n records are written, the space grows as usual
+// from there.
+func WithPreallocatedEntries(n int64) Option {
+	return func(o *options) {
+		o.preallocatedEntries = n
+	}
+}
+
+// preallocateEntries makes room in each hash table's entries for its share of
+// the expected number of records, keeping any entries it already has.
+func (cdb *Writer) preallocateEntries() {
+	n := cdb.opts.preallocatedEntries
+	if n <= 0 {
+		return
+	}
+
+	// Keys are spread evenly across the tables by their hashes, so the number
+	// in each is close to the average. Three standard deviations of slack
+	// is enough that very few tables ever need to grow.
+	mean := float64(n) / 256
+	capacity := int(math.Ceil(mean + 3*math.Sqrt(mean)))
+	for i, entries := range cdb.entries {
+		if cap(entries) < capacity {
+			cdb.entries[i] = append(make([]entry, 0, capacity), entries...)
+		}
+	}
+}
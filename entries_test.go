@@ -0,0 +1,52 @@
+package cdb_test
+
+import (
+	"encoding/binary"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreallocatedEntries(t *testing.T) {
+	// Writing more records than expected is fine, too.
+	writer := cdb.NewMem(cdb.WithPreallocatedEntries(1000))
+	for i := 0; i < 5000; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value")))
+	}
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 5000, n)
+
+	for i := 0; i < 5000; i++ {
+		value, err := db.Get([]byte(strconv.Itoa(i)))
+		require.NoError(t, err)
+		assert.Equal(t, "value", string(value))
+	}
+}
+
+func benchmarkPutEntries(b *testing.B, opts ...cdb.Option) {
+	writer := cdb.NewMem(opts...)
+	key := make([]byte, 8)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		binary.LittleEndian.PutUint64(key, uint64(i))
+		writer.Put(key, nil)
+	}
+}
+
+func BenchmarkPutEntries(b *testing.B) {
+	benchmarkPutEntries(b)
+}
+
+func BenchmarkPutPreallocatedEntries(b *testing.B) {
+	benchmarkPutEntries(b, cdb.WithPreallocatedEntries(int64(b.N)))
+}
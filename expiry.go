@@ -0,0 +1,121 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+const expiryPrefixSize = 8
+
+// WithExpiry causes each value to be stored with an expiration time, set with
+// Writer.PutWithExpiry, and records to be treated as missing once they've
+// expired: Get and GetInto skip them, as do Iter and EachRecord. Expired
+// iterates over the expired records instead. Expiration times are stored to
+// the second, and compared against the system clock.
+//
+// A database written with this option must also be read with it. Records
+// added with Put or PutReader never expire.
+func WithExpiry() Option {
+	return func(o *options) {
+		o.expiry = true
+	}
+}
+
+// ErrExpired is returned along with the value by Get, GetStrict, and GetInto,
+// for a record that has expired, when the database was opened
+// WithShowExpired.
+var ErrExpired = errors.New("record has expired")
+
+// WithShowExpired makes expired records visible, for debugging why a key is
+// missing without having to dump the file. Get, GetStrict, and GetInto return
+// the value of an expired record along with ErrExpired, and Iter, Each, and
+// the other ways of iterating include expired records along with the rest.
+// Expired still iterates over only the expired records.
+//
+// This is the only way a record can be hidden in a finished file: records
+// removed with Writer.Delete are dropped when the database is finalized. The
+// option has no effect without WithExpiry.
+func WithShowExpired() Option {
+	return func(o *options) {
+		o.showExpired = true
+	}
+}
+
+// PutWithExpiry adds a key/value pair to the database that is treated as
+// missing after expires. If expires is the zero time, it never expires. The
+// Writer must have been created WithExpiry; otherwise, the expiration time is
+// ignored.
+func (cdb *Writer) PutWithExpiry(key, value []byte, expires time.Time) error {
+	return cdb.put(key, value, expires)
+}
+
+// Expired returns an Iterator over the records that have expired, for
+// databases opened WithExpiry. For other databases, it returns no records.
+func (cdb *CDB) Expired() *Iterator {
+	iter := cdb.Iter()
+	iter.expired = true
+	if !cdb.opts.expiry {
+		iter.endPos = iter.pos
+	}
+
+	return iter
+}
+
+// expiryPrefix encodes an expiration time as a value prefix: 0 for none, and
+// otherwise seconds since the Unix epoch.
+func expiryPrefix(expires time.Time) []byte {
+	prefix := make([]byte, expiryPrefixSize)
+	if !expires.IsZero() {
+		binary.LittleEndian.PutUint64(prefix, uint64(expires.Unix()))
+	}
+
+	return prefix
+}
+
+// expired returns true if the given value, as stored, has expired and should
+// be hidden, which it isn't WithShowExpired.
+func (cdb *CDB) expired(value []byte) bool {
+	return !cdb.opts.showExpired && cdb.hasExpired(value)
+}
+
+// hasExpired returns true if the given value, as stored, has expired.
+func (cdb *CDB) hasExpired(value []byte) bool {
+	if !cdb.opts.expiry || len(value) < expiryPrefixSize {
+		return false
+	}
+
+	expires := int64(binary.LittleEndian.Uint64(value))
+	return expires != 0 && time.Now().Unix() >= expires
+}
+
+// expiresAt returns the expiration time of the given value, as stored, or the
+// zero time if it never expires.
+func (cdb *CDB) expiresAt(value []byte) time.Time {
+	if !cdb.opts.expiry || len(value) < expiryPrefixSize {
+		return time.Time{}
+	}
+
+	expires := int64(binary.LittleEndian.Uint64(value))
+	if expires == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(expires, 0)
+}
+
+// recordExpired reads the expiration time of a record, and returns true if it
+// has expired.
+func (cdb *CDB) recordExpired(rec Record) (bool, error) {
+	if !cdb.opts.expiry || rec.valueLength < expiryPrefixSize {
+		return false, nil
+	}
+
+	prefix := make([]byte, expiryPrefixSize)
+	_, err := rec.reader.ReadAt(prefix, int64(rec.offset+8+rec.keyLength))
+	if err != nil {
+		return false, readError(ErrCorruptRecord, int64(rec.offset), err)
+	}
+
+	return cdb.hasExpired(prefix), nil
+}
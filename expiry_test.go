@@ -0,0 +1,167 @@
+package cdb_test
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiry(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		opts := []cdb.Option{cdb.WithExpiry()}
+		if compress {
+			opts = append(opts, cdb.WithCompression(cdb.Gzip))
+		}
+
+		writer := cdb.NewMem(opts...)
+		past := time.Now().Add(-time.Hour)
+		future := time.Now().Add(time.Hour)
+
+		require.NoError(t, writer.Put([]byte("forever"), []byte("1")))
+		require.NoError(t, writer.PutWithExpiry([]byte("expired"), []byte("2"), past))
+		require.NoError(t, writer.PutWithExpiry([]byte("later"), []byte(strings.Repeat("3", 100)), future))
+		require.NoError(t, writer.PutReader([]byte("streamed"), 1, strings.NewReader("4")))
+
+		// An expired record doesn't shadow later records with the same key.
+		require.NoError(t, writer.PutWithExpiry([]byte("replaced"), []byte("old"), past))
+		require.NoError(t, writer.Put([]byte("replaced"), []byte("new")))
+
+		db, err := writer.Freeze()
+		require.NoError(t, err)
+
+		assertRecords(t, db, [][2]string{
+			{"forever", "1"},
+			{"later", strings.Repeat("3", 100)},
+			{"streamed", "4"},
+			{"replaced", "new"},
+		})
+
+		value, err := db.Get([]byte("expired"))
+		require.NoError(t, err)
+		assert.Nil(t, value)
+
+		value, err = db.Get([]byte("replaced"))
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(value))
+
+		_, found, err := db.GetInto([]byte("expired"), make([]byte, 8))
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		var expired []string
+		iter := db.Expired()
+		for iter.Next() {
+			expired = append(expired, string(iter.Key())+"="+string(iter.Value()))
+		}
+
+		require.NoError(t, iter.Err())
+		assert.Equal(t, []string{"expired=2", "replaced=old"}, expired)
+
+		var live []string
+		err = db.EachRecord(func(rec cdb.Record) error {
+			value, err := rec.Value()
+			live = append(live, string(value))
+			return err
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1", strings.Repeat("3", 100), "4", "new"}, live)
+	}
+}
+
+// newExpiringDB returns a database opened WithExpiry, with a record that
+// never expires, and one that expires at the returned time.
+func newExpiringDB(t *testing.T) (*cdb.CDB, time.Time) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	writer := cdb.NewMem(cdb.WithExpiry())
+	require.NoError(t, writer.Put([]byte("forever"), []byte("1")))
+	require.NoError(t, writer.PutWithExpiry([]byte("expiring"), []byte("2"), expires))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	return db, expires
+}
+
+// assertExpiry checks the expiration time stored with a record, which is
+// the zero time for records that never expire.
+func assertExpiry(t *testing.T, db *cdb.CDB, key string, expected time.Time) {
+	found := false
+	err := db.EachRecord(func(rec cdb.Record) error {
+		k, err := rec.Key()
+		if err != nil || string(k) != key {
+			return err
+		}
+
+		prefix := make([]byte, 8)
+		_, err = io.ReadFull(rec.ValueReader(), prefix)
+		require.NoError(t, err)
+
+		var expires time.Time
+		if seconds := binary.LittleEndian.Uint64(prefix); seconds != 0 {
+			expires = time.Unix(int64(seconds), 0)
+		}
+
+		assert.True(t, expected.Equal(expires), "expected %q to expire at %v, not %v", key, expected, expires)
+		found = true
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.True(t, found, "expected a record for %q", key)
+}
+
+func TestShowExpired(t *testing.T) {
+	writer := cdb.NewMem(cdb.WithExpiry(), cdb.WithShowExpired())
+	require.NoError(t, writer.Put([]byte("live"), []byte("1")))
+	require.NoError(t, writer.PutWithExpiry([]byte("expired"), []byte("2"), time.Now().Add(-time.Hour)))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	v, err := db.Get([]byte("live"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(v))
+
+	v, err = db.Get([]byte("expired"))
+	assert.Equal(t, cdb.ErrExpired, err)
+	assert.Equal(t, "2", string(v))
+
+	v, err = db.GetStrict([]byte("expired"))
+	assert.Equal(t, cdb.ErrExpired, err)
+	assert.Equal(t, "2", string(v))
+
+	buf := make([]byte, 8)
+	n, found, err := db.GetInto([]byte("expired"), buf)
+	assert.Equal(t, cdb.ErrExpired, err)
+	assert.True(t, found)
+	assert.Equal(t, "2", string(buf[:n]))
+
+	var keys []string
+	iter := db.Iter()
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	require.NoError(t, iter.Err())
+	assert.Equal(t, []string{"live", "expired"}, keys)
+
+	keys = nil
+	require.NoError(t, db.EachKey(func(key []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	}))
+	assert.Equal(t, []string{"live", "expired"}, keys)
+
+	keys = nil
+	iter = db.Expired()
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	require.NoError(t, iter.Err())
+	assert.Equal(t, []string{"expired"}, keys)
+}
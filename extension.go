@@ -0,0 +1,205 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Extensions are stored in a block immediately after the hash tables, where
+// classic readers, which only look at the index and the regions it points to,
+// ignore them. The block starts with extensionMagic, followed by any number
+// of sections, each with an id and a length (little-endian uint32s) and then
+// the section's data. A section with an id and length of zero ends the block.
+// Readers skip sections they don't recognize.
+var extensionMagic = []byte("cdbext\x00\x01")
+
+const (
+	sectionEnd       = 0
+	sectionBloom     = 1
+	sectionHeader    = 2
+	sectionRecords   = 3
+	sectionMetadata  = 4
+	sectionAlignment = 5
+)
+
+// extensionSection is a single section in the extension block.
+type extensionSection struct {
+	id   uint32
+	data []byte
+}
+
+// extensionSections returns the sections to write, based on the options.
+func (cdb *Writer) extensionSections() []extensionSection {
+	var sections []extensionSection
+	if cdb.opts.header {
+		sections = append(sections, extensionSection{sectionHeader, cdb.buildHeader()})
+	}
+
+	if cdb.opts.bloomBitsPerKey > 0 {
+		sections = append(sections, extensionSection{sectionBloom, cdb.buildBloom()})
+	}
+
+	if cdb.opts.recordIndex {
+		sections = append(sections, extensionSection{sectionRecords, cdb.buildRecordIndex()})
+	}
+
+	if len(cdb.metadata) > 0 {
+		sections = append(sections, extensionSection{sectionMetadata, cdb.buildMetadata()})
+	}
+
+	if cdb.opts.alignment > 0 {
+		sections = append(sections, extensionSection{sectionAlignment, cdb.buildAlignment()})
+	}
+
+	return sections
+}
+
+// writeExtensions writes the extension block, if there are any sections, at
+// the current position, which must be the end of the hash tables.
+func (cdb *Writer) writeExtensions() error {
+	sections := cdb.extensionSections()
+	if len(sections) == 0 {
+		return nil
+	}
+
+	sections = append(sections, extensionSection{id: sectionEnd})
+	_, err := cdb.bufferedWriter.Write(extensionMagic)
+	if err != nil {
+		return err
+	}
+
+	cdb.bufferedOffset += int64(len(extensionMagic))
+	for _, section := range sections {
+		err := writeTuple(cdb.bufferedWriter, binary.LittleEndian, section.id, uint32(len(section.data)))
+		if err != nil {
+			return err
+		}
+
+		_, err = cdb.bufferedWriter.Write(section.data)
+		if err != nil {
+			return err
+		}
+
+		cdb.bufferedOffset += 8 + int64(len(section.data))
+	}
+
+	return nil
+}
+
+// extensionsSize returns the size of the extension block for a database with
+// the given number of records.
+func (cdb *Writer) extensionsSize(records int64) int64 {
+	var size int64
+	if cdb.opts.header {
+		size += 8 + headerSize
+	}
+
+	if cdb.opts.bloomBitsPerKey > 0 {
+		size += 8 + bloomSize(records, cdb.opts.bloomBitsPerKey)
+	}
+
+	if cdb.opts.recordIndex {
+		size += 8 + 4*records
+	}
+
+	if len(cdb.metadata) > 0 {
+		size += 8 + metadataSize(cdb.metadata)
+	}
+
+	if cdb.opts.alignment > 0 {
+		size += 8 + 4
+	}
+
+	if size > 0 {
+		size += int64(len(extensionMagic)) + 8
+	}
+
+	return size
+}
+
+// readExtensions looks for an extension block after the hash tables, and
+// loads any sections it recognizes. Files without a block are left as they
+// are.
+//
+// The block is only read if the size of the underlying reader is known, so
+// that opening a file without one never costs more than reading the index.
+func (cdb *CDB) readExtensions(size int64, sizeKnown bool) error {
+	_, end := cdb.tablesRegion()
+	offset := int64(end)
+	if !sizeKnown || offset+int64(len(extensionMagic)) > size {
+		return nil
+	}
+
+	magic := make([]byte, len(extensionMagic))
+	_, err := cdb.reader.ReadAt(magic, offset)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil
+	} else if err != nil {
+		return err
+	} else if !bytes.Equal(magic, extensionMagic) {
+		return nil
+	}
+
+	offset += int64(len(extensionMagic))
+	header := make([]byte, 8)
+	for {
+		_, err := cdb.reader.ReadAt(header, offset)
+		if err != nil {
+			return readError(ErrCorruptIndex, offset, err)
+		}
+
+		id := binary.LittleEndian.Uint32(header)
+		length := binary.LittleEndian.Uint32(header[4:])
+		offset += 8
+		if id == sectionEnd {
+			break
+		} else if offset+int64(length) > size {
+			return corruptAt(ErrCorruptIndex, offset-8)
+		}
+
+		// The record index can be large, so it's only read when it's needed.
+		if id == sectionRecords {
+			cdb.records.section = offset
+			cdb.records.length = length
+		} else if id == sectionBloom || id == sectionHeader || id == sectionMetadata || id == sectionAlignment {
+			data := make([]byte, length)
+			_, err := cdb.reader.ReadAt(data, offset)
+			if err != nil {
+				return readError(ErrCorruptIndex, offset, err)
+			}
+
+			err = cdb.loadSection(id, data)
+			if err == ErrCorrupt {
+				return corruptAt(ErrCorruptIndex, offset)
+			} else if err != nil {
+				return err
+			}
+		}
+
+		offset += int64(length)
+	}
+
+	if cdb.header != nil {
+		return cdb.applyHeader()
+	}
+
+	return nil
+}
+
+// loadSection parses a section read by readExtensions.
+func (cdb *CDB) loadSection(id uint32, data []byte) error {
+	var err error
+	switch id {
+	case sectionBloom:
+		cdb.bloom, err = parseBloom(data)
+	case sectionHeader:
+		cdb.header, err = parseHeader(data)
+	case sectionMetadata:
+		cdb.metadata, err = parseMetadata(data)
+	case sectionAlignment:
+		err = cdb.parseAlignment(data)
+	}
+
+	return err
+}
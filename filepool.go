@@ -0,0 +1,101 @@
+package cdb
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// WithFilePool causes Open and NewReloader to open n file descriptors for the
+// database, instead of one, and to spread reads across them in turn. At very
+// high concurrency, reads through a single descriptor can contend in the
+// kernel, for example on the file's reference count; striping them across
+// several avoids that. It has no effect if n is less than two, or on databases
+// opened with New.
+//
+// Each descriptor counts towards the process's limit on open files.
+func WithFilePool(n int) Option {
+	return func(o *options) {
+		o.filePool = n
+	}
+}
+
+// A readFile is an open file that a database can be read from.
+type readFile interface {
+	io.ReaderAt
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+// openReadFile opens the file at path for reading, with as many descriptors
+// as the options call for.
+func openReadFile(path string, o options) (readFile, error) {
+	if o.filePool < 2 {
+		f, err := openFile(path, os.O_RDONLY, o)
+		if err != nil {
+			return nil, err
+		}
+
+		return f, nil
+	}
+
+	pool := &filePool{files: make([]*os.File, 0, o.filePool)}
+	for i := 0; i < o.filePool; i++ {
+		f, err := openFile(path, os.O_RDONLY, o)
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+
+		pool.files = append(pool.files, f)
+	}
+
+	// If the path was replaced while the descriptors were being opened, they
+	// might not all refer to the same file.
+	first, err := pool.files[0].Stat()
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	for _, f := range pool.files[1:] {
+		info, err := f.Stat()
+		if err != nil {
+			pool.Close()
+			return nil, err
+		} else if !os.SameFile(first, info) {
+			pool.Close()
+			return openReadFile(path, o)
+		}
+	}
+
+	return pool, nil
+}
+
+// filePool reads from several descriptors for the same file, in turn.
+type filePool struct {
+	files []*os.File
+	next  uint32
+}
+
+func (fp *filePool) ReadAt(b []byte, off int64) (int, error) {
+	i := atomic.AddUint32(&fp.next, 1) % uint32(len(fp.files))
+	return fp.files[i].ReadAt(b, off)
+}
+
+func (fp *filePool) Stat() (os.FileInfo, error) {
+	return fp.files[0].Stat()
+}
+
+// Close closes every descriptor, and returns the first error, if any.
+func (fp *filePool) Close() error {
+	var firstErr error
+	for _, f := range fp.files {
+		err := f.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,57 @@
+package cdb_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePool(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb", cdb.WithFilePool(4))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		for _, record := range expectedRecords {
+			v, err := db.Get(record[0])
+			require.NoError(t, err)
+			assert.Equal(t, string(record[1]), string(v))
+		}
+	}
+
+	require.NoError(t, db.Close())
+	_, err = db.Get([]byte("foo"))
+	assert.Error(t, err)
+}
+
+func TestFilePoolReloader(t *testing.T) {
+	r, err := cdb.NewReloader("./test/test.cdb", cdb.WithFilePool(2))
+	require.NoError(t, err)
+	defer r.Close()
+
+	v, err := r.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(v))
+}
+
+func BenchmarkGetParallelFilePool(b *testing.B) {
+	for _, n := range []int{1, 4, 16} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			db, err := cdb.Open("./test/test.cdb", cdb.WithFilePool(n))
+			require.NoError(b, err)
+			defer db.Close()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					db.Get(expectedRecords[i%len(expectedRecords)][0])
+					i++
+				}
+			})
+		})
+	}
+}
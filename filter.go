@@ -0,0 +1,369 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// ErrInvalidFilter is returned by ParseFilter for data that isn't a filter
+// built by BuildFilter.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// FilterKind is the kind of probabilistic filter built by BuildFilter.
+type FilterKind int
+
+const (
+	// BloomFilter is a classic bloom filter. It's the smaller of the two for
+	// moderate false positive rates, such as 1%.
+	BloomFilter FilterKind = iota
+	// CuckooFilter stores a short fingerprint of each key in a cuckoo hash
+	// table. It takes less space than a bloom filter for very low false
+	// positive rates, and only needs at most two memory accesses per lookup.
+	CuckooFilter
+)
+
+const (
+	// The number of fingerprints in each bucket of a cuckoo filter, how full
+	// the buckets are allowed to get, and how many fingerprints to evict when
+	// inserting before giving up.
+	cuckooBucketSize = 4
+	cuckooLoadFactor = 0.9
+	cuckooMaxKicks   = 500
+
+	// A serialized filter starts with its kind, followed by the number of
+	// hash functions for a bloom filter, or the size of each fingerprint and
+	// the number of buckets for a cuckoo filter.
+	filterHeaderBloom  = 5
+	filterHeaderCuckoo = 6
+)
+
+// Filter is a probabilistic set of keys, built by BuildFilter and loaded with
+// ParseFilter. Contains never returns false for a key in the set, but returns
+// true for other keys with roughly the false positive rate the filter was
+// built with.
+//
+// A Filter is meant to be shipped to services that would otherwise make a
+// remote lookup for every key, so that they can skip lookups for keys that
+// definitely aren't there. It doesn't depend on the database's hash function
+// or options, and is safe for concurrent use.
+type Filter struct {
+	kind FilterKind
+
+	// For bloom filters.
+	hashes uint32
+	bits   []byte
+
+	// For cuckoo filters.
+	fpSize  int
+	buckets uint32
+	slots   []byte
+}
+
+// BuildFilter returns a serialized filter of the given kind over every key in
+// the database, with a false positive rate of about fpRate, which must be
+// between 0 and 1. Keys are added as they're stored, so if the database was
+// opened with WithKeyNormalizer, callers of Contains should normalize keys
+// the same way. As when iterating, records hidden by a view or that have
+// expired are left out.
+//
+// BuildFilter returns os.ErrInvalid for an unknown kind or an out of range
+// fpRate.
+func (cdb *CDB) BuildFilter(kind FilterKind, fpRate float64) ([]byte, error) {
+	if !(fpRate > 0 && fpRate < 1) {
+		return nil, os.ErrInvalid
+	}
+
+	var hashes []uint64
+	err := cdb.EachKey(func(key []byte) error {
+		hashes = append(hashes, filterHash(key))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys can appear more than once, but should only be added once.
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	unique := hashes[:0]
+	for i, h := range hashes {
+		if i == 0 || h != hashes[i-1] {
+			unique = append(unique, h)
+		}
+	}
+
+	switch kind {
+	case BloomFilter:
+		return buildBloomFilter(unique, fpRate), nil
+	case CuckooFilter:
+		return buildCuckooFilter(unique, fpRate), nil
+	default:
+		return nil, os.ErrInvalid
+	}
+}
+
+// ParseFilter loads a filter returned by BuildFilter. The filter refers to
+// data, rather than copying it, so data mustn't be modified afterwards.
+func ParseFilter(data []byte) (*Filter, error) {
+	if len(data) < 1 {
+		return nil, ErrInvalidFilter
+	}
+
+	f := &Filter{kind: FilterKind(data[0])}
+	switch f.kind {
+	case BloomFilter:
+		if len(data) <= filterHeaderBloom {
+			return nil, ErrInvalidFilter
+		}
+
+		f.hashes = binary.LittleEndian.Uint32(data[1:])
+		f.bits = data[filterHeaderBloom:]
+		if f.hashes < 1 || f.hashes > maxBloomHashes {
+			return nil, ErrInvalidFilter
+		}
+	case CuckooFilter:
+		if len(data) < filterHeaderCuckoo {
+			return nil, ErrInvalidFilter
+		}
+
+		f.fpSize = int(data[1])
+		f.buckets = binary.LittleEndian.Uint32(data[2:])
+		f.slots = data[filterHeaderCuckoo:]
+		if (f.fpSize != 1 && f.fpSize != 2 && f.fpSize != 4) ||
+			f.buckets == 0 || f.buckets&(f.buckets-1) != 0 ||
+			int64(len(f.slots)) != int64(f.buckets)*cuckooBucketSize*int64(f.fpSize) {
+			return nil, ErrInvalidFilter
+		}
+	default:
+		return nil, ErrInvalidFilter
+	}
+
+	return f, nil
+}
+
+// Kind returns the kind of the filter.
+func (f *Filter) Kind() FilterKind {
+	return f.kind
+}
+
+// Contains returns false if the key definitely wasn't in the database the
+// filter was built from, and true if it probably was.
+func (f *Filter) Contains(key []byte) bool {
+	h := filterHash(key)
+	if f.kind == BloomFilter {
+		return bloomContains(f.bits, f.hashes, h)
+	}
+
+	mask := f.buckets - 1
+	i1, fp := cuckooIndex(h, mask, f.fpSize)
+	i2 := cuckooAlt(i1, fp, mask)
+	for _, i := range [2]uint32{i1, i2} {
+		for s := uint32(0); s < cuckooBucketSize; s++ {
+			if f.slot(i*cuckooBucketSize+s) == fp {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// slot returns the fingerprint stored in the nth slot of a cuckoo filter.
+func (f *Filter) slot(n uint32) uint32 {
+	b := f.slots[int(n)*f.fpSize:]
+	switch f.fpSize {
+	case 1:
+		return uint32(b[0])
+	case 2:
+		return uint32(binary.LittleEndian.Uint16(b))
+	default:
+		return binary.LittleEndian.Uint32(b)
+	}
+}
+
+// filterHash is 64-bit FNV-1a. Filters use their own hash, rather than the
+// database's, so that they can be checked without knowing how the database
+// was written, and so that 32-bit hash collisions don't add to the false
+// positive rate.
+func filterHash(key []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, c := range key {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+
+	return h
+}
+
+// bloomPositions calls fn with each of the bits for a hash in a bloom filter
+// of nbits bits, using double hashing.
+func bloomPositions(nbits uint64, hashes uint32, h uint64, fn func(bit uint64) bool) {
+	h1, h2 := uint64(fmix32(uint32(h))), uint64(fmix32(uint32(h>>32))|1)
+	for i := uint64(0); i < uint64(hashes); i++ {
+		if !fn((h1 + i*h2) % nbits) {
+			return
+		}
+	}
+}
+
+func buildBloomFilter(hashes []uint64, fpRate float64) []byte {
+	// These are the textbook optimal size and number of hash functions.
+	n := float64(len(hashes))
+	nbits := int64(math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if nbits < 64 {
+		nbits = 64
+	}
+
+	k := uint32(1)
+	if len(hashes) > 0 {
+		k = uint32(math.Round(float64(nbits) / n * math.Ln2))
+	}
+
+	if k < 1 {
+		k = 1
+	} else if k > maxBloomHashes {
+		k = maxBloomHashes
+	}
+
+	data := make([]byte, filterHeaderBloom+(nbits+7)/8)
+	data[0] = byte(BloomFilter)
+	binary.LittleEndian.PutUint32(data[1:], k)
+	bits := data[filterHeaderBloom:]
+	for _, h := range hashes {
+		bloomPositions(uint64(len(bits))*8, k, h, func(bit uint64) bool {
+			bits[bit/8] |= 1 << (bit % 8)
+			return true
+		})
+	}
+
+	return data
+}
+
+func bloomContains(bits []byte, hashes uint32, h uint64) bool {
+	found := true
+	bloomPositions(uint64(len(bits))*8, hashes, h, func(bit uint64) bool {
+		found = bits[bit/8]&(1<<(bit%8)) != 0
+		return found
+	})
+
+	return found
+}
+
+// cuckooIndex returns the primary bucket and the fingerprint for a hash. The
+// fingerprint is never zero, which marks an empty slot.
+func cuckooIndex(h uint64, mask uint32, fpSize int) (uint32, uint32) {
+	fp := uint32(h >> 32)
+	if fpSize < 4 {
+		fp &= 1<<(8*uint(fpSize)) - 1
+	}
+
+	if fp == 0 {
+		fp = 1
+	}
+
+	return uint32(h) & mask, fp
+}
+
+// cuckooAlt returns the other bucket for a fingerprint in bucket i. It's its
+// own inverse, so an entry can be moved between its buckets knowing only the
+// fingerprint.
+func cuckooAlt(i, fp, mask uint32) uint32 {
+	return (i ^ fmix32(fp)) & mask
+}
+
+func buildCuckooFilter(hashes []uint64, fpRate float64) []byte {
+	// A lookup compares against up to 2*cuckooBucketSize fingerprints, each
+	// of which matches by chance with probability 2^-bits.
+	fpSize := 4
+	for _, size := range []int{1, 2} {
+		if 2*cuckooBucketSize/math.Exp2(float64(8*size)) <= fpRate {
+			fpSize = size
+			break
+		}
+	}
+
+	buckets := uint32(1)
+	for float64(buckets)*cuckooBucketSize*cuckooLoadFactor < float64(len(hashes)) {
+		buckets <<= 1
+	}
+
+	// Inserting can fail if too many keys land in the same buckets, in which
+	// case the table is doubled and the keys are inserted again.
+	slots, ok := cuckooInsertAll(hashes, buckets, fpSize)
+	for !ok {
+		buckets <<= 1
+		slots, ok = cuckooInsertAll(hashes, buckets, fpSize)
+	}
+
+	data := make([]byte, filterHeaderCuckoo+len(slots)*fpSize)
+	data[0] = byte(CuckooFilter)
+	data[1] = byte(fpSize)
+	binary.LittleEndian.PutUint32(data[2:], buckets)
+	out := data[filterHeaderCuckoo:]
+	for n, fp := range slots {
+		switch fpSize {
+		case 1:
+			out[n] = byte(fp)
+		case 2:
+			binary.LittleEndian.PutUint16(out[n*2:], uint16(fp))
+		default:
+			binary.LittleEndian.PutUint32(out[n*4:], fp)
+		}
+	}
+
+	return data
+}
+
+// cuckooInsertAll builds the slots for a cuckoo filter with the given number
+// of buckets, or returns false if they don't all fit.
+func cuckooInsertAll(hashes []uint64, buckets uint32, fpSize int) ([]uint32, bool) {
+	mask := buckets - 1
+	slots := make([]uint32, buckets*cuckooBucketSize)
+	insert := func(i, fp uint32) bool {
+		bucket := slots[i*cuckooBucketSize : (i+1)*cuckooBucketSize]
+		for s := range bucket {
+			if bucket[s] == 0 {
+				bucket[s] = fp
+				return true
+			}
+		}
+
+		return false
+	}
+
+	// The same seed makes the same filter from the same keys.
+	rnd := rand.New(rand.NewSource(1))
+	for _, h := range hashes {
+		i, fp := cuckooIndex(h, mask, fpSize)
+		alt := cuckooAlt(i, fp, mask)
+		if insert(i, fp) || insert(alt, fp) {
+			continue
+		}
+
+		// Evict fingerprints to their other buckets until one of them has
+		// room.
+		if rnd.Intn(2) == 1 {
+			i = alt
+		}
+
+		placed := false
+		for kick := 0; kick < cuckooMaxKicks; kick++ {
+			s := i*cuckooBucketSize + uint32(rnd.Intn(cuckooBucketSize))
+			fp, slots[s] = slots[s], fp
+			i = cuckooAlt(i, fp, mask)
+			if insert(i, fp) {
+				placed = true
+				break
+			}
+		}
+
+		if !placed {
+			return nil, false
+		}
+	}
+
+	return slots, true
+}
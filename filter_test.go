@@ -0,0 +1,80 @@
+package cdb_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildFilter(t *testing.T) {
+	writer := cdb.NewMem()
+	for i := 0; i < 10000; i++ {
+		require.NoError(t, writer.Put([]byte("key"+strconv.Itoa(i)), []byte("value")))
+	}
+
+	// Duplicates are only added once.
+	require.NoError(t, writer.Put([]byte("key0"), []byte("again")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	for _, kind := range []cdb.FilterKind{cdb.BloomFilter, cdb.CuckooFilter} {
+		for _, fpRate := range []float64{0.01, 0.0001} {
+			data, err := db.BuildFilter(kind, fpRate)
+			require.NoError(t, err)
+
+			filter, err := cdb.ParseFilter(data)
+			require.NoError(t, err)
+			assert.Equal(t, kind, filter.Kind())
+
+			for i := 0; i < 10000; i++ {
+				require.True(t, filter.Contains([]byte("key"+strconv.Itoa(i))), "key%d", i)
+			}
+
+			falsePositives := 0
+			for i := 0; i < 100000; i++ {
+				if filter.Contains([]byte("missing" + strconv.Itoa(i))) {
+					falsePositives++
+				}
+			}
+
+			rate := float64(falsePositives) / 100000
+			assert.True(t, rate < 2*fpRate, "kind %d: false positive rate %f for %f", kind, rate, fpRate)
+		}
+	}
+}
+
+func TestBuildFilterEmpty(t *testing.T) {
+	db, err := cdb.NewMem().Freeze()
+	require.NoError(t, err)
+
+	for _, kind := range []cdb.FilterKind{cdb.BloomFilter, cdb.CuckooFilter} {
+		data, err := db.BuildFilter(kind, 0.01)
+		require.NoError(t, err)
+
+		filter, err := cdb.ParseFilter(data)
+		require.NoError(t, err)
+		assert.False(t, filter.Contains([]byte("foo")))
+	}
+}
+
+func TestBuildFilterInvalid(t *testing.T) {
+	db, err := cdb.NewMem().Freeze()
+	require.NoError(t, err)
+
+	_, err = db.BuildFilter(cdb.BloomFilter, 0)
+	assert.Equal(t, os.ErrInvalid, err)
+	_, err = db.BuildFilter(cdb.BloomFilter, 1)
+	assert.Equal(t, os.ErrInvalid, err)
+	_, err = db.BuildFilter(cdb.FilterKind(7), 0.01)
+	assert.Equal(t, os.ErrInvalid, err)
+
+	for _, data := range [][]byte{nil, {0}, {1, 2, 1, 0, 0, 0}, {7, 1, 2, 3, 4, 5}} {
+		_, err = cdb.ParseFilter(data)
+		assert.Equal(t, cdb.ErrInvalidFilter, err)
+	}
+}
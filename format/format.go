@@ -0,0 +1,172 @@
+// Package format describes the layout of a cdb file, for tools that need to
+// read or write the format directly, such as hexdump annotators or generators
+// for bindings in other languages.
+//
+// A cdb file has three parts, one after another:
+//
+//   - The index, IndexSize bytes long, which holds TableCount Tables: the
+//     offset and number of slots of each hash table.
+//   - The data section, which holds the records. Each record is a
+//     RecordHeader, giving the length of the key and the value, followed by
+//     the key and the value themselves.
+//   - The hash tables, each of which is a run of Slots. A Slot holds the hash
+//     of a record's key, and the offset of the record in the file; empty slots
+//     have an offset of zero.
+//
+// Every number is a uint32, and they're packed in pairs, called tuples, in
+// the byte order given by TuplePacking. To find a key, hash it with Hash, then
+// probe the slots of the table that TableFor selects, starting at StartSlot
+// and wrapping around at the end, until reaching an empty slot.
+//
+// The github.com/colinmarc/cdb package can also write files using a
+// different byte order, or with additional metadata after the last hash
+// table, which other implementations ignore. Neither is described here.
+package format
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	// TableCount is the number of hash tables.
+	TableCount = 256
+	// TupleSize is the size of a tuple of two uint32s, in bytes.
+	TupleSize = 8
+	// IndexSize is the size of the index, in bytes. The data section starts
+	// right after it.
+	IndexSize = TableCount * TupleSize
+	// RecordHeaderSize is the size of a RecordHeader, in bytes.
+	RecordHeaderSize = TupleSize
+	// SlotSize is the size of a Slot, in bytes.
+	SlotSize = TupleSize
+)
+
+// TuplePacking is the byte order of every number in the file.
+var TuplePacking binary.ByteOrder = binary.LittleEndian
+
+// A Table is an entry in the index. It's stored as the tuple (Offset, Length).
+type Table struct {
+	// Offset is the position in the file of the table's first slot.
+	Offset uint32
+	// Length is the number of slots in the table, which may be zero.
+	Length uint32
+}
+
+// An Index lists the hash tables, as stored at the start of the file.
+type Index [TableCount]Table
+
+// A RecordHeader starts each record in the data section. It's stored as the
+// tuple (KeyLength, ValueLength).
+type RecordHeader struct {
+	KeyLength   uint32
+	ValueLength uint32
+}
+
+// Size returns the total size of the record, including the header, in bytes.
+func (h RecordHeader) Size() int64 {
+	return RecordHeaderSize + int64(h.KeyLength) + int64(h.ValueLength)
+}
+
+// A Slot is an entry in a hash table. It's stored as the tuple (Hash, Offset).
+type Slot struct {
+	// Hash is the hash of the record's key.
+	Hash uint32
+	// Offset is the position in the file of the record, or zero if the slot
+	// is empty.
+	Offset uint32
+}
+
+// Empty returns true if the slot doesn't point to a record.
+func (s Slot) Empty() bool {
+	return s.Offset == 0
+}
+
+// EncodeTuple packs two numbers into the first TupleSize bytes of b. It
+// panics if b is too short.
+func EncodeTuple(b []byte, first, second uint32) {
+	TuplePacking.PutUint32(b[:4], first)
+	TuplePacking.PutUint32(b[4:TupleSize], second)
+}
+
+// DecodeTuple unpacks two numbers from the start of b. It returns
+// io.ErrUnexpectedEOF if b is shorter than TupleSize.
+func DecodeTuple(b []byte) (uint32, uint32, error) {
+	if len(b) < TupleSize {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	return TuplePacking.Uint32(b[:4]), TuplePacking.Uint32(b[4:TupleSize]), nil
+}
+
+// Encode stores the index in the first IndexSize bytes of b. It panics if b
+// is too short.
+func (idx *Index) Encode(b []byte) {
+	for i, table := range idx {
+		EncodeTuple(b[i*TupleSize:], table.Offset, table.Length)
+	}
+}
+
+// DecodeIndex reads an index from the start of b. It returns
+// io.ErrUnexpectedEOF if b is shorter than IndexSize.
+func DecodeIndex(b []byte) (Index, error) {
+	var idx Index
+	if len(b) < IndexSize {
+		return idx, io.ErrUnexpectedEOF
+	}
+
+	for i := range idx {
+		idx[i].Offset, idx[i].Length, _ = DecodeTuple(b[i*TupleSize:])
+	}
+
+	return idx, nil
+}
+
+// Encode stores the header in the first RecordHeaderSize bytes of b. It
+// panics if b is too short.
+func (h RecordHeader) Encode(b []byte) {
+	EncodeTuple(b, h.KeyLength, h.ValueLength)
+}
+
+// DecodeRecordHeader reads a record header from the start of b. It returns
+// io.ErrUnexpectedEOF if b is shorter than RecordHeaderSize.
+func DecodeRecordHeader(b []byte) (RecordHeader, error) {
+	keyLength, valueLength, err := DecodeTuple(b)
+	return RecordHeader{KeyLength: keyLength, ValueLength: valueLength}, err
+}
+
+// Encode stores the slot in the first SlotSize bytes of b. It panics if b is
+// too short.
+func (s Slot) Encode(b []byte) {
+	EncodeTuple(b, s.Hash, s.Offset)
+}
+
+// DecodeSlot reads a slot from the start of b. It returns
+// io.ErrUnexpectedEOF if b is shorter than SlotSize.
+func DecodeSlot(b []byte) (Slot, error) {
+	hash, offset, err := DecodeTuple(b)
+	return Slot{Hash: hash, Offset: offset}, err
+}
+
+// Hash returns the cdb hash of a key: starting from 5381, each byte is
+// combined as h = ((h << 5) + h) ^ b.
+func Hash(key []byte) uint32 {
+	h := uint32(5381)
+	for _, b := range key {
+		h = ((h << 5) + h) ^ uint32(b)
+	}
+
+	return h
+}
+
+// TableFor returns which hash table a key with the given hash is stored in:
+// the low 8 bits of the hash.
+func TableFor(hash uint32) int {
+	return int(hash % TableCount)
+}
+
+// StartSlot returns the slot to start probing at for a key with the given
+// hash, in a table with the given number of slots, which must not be zero.
+func StartSlot(hash, length uint32) uint32 {
+	return (hash / TableCount) % length
+}
@@ -0,0 +1,106 @@
+package format_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/colinmarc/cdb/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lookup finds the first value for key by following the format by hand.
+func lookup(t *testing.T, data []byte, idx format.Index, key []byte) []byte {
+	hash := format.Hash(key)
+	table := idx[format.TableFor(hash)]
+	if table.Length == 0 {
+		return nil
+	}
+
+	slot := format.StartSlot(hash, table.Length)
+	for i := uint32(0); i < table.Length; i++ {
+		s, err := format.DecodeSlot(data[table.Offset+slot*format.SlotSize:])
+		require.NoError(t, err)
+		if s.Empty() {
+			return nil
+		}
+
+		if s.Hash == hash {
+			h, err := format.DecodeRecordHeader(data[s.Offset:])
+			require.NoError(t, err)
+
+			start := s.Offset + format.RecordHeaderSize
+			if bytes.Equal(data[start:start+h.KeyLength], key) {
+				start += h.KeyLength
+				return data[start : start+h.ValueLength]
+			}
+		}
+
+		slot = (slot + 1) % table.Length
+	}
+
+	return nil
+}
+
+func TestFormat(t *testing.T) {
+	data, err := ioutil.ReadFile("../test/test.cdb")
+	require.NoError(t, err)
+
+	db, err := cdb.New(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, cdb.DataOffset, format.IndexSize)
+
+	idx, err := format.DecodeIndex(data)
+	require.NoError(t, err)
+
+	expected, err := cdb.UnmarshalIndex(db.MarshalIndex())
+	require.NoError(t, err)
+	for i, table := range expected {
+		assert.Equal(t, format.Table{Offset: table.Offset, Length: table.Length}, idx[i])
+	}
+
+	encoded := make([]byte, format.IndexSize)
+	idx.Encode(encoded)
+	assert.Equal(t, data[:format.IndexSize], encoded)
+
+	iter := db.Iter()
+	for iter.Next() {
+		assert.Equal(t, cdb.HashKey(iter.Key()), format.Hash(iter.Key()))
+
+		value, err := db.Get(iter.Key())
+		require.NoError(t, err)
+		assert.Equal(t, value, lookup(t, data, idx, iter.Key()))
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Nil(t, lookup(t, data, idx, []byte("not in the table")))
+}
+
+func TestRoundTrip(t *testing.T) {
+	b := make([]byte, format.TupleSize)
+
+	h := format.RecordHeader{KeyLength: 3, ValueLength: 0xdeadbeef}
+	h.Encode(b)
+	assert.Equal(t, []byte{3, 0, 0, 0, 0xef, 0xbe, 0xad, 0xde}, b)
+
+	decoded, err := format.DecodeRecordHeader(b)
+	require.NoError(t, err)
+	assert.Equal(t, h, decoded)
+	assert.Equal(t, int64(8+3+0xdeadbeef), h.Size())
+
+	s := format.Slot{Hash: 42, Offset: 2048}
+	s.Encode(b)
+	slot, err := format.DecodeSlot(b)
+	require.NoError(t, err)
+	assert.Equal(t, s, slot)
+	assert.False(t, slot.Empty())
+
+	_, err = format.DecodeSlot(b[:7])
+	assert.Error(t, err)
+
+	_, err = format.DecodeIndex(make([]byte, format.IndexSize-1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package cdb
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+)
+
+// OpenFS opens an existing CDB database at the given path in fsys. This works
+// with embed.FS, so a database can be compiled into a binary with go:embed
+// and opened directly.
+//
+// If the file opened from fsys implements io.ReaderAt, as files from embed.FS
+// and os.DirFS do, it is used directly. Otherwise, the whole file is read into
+// memory.
+func OpenFS(fsys fs.FS, path string, opts ...Option) (*CDB, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if readerAt, ok := f.(io.ReaderAt); ok {
+		db, err := New(fsFile{f, readerAt}, nil, opts...)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		return db, nil
+	}
+
+	buf, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(bytes.NewReader(buf), nil, opts...)
+}
+
+// fsFile lets a CDB close the file it was opened from.
+type fsFile struct {
+	fs.File
+	io.ReaderAt
+}
@@ -0,0 +1,54 @@
+package cdb_test
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"testing"
+	"testing/fstest"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamFS hides the io.ReaderAt implementation of the files in an fs.FS.
+type streamFS struct {
+	fs.FS
+}
+
+func (sfs streamFS) Open(name string) (fs.File, error) {
+	f, err := sfs.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return struct{ fs.File }{f}, nil
+}
+
+func testOpenFS(t *testing.T, fsys fs.FS) {
+	db, err := cdb.OpenFS(fsys, "test.cdb")
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	require.NoError(t, db.Close())
+}
+
+func TestOpenFS(t *testing.T) {
+	data, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{"test.cdb": &fstest.MapFile{Data: data}}
+	testOpenFS(t, fsys)
+	testOpenFS(t, streamFS{fsys})
+}
+
+func TestOpenFSNotExist(t *testing.T) {
+	_, err := cdb.OpenFS(fstest.MapFS{}, "test.cdb")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
@@ -0,0 +1,130 @@
+package cdb
+
+import (
+	"bytes"
+	"io"
+)
+
+// GetInto looks up the value for a given key, and copies it into dst instead
+// of allocating a new slice. It returns the length of the value and whether
+// the key was found.
+//
+// If the value is longer than dst, GetInto returns the length of the value,
+// true, and io.ErrShortBuffer, and dst is left unmodified; the caller can
+// retry with a larger buffer.
+//
+// In the common case, GetInto doesn't allocate at all, which makes it a good
+// fit for high-throughput services where GC pressure matters. The exception
+// is databases opened WithCompression or WithExpiry, where the value has to be
+// decoded before it can be copied.
+func (cdb *CDB) GetInto(key, dst []byte) (int, bool, error) {
+	if cdb.opts.trace != nil && cdb.tracing == nil {
+		var n int
+		var found bool
+		original, normalized := key, cdb.opts.normalizeKey(key)
+		err := cdb.traceLookup(cdb.hash(normalized), normalized, func(db *CDB) (err error) {
+			n, found, err = db.GetInto(original, dst)
+			return err
+		})
+
+		return n, found, err
+	}
+
+	key = cdb.opts.normalizeKey(key)
+	if cdb.hidden(key) {
+		return 0, false, nil
+	} else if cdb.opts.encodesValues() {
+		return cdb.getIntoDecoded(key, dst)
+	}
+
+	err := cdb.acquire(opGet)
+	if err != nil {
+		return 0, false, err
+	}
+	defer cdb.release(opGet)
+
+	hash := cdb.hash(key)
+	p := cdb.newProbe(hash)
+	if cdb.bloom != nil && !cdb.bloom.mayContain(hash) {
+		cdb.observeGet(false, &p)
+		return 0, false, nil
+	}
+
+	scratch := cdb.opts.getScratch()
+	defer cdb.opts.putScratch(scratch)
+
+	p.scratch = *scratch
+	for {
+		offset, ok, err := p.next()
+		if err != nil {
+			return 0, false, err
+		} else if !ok {
+			break
+		}
+
+		cdb.traceRecord(offset)
+		n, found, err := cdb.copyValueAt(offset, key, dst, scratch)
+		if err != nil || found {
+			p.finish()
+			cdb.observeGet(found, &p)
+			return n, found, err
+		}
+	}
+
+	cdb.observeGet(false, &p)
+	return 0, false, nil
+}
+
+// copyValueAt is like getValueAt, but copies the value into dst, and uses
+// scratch to read the header and key. If scratch is too short, it's replaced
+// with a larger buffer.
+func (cdb *CDB) copyValueAt(offset uint32, expectedKey, dst []byte, scratch *[]byte) (int, bool, error) {
+	keyLength, valueLength, err := cdb.readHeader(offset, *scratch)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if int(keyLength) != len(expectedKey) {
+		return 0, false, nil
+	}
+
+	if int(keyLength) > cap(*scratch) {
+		*scratch = make([]byte, keyLength)
+	}
+
+	buf := (*scratch)[:keyLength]
+	_, err = cdb.reader.ReadAt(buf, int64(offset+8))
+	if err != nil {
+		return 0, false, readError(ErrCorruptRecord, int64(offset), err)
+	}
+
+	if !bytes.Equal(buf, expectedKey) {
+		return 0, false, nil
+	}
+
+	if int(valueLength) > len(dst) {
+		return int(valueLength), true, io.ErrShortBuffer
+	}
+
+	_, err = cdb.reader.ReadAt(dst[:valueLength], int64(offset+8+keyLength))
+	if err != nil {
+		return 0, false, readError(ErrCorruptRecord, int64(offset), err)
+	}
+
+	return int(valueLength), true, nil
+}
+
+// getIntoDecoded implements GetInto on top of get, for values that need to be
+// decoded. The key has already been normalized.
+func (cdb *CDB) getIntoDecoded(key, dst []byte) (int, bool, error) {
+	value, err := cdb.get(cdb.hash(key), key)
+	if err != nil && err != ErrExpired {
+		return 0, false, err
+	} else if value == nil {
+		return 0, false, nil
+	} else if len(value) > len(dst) {
+		return len(value), true, io.ErrShortBuffer
+	}
+
+	return copy(dst, value), true, err
+}
@@ -0,0 +1,70 @@
+package cdb_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetInto(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	buf := make([]byte, 64)
+	for _, record := range expectedRecords {
+		msg := "while fetching " + string(record[0])
+
+		n, found, err := db.GetInto(record[0], buf)
+		require.NoError(t, err, msg)
+		assert.Equal(t, record[1] != nil, found, msg)
+		assert.Equal(t, string(record[1]), string(buf[:n]), msg)
+	}
+}
+
+func TestGetIntoShortBuffer(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	n, found, err := db.GetInto([]byte("crystal"), make([]byte, 3))
+	assert.Equal(t, io.ErrShortBuffer, err)
+	assert.True(t, found)
+	assert.Equal(t, len("CASTLES"), n)
+}
+
+func TestGetIntoAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations aren't predictable with the race detector")
+	}
+
+	data, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	db, err := cdb.New(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+
+	buf := make([]byte, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, record := range expectedRecords {
+			db.GetInto(record[0], buf)
+		}
+	})
+
+	assert.Equal(t, 0.0, allocs)
+}
+
+func BenchmarkGetInto(b *testing.B) {
+	db, _ := cdb.Open("./test/test.cdb")
+	buf := make([]byte, 64)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		record := expectedRecords[i%len(expectedRecords)]
+		db.GetInto(record[0], buf)
+	}
+}
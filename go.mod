@@ -1,6 +1,13 @@
 module github.com/colinmarc/cdb
 
+go 1.21
+
 require (
 	github.com/Pallinder/go-randomdata v1.1.0
 	github.com/stretchr/testify v1.3.0
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
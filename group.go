@@ -0,0 +1,181 @@
+package cdb
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// Grouping is an order for a Writer to store records in, set with
+// WithGrouping.
+type Grouping int
+
+const (
+	// GroupByTable stores the records in each hash table next to each other,
+	// in table order, so that the records a lookup might read are close
+	// together in the file, and so more likely to share pages in the page
+	// cache. Records in the same table keep the order they were added in.
+	GroupByTable Grouping = iota + 1
+	// GroupByKey stores the records sorted by key, so that iterating visits
+	// them in key order. Records with the same key keep the order they were
+	// added in.
+	GroupByKey
+)
+
+// WithGrouping makes a Writer reorder the records it has written when the
+// database is finalized, so that they're stored grouped by hash table or
+// sorted by key. Records are written to the stream as usual, then copied, in
+// the new order, via a temporary file in the default directory for temporary
+// files, and back. That takes as much temporary disk space as the data
+// section, and, for GroupByKey, enough memory to hold every key.
+//
+// Like Delete, reordering needs to read back the records, so the underlying
+// stream must also be an io.ReaderAt; if it isn't, Close and Freeze return
+// os.ErrInvalid.
+func WithGrouping(g Grouping) Option {
+	return func(o *options) {
+		o.grouping = g
+	}
+}
+
+// groupedRecord is a reference to an entry in one of the Writer's hash
+// tables, to be sorted.
+type groupedRecord struct {
+	table  int
+	i      int
+	offset uint32
+	key    []byte
+}
+
+// regroup rewrites the data section in the order set by WithGrouping, and
+// fixes up the offsets in the hash tables. The write position is left at the
+// end of the data section, which only changes size if there's padding between
+// records.
+func (cdb *Writer) regroup() error {
+	readerAt, ok := cdb.writer.(io.ReaderAt)
+	if !ok {
+		return os.ErrInvalid
+	}
+
+	err := cdb.bufferedWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	records := make([]groupedRecord, 0, cdb.records)
+	for table, entries := range cdb.entries {
+		for i, entry := range entries {
+			records = append(records, groupedRecord{table: table, i: i, offset: entry.offset})
+		}
+	}
+
+	if cdb.opts.grouping == GroupByKey {
+		for i := range records {
+			key, err := cdb.readWrittenKey(readerAt, records[i].offset)
+			if err != nil {
+				return err
+			}
+
+			records[i].key = key
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if cdb.opts.grouping == GroupByKey {
+			if c := bytes.Compare(a.key, b.key); c != 0 {
+				return c < 0
+			}
+		} else if a.table != b.table {
+			return a.table < b.table
+		}
+
+		return a.offset < b.offset
+	})
+
+	f, err := ioutil.TempFile("", "cdb-group")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// Copy the records to the temporary file in the new order, then copy the
+	// whole thing back over the data section. With WithAlignment, the
+	// padding between records changes along with the order.
+	bw := bufio.NewWriterSize(f, 65536)
+	offset := int64(indexSize)
+	for _, rec := range records {
+		keyLength, valueLength, err := readTuple(readerAt, cdb.opts.order(), rec.offset)
+		if err != nil {
+			return err
+		}
+
+		pad := cdb.opts.align(offset) - offset
+		_, err = bw.Write(make([]byte, pad))
+		if err != nil {
+			return err
+		}
+
+		offset += pad
+		length := int64(8 + keyLength + valueLength)
+		_, err = io.Copy(bw, io.NewSectionReader(readerAt, int64(rec.offset), length))
+		if err != nil {
+			return err
+		}
+
+		cdb.entries[rec.table][rec.i].offset = uint32(offset)
+		offset += length
+	}
+
+	err = cdb.checkFinalSize(offset, cdb.estimatedFooterSize, cdb.records)
+	if err != nil {
+		return err
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	if truncater, ok := cdb.writer.(interface{ Truncate(int64) error }); ok && offset < cdb.bufferedOffset {
+		err = truncater.Truncate(offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = cdb.resetBuffer(indexSize)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(cdb.bufferedWriter, f, offset-indexSize)
+	if err != nil {
+		return err
+	}
+
+	cdb.bufferedOffset = offset
+	return nil
+}
+
+// readWrittenKey reads the key of a record that's already been written to
+// the stream.
+func (cdb *Writer) readWrittenKey(r io.ReaderAt, offset uint32) ([]byte, error) {
+	keyLength, _, err := readTuple(r, cdb.opts.order(), offset)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, keyLength)
+	_, err = r.ReadAt(key, int64(offset+8))
+	return key, err
+}
@@ -0,0 +1,112 @@
+package cdb_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrouping(t *testing.T) {
+	for _, grouping := range []cdb.Grouping{cdb.GroupByTable, cdb.GroupByKey} {
+		f, err := ioutil.TempFile("", "test-cdb")
+		require.NoError(t, err)
+		defer os.Remove(f.Name())
+
+		writer, err := cdb.NewWriter(f, nil, cdb.WithGrouping(grouping))
+		require.NoError(t, err)
+
+		for i := 0; i < 1000; i++ {
+			key := []byte(fmt.Sprintf("key%d", 999-i))
+			require.NoError(t, writer.Put(key, []byte(fmt.Sprintf("first%d", i))))
+			require.NoError(t, writer.Put(key, []byte(fmt.Sprintf("second%d", i))))
+		}
+
+		require.NoError(t, writer.Delete([]byte("key500")))
+		db, err := writer.Freeze()
+		require.NoError(t, err)
+
+		// Every record is still found, with duplicates in the order they were
+		// added.
+		for i := 0; i < 1000; i++ {
+			values, err := db.GetAll([]byte(fmt.Sprintf("key%d", 999-i)))
+			require.NoError(t, err)
+			if i == 499 {
+				assert.Empty(t, values)
+				continue
+			}
+
+			require.Len(t, values, 2)
+			assert.Equal(t, fmt.Sprintf("first%d", i), string(values[0]))
+			assert.Equal(t, fmt.Sprintf("second%d", i), string(values[1]))
+		}
+
+		var prevKey []byte
+		prevTable, n := -1, 0
+		iter := db.Iter()
+		for iter.Next() {
+			n++
+			if grouping == cdb.GroupByKey {
+				assert.True(t, bytes.Compare(prevKey, iter.Key()) <= 0)
+				prevKey = iter.Key()
+			} else {
+				table := int(cdb.HashKey(iter.Key()) & 0xff)
+				assert.True(t, table >= prevTable)
+				prevTable = table
+			}
+		}
+
+		require.NoError(t, iter.Err())
+		assert.Equal(t, 1998, n)
+		db.Close()
+	}
+}
+
+func BenchmarkGetGrouped(b *testing.B) {
+	const records = 200000
+	keys := make([][]byte, records)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key%d", i))
+	}
+
+	value := bytes.Repeat([]byte("x"), 512)
+	cases := []struct {
+		name string
+		opts []cdb.Option
+	}{
+		{"ungrouped", nil},
+		{"by-table", []cdb.Option{cdb.WithGrouping(cdb.GroupByTable)}},
+		{"by-key", []cdb.Option{cdb.WithGrouping(cdb.GroupByKey)}},
+	}
+
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			f, err := ioutil.TempFile("", "test-cdb")
+			require.NoError(b, err)
+			defer os.Remove(f.Name())
+
+			writer, err := cdb.NewWriter(f, nil, c.opts...)
+			require.NoError(b, err)
+			for _, key := range keys {
+				require.NoError(b, writer.Put(key, value))
+			}
+
+			db, err := writer.Freeze()
+			require.NoError(b, err)
+			defer db.Close()
+
+			r := rand.New(rand.NewSource(1))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				db.Get(keys[r.Intn(records)])
+			}
+		})
+	}
+}
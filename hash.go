@@ -1,5 +1,9 @@
 package cdb
 
+import (
+	"hash"
+)
+
 const start uint32 = 5381
 
 func cdbHash(data []byte) uint32 {
@@ -10,3 +14,50 @@ func cdbHash(data []byte) uint32 {
 
 	return v
 }
+
+// HashKey returns the CDB hash of key, which is the default hash function for
+// readers and writers. The low 8 bits of the hash select the hash table a key
+// is stored in, and the remaining bits select the starting slot within it.
+func HashKey(key []byte) uint32 {
+	return cdbHash(key)
+}
+
+// Hash32 returns a new hash.Hash32 computing the CDB hash. Writing a key to it
+// in any number of pieces produces the same sum as HashKey.
+func Hash32() hash.Hash32 {
+	h := digest(start)
+	return &h
+}
+
+type digest uint32
+
+func (d *digest) Write(p []byte) (int, error) {
+	v := uint32(*d)
+	for _, b := range p {
+		v = ((v << 5) + v) ^ uint32(b)
+	}
+
+	*d = digest(v)
+	return len(p), nil
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	v := uint32(*d)
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (d *digest) Sum32() uint32 {
+	return uint32(*d)
+}
+
+func (d *digest) Reset() {
+	*d = digest(start)
+}
+
+func (d *digest) Size() int {
+	return 4
+}
+
+func (d *digest) BlockSize() int {
+	return 1
+}
@@ -10,3 +10,55 @@ func TestHash(t *testing.T) {
 	assert.EqualValues(t, 776976811, cdbHash([]byte("foo bar baz")))
 	assert.EqualValues(t, 3538394712, cdbHash([]byte("The quick brown fox jumped over the lazy dog")))
 }
+
+func TestHash32(t *testing.T) {
+	h := Hash32()
+	h.Write([]byte("foo "))
+	h.Write([]byte("bar baz"))
+	assert.EqualValues(t, 776976811, h.Sum32())
+	assert.Equal(t, []byte{0x2e, 0x4f, 0xb9, 0xab}, h.Sum(nil))
+
+	h.Reset()
+	h.Write([]byte("The quick brown fox jumped over the lazy dog"))
+	assert.Equal(t, HashKey([]byte("The quick brown fox jumped over the lazy dog")), h.Sum32())
+}
+
+func TestHashAllocs(t *testing.T) {
+	key := []byte("The quick brown fox jumped over the lazy dog")
+	w := &Writer{hash: cdbHash}
+	db := &CDB{hash: cdbHash}
+
+	// The hash step of Put and Get.
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { w.hash(key) }))
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { db.hash(key) }))
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { HashKey(key) }))
+
+	// A Hash32 only allocates when it's created.
+	h := Hash32()
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		h.Reset()
+		h.Write(key)
+		h.Sum32()
+	}))
+}
+
+func BenchmarkHashKey(b *testing.B) {
+	key := []byte("The quick brown fox jumped over the lazy dog")
+	b.ReportAllocs()
+	b.SetBytes(int64(len(key)))
+	for i := 0; i < b.N; i++ {
+		HashKey(key)
+	}
+}
+
+func BenchmarkHash32(b *testing.B) {
+	key := []byte("The quick brown fox jumped over the lazy dog")
+	h := Hash32()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(key)))
+	for i := 0; i < b.N; i++ {
+		h.Reset()
+		h.Write(key)
+		h.Sum32()
+	}
+}
@@ -0,0 +1,44 @@
+package cdb
+
+import "encoding/binary"
+
+// WithHashedKeys causes every key to be replaced with a hash of itself,
+// bits long, before it's used, after any normalizer registered with
+// WithKeyNormalizer and any HMAC from WithKeyHMAC. bits must be 32 or 64;
+// other values are ignored. This saves space when keys are long, such as
+// URLs, since only four or eight bytes are stored per key.
+//
+// The cost is that lookups can no longer tell keys apart if their hashes
+// collide: Get may return the value for a different key, with a probability
+// of about n/2^bits for a database of n records, and for a key that's not in
+// the database at all. With 64 bits, that's negligible for most uses; with
+// 32, it's one in a few thousand for a million records.
+//
+// A database written with this option must be read with it, with the same
+// number of bits. As with WithKeyHMAC, keys read back from the database, by
+// Iter for example, are the hashes, which can't be looked up again.
+func WithHashedKeys(bits int) Option {
+	return func(o *options) {
+		if bits == 32 || bits == 64 {
+			o.hashedKeyBits = bits
+		}
+	}
+}
+
+// compactKey replaces key with its hash, if WithHashedKeys is set. The hash is
+// the same 64-bit FNV-1a used by filters, folded in half for 32 bits.
+func (o *options) compactKey(key []byte) []byte {
+	switch o.hashedKeyBits {
+	case 32:
+		h := filterHash(key)
+		digest := make([]byte, 4)
+		binary.LittleEndian.PutUint32(digest, uint32(h^h>>32))
+		return digest
+	case 64:
+		digest := make([]byte, 8)
+		binary.LittleEndian.PutUint64(digest, filterHash(key))
+		return digest
+	default:
+		return key
+	}
+}
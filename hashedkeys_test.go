@@ -0,0 +1,62 @@
+package cdb_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashedKeys(t *testing.T) {
+	url := func(i int) []byte {
+		return []byte(fmt.Sprintf("https://example.com/some/long/path/to/resource/%d?with=query&params=true", i))
+	}
+
+	for _, bits := range []int{32, 64} {
+		plain := cdb.NewMem()
+		writer := cdb.NewMem(cdb.WithHashedKeys(bits))
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, plain.Put(url(i), []byte("v")))
+			require.NoError(t, writer.Put(url(i), []byte("v")))
+		}
+
+		plainDB, err := plain.Freeze()
+		require.NoError(t, err)
+		db, err := writer.Freeze()
+		require.NoError(t, err)
+		assert.True(t, db.DataSize() < plainDB.DataSize()/4, "%d bits", bits)
+
+		for i := 0; i < 1000; i++ {
+			value, err := db.Get(url(i))
+			require.NoError(t, err)
+			assert.Equal(t, "v", string(value), "%d bits", bits)
+		}
+
+		value, err := db.Get([]byte("https://example.com/missing"))
+		require.NoError(t, err)
+		assert.Nil(t, value)
+
+		iter := db.Iter()
+		for iter.Next() {
+			assert.Len(t, iter.Key(), bits/8)
+		}
+
+		require.NoError(t, iter.Err())
+	}
+}
+
+func TestHashedKeysInvalidBits(t *testing.T) {
+	for _, bits := range []int{0, 16, 48, 128} {
+		writer := cdb.NewMem(cdb.WithHashedKeys(bits))
+		require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+
+		db, err := writer.Freeze()
+		require.NoError(t, err)
+
+		iter := db.Iter()
+		require.True(t, iter.Next())
+		assert.Equal(t, "foo", string(iter.Key()), "%d bits", bits)
+	}
+}
@@ -0,0 +1,184 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// HeaderVersion is the version of the format written by WithHeader.
+const HeaderVersion = 1
+
+const headerSize = 24
+
+const (
+	headerCustomHash = 1 << iota
+	headerExpiry
+	headerEncrypted
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrCustomHash is returned when opening a database with the default hash
+// function, if its header says it was written with a different one.
+var ErrCustomHash = errors.New("database was written with a custom hash function")
+
+// Header describes how a database was written. It's only present in databases
+// written WithHeader.
+type Header struct {
+	// Version is the version of the format, currently HeaderVersion.
+	Version uint32
+	// CustomHash is true if the database was written with a hash function
+	// other than the default.
+	CustomHash bool
+	// Compression is the ID of the Compressor the database was written with,
+	// or 0 if it was written without WithCompression.
+	Compression byte
+	// Expiry is true if the database was written WithExpiry.
+	Expiry bool
+	// Encrypted is true if the database was written WithEncryption.
+	Encrypted bool
+	// Records is the number of records in the database.
+	Records uint64
+	// TablesChecksum is the CRC-32C of the hash tables.
+	TablesChecksum uint32
+}
+
+// WithHeader causes a Writer to store a Header after the hash tables, where
+// other cdb implementations ignore it, so that the file stays compatible with
+// them.
+//
+// When a database with a header is opened, the header takes precedence over
+// the options passed to Open or New: WithExpiry is enabled or disabled to
+// match it, as is WithCompression, using Gzip if the database was compressed
+// with it and no Compressor was given. Opening a database with a Compressor
+// other than the one it was written with returns ErrUnknownCompression.
+// Opening a database written with a custom hash function without passing one
+// returns ErrCustomHash, and opening an encrypted database without
+// WithEncryption returns ErrEncrypted. Like bloom filters, the header is only
+// read if the size of the underlying io.ReaderAt is known.
+func WithHeader() Option {
+	return func(o *options) {
+		o.header = true
+	}
+}
+
+// Header returns the database's header, and false if it doesn't have one.
+func (cdb *CDB) Header() (Header, bool) {
+	if cdb.header == nil {
+		return Header{}, false
+	}
+
+	return *cdb.header, true
+}
+
+// VerifyHeader checks the hash tables against the checksum and record count
+// in the header, and returns ErrCorrupt if they don't match. If the database
+// doesn't have a header, it returns nil.
+func (cdb *CDB) VerifyHeader() error {
+	if cdb.header == nil {
+		return nil
+	}
+
+	start, end := cdb.tablesRegion()
+	tables := cdb.tables
+	if tables == nil {
+		tables = make([]byte, end-start)
+		err := cdb.readTables(tables, start)
+		if err != nil {
+			return err
+		}
+	}
+
+	if crc32.Checksum(tables, castagnoli) != cdb.header.TablesChecksum {
+		return ErrCorrupt
+	}
+
+	records, err := cdb.Len()
+	if err != nil {
+		return err
+	} else if uint64(records) != cdb.header.Records {
+		return ErrCorrupt
+	}
+
+	return nil
+}
+
+// buildHeader returns the serialized header for the database being written.
+func (cdb *Writer) buildHeader() []byte {
+	var flags uint32
+	if cdb.customHash {
+		flags |= headerCustomHash
+	}
+
+	if cdb.opts.expiry {
+		flags |= headerExpiry
+	}
+
+	if cdb.opts.aead != nil {
+		flags |= headerEncrypted
+	}
+
+	var compression uint32
+	if cdb.opts.compressor != nil {
+		compression = uint32(cdb.opts.compressor.ID())
+	}
+
+	buf := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(buf, HeaderVersion)
+	binary.LittleEndian.PutUint32(buf[4:], flags)
+	binary.LittleEndian.PutUint32(buf[8:], compression)
+	binary.LittleEndian.PutUint64(buf[12:], uint64(cdb.records))
+	binary.LittleEndian.PutUint32(buf[20:], cdb.tablesChecksum.Sum32())
+	return buf
+}
+
+// parseHeader reads a header section, ignoring any fields added by later
+// versions.
+func parseHeader(data []byte) (*Header, error) {
+	if len(data) < headerSize {
+		return nil, ErrCorrupt
+	}
+
+	flags := binary.LittleEndian.Uint32(data[4:])
+	compression := binary.LittleEndian.Uint32(data[8:])
+	if compression > 0xff {
+		return nil, ErrCorrupt
+	}
+
+	return &Header{
+		Version:        binary.LittleEndian.Uint32(data),
+		CustomHash:     flags&headerCustomHash != 0,
+		Compression:    byte(compression),
+		Expiry:         flags&headerExpiry != 0,
+		Encrypted:      flags&headerEncrypted != 0,
+		Records:        binary.LittleEndian.Uint64(data[12:]),
+		TablesChecksum: binary.LittleEndian.Uint32(data[20:]),
+	}, nil
+}
+
+// applyHeader updates the options to match the header.
+func (cdb *CDB) applyHeader() error {
+	h := cdb.header
+	if h.CustomHash && !cdb.customHash {
+		return ErrCustomHash
+	} else if h.Encrypted && cdb.opts.aead == nil {
+		return ErrEncrypted
+	}
+
+	cdb.opts.expiry = h.Expiry
+	switch {
+	case h.Compression == 0:
+		cdb.opts.compressor = nil
+	case cdb.opts.compressor != nil:
+		if cdb.opts.compressor.ID() != h.Compression {
+			return ErrUnknownCompression
+		}
+	case h.Compression == Gzip.ID():
+		cdb.opts.compressor = Gzip
+	default:
+		return ErrUnknownCompression
+	}
+
+	return nil
+}
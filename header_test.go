@@ -0,0 +1,120 @@
+package cdb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "header.cdb")
+	writer, err := cdb.Create(path, cdb.WithHeader(), cdb.WithCompression(cdb.Gzip), cdb.WithExpiry())
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, writer.Put(record[0], record[1]))
+		}
+	}
+
+	require.NoError(t, writer.Close())
+
+	// The header is enough to read the database back, without any options.
+	db, err := cdb.Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	header, ok := db.Header()
+	require.True(t, ok)
+	assert.Equal(t, cdb.Header{
+		Version:        cdb.HeaderVersion,
+		Compression:    cdb.Gzip.ID(),
+		Expiry:         true,
+		Records:        uint64(len(expectedRecords) - 1),
+		TablesChecksum: header.TablesChecksum,
+	}, header)
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	assert.NoError(t, db.VerifyHeader())
+}
+
+func TestHeaderClassic(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, ok := db.Header()
+	assert.False(t, ok)
+	assert.NoError(t, db.VerifyHeader())
+}
+
+func TestHeaderCustomHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "header.cdb")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	writer, err := cdb.NewWriter(f, fnvHash, cdb.WithHeader())
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Close())
+
+	_, err = cdb.Open(path)
+	assert.Equal(t, cdb.ErrCustomHash, err)
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+
+	db, err := cdb.New(f, fnvHash)
+	require.NoError(t, err)
+	defer db.Close()
+
+	value, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(value))
+}
+
+func TestHeaderCompressionMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compressed.cdb")
+	writer, err := cdb.Create(path, cdb.WithHeader(), cdb.WithCompression(cdb.Gzip))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Close())
+
+	_, err = cdb.Open(path, cdb.WithCompression(reverseCompressor{}))
+	assert.Equal(t, cdb.ErrUnknownCompression, err)
+
+	db, err := cdb.Open(path, cdb.WithCompression(cdb.Gzip))
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestVerifyHeaderCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "header.cdb")
+	writer, err := cdb.Create(path, cdb.WithHeader())
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	db, err := cdb.FromBytes(data)
+	require.NoError(t, err)
+	require.NoError(t, db.VerifyHeader())
+
+	// Flip a bit in the first slot of the first hash table.
+	data[db.Index()[0].Offset] ^= 1
+	db, err = cdb.FromBytes(data)
+	require.NoError(t, err)
+	assert.True(t, errors.Is(db.VerifyHeader(), cdb.ErrCorrupt))
+}
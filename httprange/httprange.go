@@ -0,0 +1,247 @@
+/*
+Package httprange provides an io.ReaderAt backed by HTTP range requests, which
+can be used to read a CDB database directly from a web server or an object
+store like S3 or GCS, without downloading it first.
+
+Reads are done in fixed-size blocks, and recently used blocks are cached in
+memory. Since a CDB lookup normally touches only a few small regions of the
+file, most lookups only need one or two requests. The size of the file is
+taken from the Content-Range header of the responses.
+*/
+package httprange
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrRangeNotSupported is returned by ReadAt if the server ignores the Range
+// header and returns the whole file.
+var ErrRangeNotSupported = errors.New("server does not support range requests")
+
+// ErrSizeUnknown is returned by Size if the server doesn't say how long the
+// file is.
+var ErrSizeUnknown = errors.New("server did not report the file size")
+
+const (
+	// DefaultBlockSize is the default size of each range request.
+	DefaultBlockSize = 64 * 1024
+	// DefaultCacheBlocks is the default number of blocks to keep cached.
+	DefaultCacheBlocks = 64
+)
+
+// Options configures a Reader.
+type Options struct {
+	// Client is the HTTP client to use. If nil, http.DefaultClient is used.
+	Client *http.Client
+	// BlockSize is the size of each range request. If zero, DefaultBlockSize
+	// is used.
+	BlockSize int64
+	// CacheBlocks is the number of blocks to cache. If zero,
+	// DefaultCacheBlocks is used; if negative, blocks aren't cached.
+	CacheBlocks int
+	// Header contains extra headers to add to each request, for example for
+	// authentication.
+	Header http.Header
+}
+
+// Reader is an io.ReaderAt over a remote file, using HTTP range requests. It
+// is safe for concurrent use.
+type Reader struct {
+	url         string
+	client      *http.Client
+	header      http.Header
+	blockSize   int64
+	cacheBlocks int
+
+	mu        sync.Mutex
+	lru       *list.List
+	cache     map[int64]*list.Element
+	size      int64
+	sizeKnown bool
+}
+
+type block struct {
+	index int64
+	data  []byte
+}
+
+// New creates a Reader for the file at url. If opts is nil, the default
+// options are used.
+func New(url string, opts *Options) *Reader {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	r := &Reader{
+		url:         url,
+		client:      opts.Client,
+		header:      opts.Header,
+		blockSize:   opts.BlockSize,
+		cacheBlocks: opts.CacheBlocks,
+		lru:         list.New(),
+		cache:       make(map[int64]*list.Element),
+	}
+
+	if r.client == nil {
+		r.client = http.DefaultClient
+	}
+
+	if r.blockSize <= 0 {
+		r.blockSize = DefaultBlockSize
+	}
+
+	if r.cacheBlocks == 0 {
+		r.cacheBlocks = DefaultCacheBlocks
+	}
+
+	return r
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("httprange: negative offset")
+	}
+
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		data, err := r.block(pos / r.blockSize)
+		if err != nil {
+			return n, err
+		}
+
+		start := pos % r.blockSize
+		if start >= int64(len(data)) {
+			return n, io.EOF
+		}
+
+		copied := copy(p[n:], data[start:])
+		n += copied
+
+		// A short block means we've reached the end of the file.
+		if n < len(p) && int64(len(data)) < r.blockSize {
+			return n, io.EOF
+		}
+	}
+
+	return n, nil
+}
+
+// Size returns the size of the remote file, as reported by the server in the
+// Content-Range header. If no request has been made yet, it fetches the first
+// block, which is then cached.
+func (r *Reader) Size() (int64, error) {
+	size, ok := r.knownSize()
+	if ok {
+		return size, nil
+	}
+
+	_, err := r.block(0)
+	if err != nil {
+		return 0, err
+	}
+
+	size, ok = r.knownSize()
+	if !ok {
+		return 0, ErrSizeUnknown
+	}
+
+	return size, nil
+}
+
+func (r *Reader) knownSize() (int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.size, r.sizeKnown
+}
+
+func (r *Reader) block(index int64) ([]byte, error) {
+	r.mu.Lock()
+	if elem, ok := r.cache[index]; ok {
+		r.lru.MoveToFront(elem)
+		r.mu.Unlock()
+		return elem.Value.(*block).data, nil
+	}
+	r.mu.Unlock()
+
+	data, err := r.fetch(index*r.blockSize, r.blockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.cacheBlocks > 0 {
+		r.mu.Lock()
+		if _, ok := r.cache[index]; !ok {
+			r.cache[index] = r.lru.PushFront(&block{index: index, data: data})
+			if r.lru.Len() > r.cacheBlocks {
+				oldest := r.lru.Back()
+				r.lru.Remove(oldest)
+				delete(r.cache, oldest.Value.(*block).index)
+			}
+		}
+		r.mu.Unlock()
+	}
+
+	return data, nil
+}
+
+func (r *Reader) fetch(off, length int64) ([]byte, error) {
+	req, err := http.NewRequest("GET", r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+length-1))
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if size, ok := parseContentRangeSize(resp.Header.Get("Content-Range")); ok {
+		r.mu.Lock()
+		r.size, r.sizeKnown = size, true
+		r.mu.Unlock()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, nil
+	case http.StatusOK:
+		return nil, ErrRangeNotSupported
+	default:
+		return nil, fmt.Errorf("httprange: unexpected status fetching %s: %s", r.url, resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, length))
+}
+
+// parseContentRangeSize returns the complete length from a Content-Range
+// header, such as "bytes 0-99/1234" or "bytes */1234", if it's given.
+func parseContentRangeSize(header string) (int64, bool) {
+	i := strings.LastIndexByte(header, '/')
+	if !strings.HasPrefix(header, "bytes ") || i < 0 {
+		return 0, false
+	}
+
+	size, err := strconv.ParseInt(header[i+1:], 10, 64)
+	if err != nil || size < 0 {
+		return 0, false
+	}
+
+	return size, true
+}
@@ -0,0 +1,99 @@
+package httprange
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveFile(t *testing.T, data []byte) (*httptest.Server, *int64) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		http.ServeContent(w, req, "test.cdb", time.Time{}, bytes.NewReader(data))
+	}))
+
+	return server, &requests
+}
+
+func TestReadAt(t *testing.T) {
+	data, err := ioutil.ReadFile("../test/test.cdb")
+	require.NoError(t, err)
+
+	server, requests := serveFile(t, data)
+	defer server.Close()
+
+	r := New(server.URL, &Options{BlockSize: 100})
+
+	buf := make([]byte, 250)
+	n, err := r.ReadAt(buf, 50)
+	require.NoError(t, err)
+	assert.Equal(t, 250, n)
+	assert.Equal(t, data[50:300], buf)
+	assert.EqualValues(t, 3, *requests)
+
+	// These blocks are now cached.
+	n, err = r.ReadAt(buf[:10], 120)
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, data[120:130], buf[:10])
+	assert.EqualValues(t, 3, *requests)
+
+	// Reading past the end of the file returns io.EOF.
+	n, err = r.ReadAt(buf, int64(len(data)-5))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, data[len(data)-5:], buf[:5])
+
+	n, err = r.ReadAt(buf, int64(len(data)+1000))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestReadAtRangeNotSupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		f, _ := os.Open("../test/test.cdb")
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	defer server.Close()
+
+	r := New(server.URL, nil)
+	_, err := r.ReadAt(make([]byte, 10), 0)
+	assert.Equal(t, ErrRangeNotSupported, err)
+}
+
+func TestSize(t *testing.T) {
+	data, err := ioutil.ReadFile("../test/test.cdb")
+	require.NoError(t, err)
+
+	server, requests := serveFile(t, data)
+	defer server.Close()
+
+	r := New(server.URL, &Options{BlockSize: 100})
+	size, err := r.Size()
+	require.NoError(t, err)
+	assert.EqualValues(t, len(data), size)
+	assert.EqualValues(t, 1, *requests)
+
+	// The first block was fetched to find the size, so it's cached.
+	buf := make([]byte, 10)
+	_, err = r.ReadAt(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, data[:10], buf)
+	assert.EqualValues(t, 1, *requests)
+
+	size, err = r.Size()
+	require.NoError(t, err)
+	assert.EqualValues(t, len(data), size)
+	assert.EqualValues(t, 1, *requests)
+}
@@ -1,5 +1,7 @@
 package cdb
 
+import "time"
+
 // Iterator represents a sequential iterator over a CDB database.
 type Iterator struct {
 	db     *CDB
@@ -8,13 +10,30 @@ type Iterator struct {
 	err    error
 	key    []byte
 	value  []byte
+
+	// expires is the expiration time of the current record, for copying it
+	// to another database.
+	expires time.Time
+
+	// If expired is set, the iterator returns only expired records, instead
+	// of skipping them.
+	expired bool
+
+	// If keysOnly is set, the iterator doesn't read values.
+	keysOnly bool
+
+	// If reverse is set, the iterator visits the records at offsets
+	// backwards, and remaining is the number left, including the one at pos.
+	reverse   bool
+	offsets   []uint32
+	remaining int
 }
 
 // Iter creates an Iterator that can be used to iterate the database.
 func (cdb *CDB) Iter() *Iterator {
 	return &Iterator{
 		db:     cdb,
-		pos:    uint32(indexSize),
+		pos:    cdb.firstRecord(),
 		endPos: cdb.index[0].offset,
 	}
 }
@@ -24,29 +43,101 @@ func (cdb *CDB) Iter() *Iterator {
 // database or an error. After Next returns false, the Err method will return
 // any error that occurred while iterating.
 func (iter *Iterator) Next() bool {
-	if iter.pos >= iter.endPos {
+	if !iter.more() {
 		return false
 	}
 
-	keyLength, valueLength, err := readTuple(iter.db.reader, iter.pos)
+	err := iter.db.acquire(opScan)
 	if err != nil {
 		iter.err = err
 		return false
 	}
+	defer iter.db.release(opScan)
 
-	buf := make([]byte, keyLength+valueLength)
-	_, err = iter.db.reader.ReadAt(buf, int64(iter.pos+8))
-	if err != nil {
-		iter.err = err
-		return false
+	if iter.keysOnly {
+		return iter.nextKey()
 	}
 
-	// Update iterator state
-	iter.key = buf[:keyLength]
-	iter.value = buf[keyLength:]
-	iter.pos += 8 + keyLength + valueLength
+	// Skip over any records hidden by a view, or that have expired.
+	for iter.more() {
+		keyLength, valueLength, err := iter.db.readHeader(iter.pos, nil)
+		if err != nil {
+			iter.err = err
+			return false
+		}
+
+		buf := make([]byte, keyLength+valueLength)
+		_, err = iter.db.reader.ReadAt(buf, int64(iter.pos+8))
+		if err != nil {
+			iter.err = readError(ErrCorruptRecord, int64(iter.pos), err)
+			return false
+		}
+
+		iter.advance(iter.db.nextRecord(iter.pos + 8 + keyLength + valueLength))
+		if iter.db.hidden(buf[:keyLength]) {
+			continue
+		} else if iter.expired && !iter.db.hasExpired(buf[keyLength:]) {
+			continue
+		} else if !iter.expired && iter.db.expired(buf[keyLength:]) {
+			continue
+		}
+
+		value, err := iter.db.decodeValue(buf[:keyLength], buf[keyLength:])
+		if err != nil {
+			iter.err = err
+			return false
+		}
 
-	return true
+		// Update iterator state
+		iter.key = buf[:keyLength]
+		iter.value = value
+		iter.expires = iter.db.expiresAt(buf[keyLength:])
+		return true
+	}
+
+	return false
+}
+
+// nextKey is Next for iterators that don't read values.
+func (iter *Iterator) nextKey() bool {
+	for iter.more() {
+		key, next, skip, err := iter.db.readKey(iter.pos)
+		if err != nil {
+			iter.err = err
+			return false
+		}
+
+		iter.advance(next)
+		if !skip {
+			iter.key = key
+			return true
+		}
+	}
+
+	return false
+}
+
+// more returns true if there are records left to visit.
+func (iter *Iterator) more() bool {
+	if iter.reverse {
+		return iter.err == nil && iter.remaining > 0
+	}
+
+	return iter.pos < iter.endPos
+}
+
+// advance moves past the record at pos, given the offset of the record
+// stored after it.
+func (iter *Iterator) advance(next uint32) {
+	if !iter.reverse {
+		iter.pos = next
+		return
+	}
+
+	iter.remaining--
+	if iter.remaining > 0 {
+		iter.pos = iter.offsets[iter.remaining-1]
+	}
 }
 
 // Key returns the current key.
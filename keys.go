@@ -0,0 +1,69 @@
+package cdb
+
+// EachKey calls fn with the key of each record in the database, in the order
+// they're stored. Values are skipped over using their stored lengths, without
+// being read, so this is much cheaper than a full scan for databases with
+// large values. If fn returns an error, EachKey stops and returns it.
+func (cdb *CDB) EachKey(fn func(key []byte) error) error {
+	err := cdb.acquire(opScan)
+	if err != nil {
+		return err
+	}
+	defer cdb.release(opScan)
+
+	end := cdb.index[0].offset
+	for offset := cdb.firstRecord(); offset < end; {
+		key, next, skip, err := cdb.readKey(offset)
+		if err != nil {
+			return err
+		}
+
+		offset = next
+		if skip {
+			continue
+		}
+
+		err = fn(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Keys returns an Iterator over the keys in the database, which skips over
+// the values instead of reading them, as with EachKey. The iterator's Value
+// method always returns nil.
+func (cdb *CDB) Keys() *Iterator {
+	iter := cdb.Iter()
+	iter.keysOnly = true
+	return iter
+}
+
+// readKey reads the key of the record at offset, and returns it along with the
+// offset of the next record. It also returns true if the record should be
+// skipped, because it's hidden by a view or has expired.
+func (cdb *CDB) readKey(offset uint32) ([]byte, uint32, bool, error) {
+	keyLength, valueLength, err := cdb.readHeader(offset, nil)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	// The expiration time is stored at the start of the value, so it can be
+	// read along with the key.
+	n := keyLength
+	if cdb.opts.expiry && valueLength >= expiryPrefixSize {
+		n += expiryPrefixSize
+	}
+
+	buf := make([]byte, n)
+	_, err = cdb.reader.ReadAt(buf, int64(offset+8))
+	if err != nil {
+		return nil, 0, false, readError(ErrCorruptRecord, int64(offset), err)
+	}
+
+	key := buf[:keyLength]
+	next := cdb.nextRecord(offset + 8 + keyLength + valueLength)
+	return key, next, cdb.hidden(key) || cdb.expired(buf[keyLength:]), nil
+}
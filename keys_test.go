@@ -0,0 +1,56 @@
+package cdb_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEachKey(t *testing.T) {
+	m := &testMetrics{}
+	db, err := cdb.Open("./test/test.cdb", cdb.WithMetrics(m))
+	require.NoError(t, err)
+	defer db.Close()
+
+	m.bytes = 0
+	var keys []string
+	err = db.EachKey(func(key []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	})
+	require.NoError(t, err)
+
+	var expected []string
+	var valueBytes int
+	for _, record := range expectedRecords[:len(expectedRecords)-1] {
+		expected = append(expected, string(record[0]))
+		valueBytes += len(record[1])
+	}
+
+	assert.Equal(t, expected, keys)
+	assert.Equal(t, int(db.DataSize())-valueBytes, m.bytes)
+}
+
+func TestKeys(t *testing.T) {
+	writer := cdb.NewMem(cdb.WithExpiry())
+	require.NoError(t, writer.Put([]byte("foo"), bytes.Repeat([]byte("x"), 1000)))
+	require.NoError(t, writer.PutWithExpiry([]byte("expired"), []byte("x"), time.Now().Add(-time.Hour)))
+	require.NoError(t, writer.Put([]byte("bar"), []byte("y")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	var keys []string
+	iter := db.View(func(key []byte) bool { return string(key) != "bar" }).Keys()
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+		assert.Nil(t, iter.Value())
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, []string{"foo"}, keys)
+}
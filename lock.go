@@ -0,0 +1,60 @@
+package cdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrLocked is returned by Create and OpenForAppend, with WithLocking, if
+	// the database is open in another process, for reading or writing.
+	ErrLocked = errors.New("database is locked by another process")
+	// ErrBuilding is returned by Open, with WithLocking, if the database is
+	// still being written by another process. It matches ErrLocked, with
+	// errors.Is.
+	ErrBuilding = fmt.Errorf("%w: database is still being written", ErrLocked)
+)
+
+// WithLocking takes an advisory lock on the file (with flock(2)) when a
+// database is opened by path: a shared lock for Open and NewReloader, and an
+// exclusive lock for Create and OpenForAppend. That keeps servers from
+// opening a file that's partway through being written, and writers from
+// overwriting a file that's being served. Locks never wait; if the file is
+// locked, the function returns ErrBuilding or ErrLocked straight away.
+//
+// Locks are released when the CDB or Writer is closed; Freeze keeps the file
+// open, so it downgrades the exclusive lock to a shared one. They're only
+// advisory, so they only protect against other processes which also use
+// them. On platforms without flock, WithLocking has no effect.
+//
+// With WithLocking, Create doesn't truncate an existing file until it has
+// the lock.
+func WithLocking() Option {
+	return func(o *options) {
+		o.locking = true
+	}
+}
+
+// openFile opens the file at path with the given flags and, if the options
+// call for it, locks it: exclusively if it's opened for writing, and shared
+// otherwise.
+func openFile(path string, flag int, o options) (*os.File, error) {
+	f, err := os.OpenFile(path, flag, 0666)
+	if err != nil || !o.locking {
+		return f, err
+	}
+
+	exclusive := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	err = lockFile(f, exclusive)
+	if err == ErrLocked && !exclusive {
+		err = ErrBuilding
+	}
+
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,83 @@
+package cdb_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocking(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("flock isn't supported")
+	}
+
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.cdb")
+	writer, err := cdb.Create(path, cdb.WithLocking())
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+
+	// Readers can't open the file until it's finished, and neither can
+	// other writers.
+	_, err = cdb.Open(path, cdb.WithLocking())
+	assert.Equal(t, cdb.ErrBuilding, err)
+	assert.True(t, errors.Is(err, cdb.ErrLocked))
+
+	_, err = cdb.Create(path, cdb.WithLocking())
+	assert.Equal(t, cdb.ErrLocked, err)
+
+	_, err = cdb.NewReloader(path, cdb.WithLocking())
+	assert.Equal(t, cdb.ErrBuilding, err)
+
+	// Without the option, the lock is ignored.
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	f.Close()
+
+	require.NoError(t, writer.Close())
+
+	db, err := cdb.Open(path, cdb.WithLocking())
+	require.NoError(t, err)
+
+	other, err := cdb.Open(path, cdb.WithLocking())
+	require.NoError(t, err)
+	require.NoError(t, other.Close())
+
+	// A file that's being read can't be overwritten, or appended to.
+	_, err = cdb.Create(path, cdb.WithLocking())
+	assert.Equal(t, cdb.ErrLocked, err)
+
+	_, err = cdb.OpenForAppend(path, cdb.WithLocking())
+	assert.Equal(t, cdb.ErrLocked, err)
+
+	v, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(v))
+	require.NoError(t, db.Close())
+
+	writer, err = cdb.OpenForAppend(path, cdb.WithLocking())
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("baz"), []byte("qux")))
+
+	// Freeze leaves the file open for reading, with a shared lock.
+	db, err = writer.Freeze()
+	require.NoError(t, err)
+	defer db.Close()
+
+	other, err = cdb.Open(path, cdb.WithLocking())
+	require.NoError(t, err)
+	require.NoError(t, other.Close())
+
+	_, err = cdb.Create(path, cdb.WithLocking())
+	assert.Equal(t, cdb.ErrLocked, err)
+}
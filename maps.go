@@ -0,0 +1,69 @@
+package cdb
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrTooManyRecords is returned by ToMap for a database with more records
+// than the limit.
+var ErrTooManyRecords = errors.New("database has too many records")
+
+// FromMap creates a database at the given path, overwriting any existing file,
+// with a record for each entry in m. The records are written sorted by key, so
+// the same map always produces the same file.
+//
+// The keys in m are sorted in memory first, so FromMap is best kept to small
+// databases; for larger ones, write the records with a Writer as they're
+// produced, or use a Sorter.
+func FromMap(m map[string][]byte, path string, opts ...Option) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	writer, err := Create(path, opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err := writer.Put(stringBytes(key), m[key])
+		if err != nil {
+			writer.Abort()
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// ToMap reads every record in the database into a map. Where there are several
+// records for a key, the map holds the first, as returned by Get.
+//
+// Since the whole database ends up in memory, ToMap returns
+// ErrTooManyRecords, without reading any values, if there are more than limit
+// records. If limit is zero or less, there's no limit, which is only safe if
+// the database is known to be small.
+func (cdb *CDB) ToMap(limit int) (map[string][]byte, error) {
+	if limit > 0 {
+		n, err := cdb.Len()
+		if err != nil {
+			return nil, err
+		} else if n > limit {
+			return nil, ErrTooManyRecords
+		}
+	}
+
+	m := make(map[string][]byte)
+	iter := cdb.Iter()
+	for iter.Next() {
+		key := string(iter.Key())
+		if _, ok := m[key]; !ok {
+			m[key] = iter.Value()
+		}
+	}
+
+	return m, iter.Err()
+}
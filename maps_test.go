@@ -0,0 +1,56 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromMap(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	m := map[string][]byte{"foo": []byte("bar"), "baz": []byte("qux"), "empty": {}}
+	require.NoError(t, cdb.FromMap(m, f.Name()))
+
+	db, err := cdb.Open(f.Name())
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The records are sorted by key.
+	var keys []string
+	iter := db.Iter()
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, []string{"baz", "empty", "foo"}, keys)
+
+	got, err := db.ToMap(3)
+	require.NoError(t, err)
+	assert.Equal(t, m, got)
+}
+
+func TestToMap(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ToMap(2)
+	assert.Equal(t, cdb.ErrTooManyRecords, err)
+
+	m, err := db.ToMap(0)
+	require.NoError(t, err)
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, value, m[string(record[0])])
+	}
+}
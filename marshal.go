@@ -0,0 +1,56 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// MarshalIndex returns the database's index in the same form it's stored at
+// the head of the file: 256 pairs of little-endian uint32s, giving the offset
+// and length of each hash table. It can be saved, and passed to
+// UnmarshalIndex and NewWithIndex to open the database again later without
+// reading the index from the file.
+func (cdb *CDB) MarshalIndex() []byte {
+	return cdb.index.marshal(binary.LittleEndian)
+}
+
+// UnmarshalIndex parses an index returned by MarshalIndex. It returns
+// ErrCorrupt if b isn't the right length.
+func UnmarshalIndex(b []byte) (Index, error) {
+	var idx Index
+	if len(b) != indexSize {
+		return idx, ErrCorrupt
+	}
+
+	for i, table := range parseIndex(b, binary.LittleEndian) {
+		idx[i] = Table{Offset: table.offset, Length: table.length}
+	}
+
+	return idx, nil
+}
+
+// NewWithIndex is like New, but uses the given index instead of reading it
+// from the file, which saves a read when opening a large number of databases
+// whose indexes are already known, such as from an earlier call to
+// MarshalIndex. The index must be the one stored in the file; NewWithIndex
+// checks that it fits the file, if the size of reader is known, but can't
+// otherwise tell whether it's out of date.
+func NewWithIndex(reader io.ReaderAt, hash func([]byte) uint32, idx Index, opts ...Option) (*CDB, error) {
+	customHash := hash != nil
+	if hash == nil {
+		hash = cdbHash
+	}
+
+	var index index
+	for i, t := range idx {
+		index[i] = table{offset: t.Offset, length: t.Length}
+	}
+
+	cdb := &CDB{reader: reader, hash: hash, opts: buildOptions(opts), customHash: customHash}
+	err := cdb.init(&index)
+	if err != nil {
+		return nil, err
+	}
+
+	return cdb, nil
+}
@@ -0,0 +1,44 @@
+package cdb_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalIndex(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	marshaled := db.MarshalIndex()
+	assert.Equal(t, data[:cdb.DataOffset], marshaled)
+	require.NoError(t, db.Close())
+
+	idx, err := cdb.UnmarshalIndex(marshaled)
+	require.NoError(t, err)
+
+	m := &testMetrics{}
+	f, err := os.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	db, err = cdb.NewWithIndex(f, nil, idx, cdb.WithMetrics(m))
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Equal(t, 0, m.bytes)
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	_, err = cdb.UnmarshalIndex(marshaled[:100])
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+}
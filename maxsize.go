@@ -0,0 +1,64 @@
+package cdb
+
+import (
+	"math"
+)
+
+// WithMaxSize limits the size of the file a Writer produces to maxSize bytes,
+// including the hash tables and anything else written when the database is
+// finalized. Put and PutReader return ErrTooMuchData as soon as a record
+// would take the finished file over the limit, rather than the database
+// failing to finalize; the estimate is conservative, so a database may be
+// rejected slightly before it would actually reach the limit.
+//
+// If the stream is a file, NewWriter also preallocates maxSize bytes of disk
+// space for it on platforms that support it (currently Linux), without
+// changing the file's size, so that a full disk is reported up front. If the
+// filesystem doesn't support preallocation, this step is skipped.
+func WithMaxSize(maxSize int64) Option {
+	return func(o *options) {
+		o.maxSize = maxSize
+	}
+}
+
+// checkSize returns ErrTooMuchData if adding a record of entrySize bytes would
+// make the finished database too large.
+func (cdb *Writer) checkSize(entrySize int64) error {
+	return cdb.checkFinalSize(cdb.bufferedOffset+entrySize, cdb.estimatedFooterSize, cdb.records+1)
+}
+
+// checkFinalSize returns ErrTooMuchData if a database with records up to
+// dataEnd, footerSize bytes of hash tables, and the given number of records
+// would be too large once finalized.
+func (cdb *Writer) checkFinalSize(dataEnd, footerSize, records int64) error {
+	end := dataEnd + footerSize + 16
+	if end > math.MaxUint32 {
+		return ErrTooMuchData
+	}
+
+	// Each of the 256 tables can be rounded up by a slot, on top of the
+	// per-entry estimate.
+	if cdb.opts.maxSize > 0 {
+		end += 256*8 + cdb.extensionsSize(records)
+		if end > cdb.opts.maxSize {
+			return ErrTooMuchData
+		}
+	}
+
+	return nil
+}
+
+// preallocate reserves disk space for the database, if the options call for
+// it and the stream is a file.
+func (cdb *Writer) preallocate() error {
+	if cdb.opts.maxSize <= 0 {
+		return nil
+	}
+
+	f, ok := cdb.writer.(interface{ Fd() uintptr })
+	if !ok {
+		return nil
+	}
+
+	return fallocate(f.Fd(), cdb.opts.maxSize)
+}
@@ -0,0 +1,16 @@
+package cdb
+
+import "syscall"
+
+// fallocateKeepSize is FALLOC_FL_KEEP_SIZE, which allocates space without
+// changing the file size.
+const fallocateKeepSize = 0x1
+
+func fallocate(fd uintptr, size int64) error {
+	err := syscall.Fallocate(int(fd), fallocateKeepSize, 0, size)
+	if err == syscall.EOPNOTSUPP || err == syscall.ENOSYS {
+		return nil
+	}
+
+	return err
+}
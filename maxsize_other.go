@@ -0,0 +1,7 @@
+//go:build !linux
+
+package cdb
+
+func fallocate(fd uintptr, size int64) error {
+	return nil
+}
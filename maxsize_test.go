@@ -0,0 +1,49 @@
+package cdb_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxSize(t *testing.T) {
+	for _, opts := range [][]cdb.Option{
+		{cdb.WithMaxSize(8192)},
+		{cdb.WithMaxSize(8192), cdb.WithHeader(), cdb.WithBloomFilter(16)},
+		{cdb.WithMaxSize(8192), cdb.WithLoadFactor(0.3)},
+	} {
+		path := filepath.Join(t.TempDir(), "max.cdb")
+		writer, err := cdb.Create(path, opts...)
+		require.NoError(t, err)
+
+		n := 0
+		for ; ; n++ {
+			err := writer.Put([]byte(fmt.Sprintf("key%d", n)), []byte("value"))
+			if err == cdb.ErrTooMuchData {
+				break
+			}
+
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, writer.Close())
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.True(t, info.Size() <= 8192, "expected at most 8192 bytes, got %d", info.Size())
+		assert.True(t, info.Size() > 4096, "expected the limit to be mostly used, got %d", info.Size())
+
+		db, err := cdb.Open(path)
+		require.NoError(t, err)
+
+		count, err := db.Len()
+		require.NoError(t, err)
+		assert.Equal(t, n, count)
+		require.NoError(t, db.Close())
+	}
+}
@@ -0,0 +1,71 @@
+package cdb
+
+import (
+	"bytes"
+	"io"
+)
+
+// NewMem creates a Writer that builds a database in memory. Freeze returns a
+// CDB that reads directly from the resulting byte slice, so no files are
+// involved at any point; this is useful for tests and for small lookup tables
+// built at runtime.
+func NewMem(opts ...Option) *Writer {
+	// Writing to memory can't fail.
+	writer, _ := NewWriter(&memBuffer{}, nil, opts...)
+	return writer
+}
+
+// FromBytes opens a database stored in b, which must not be modified while
+// the database is in use.
+func FromBytes(b []byte, opts ...Option) (*CDB, error) {
+	return New(bytes.NewReader(b), nil, opts...)
+}
+
+// memBuffer is a growable in-memory file.
+type memBuffer struct {
+	buf []byte
+}
+
+func (mb *memBuffer) Write(b []byte) (int, error) {
+	mb.buf = append(mb.buf, b...)
+	return len(b), nil
+}
+
+func (mb *memBuffer) WriteAt(b []byte, off int64) (int, error) {
+	if end := off + int64(len(b)); end > int64(len(mb.buf)) {
+		if end > int64(cap(mb.buf)) {
+			grown := make([]byte, end, 2*end)
+			copy(grown, mb.buf)
+			mb.buf = grown
+		} else {
+			mb.buf = mb.buf[:end]
+		}
+	}
+
+	return copy(mb.buf[off:], b), nil
+}
+
+func (mb *memBuffer) ReadAt(b []byte, off int64) (int, error) {
+	if off >= int64(len(mb.buf)) {
+		return 0, io.EOF
+	}
+
+	n := copy(b, mb.buf[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (mb *memBuffer) Size() int64 {
+	return int64(len(mb.buf))
+}
+
+func (mb *memBuffer) Truncate(size int64) error {
+	if size < int64(len(mb.buf)) {
+		mb.buf = mb.buf[:size]
+	}
+
+	return nil
+}
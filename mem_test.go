@@ -0,0 +1,48 @@
+package cdb_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMem(t *testing.T) {
+	writer := cdb.NewMem()
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, writer.Put(record[0], record[1]))
+		}
+	}
+
+	require.NoError(t, writer.Put([]byte("deleted"), []byte("x")))
+	require.NoError(t, writer.Delete([]byte("deleted")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	value, err := db.Get([]byte("deleted"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestFromBytes(t *testing.T) {
+	data, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	db, err := cdb.FromBytes(data)
+	require.NoError(t, err)
+	testArchivedGet(t, db)
+
+	_, err = cdb.FromBytes(data[:100])
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+}
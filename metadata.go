@@ -0,0 +1,106 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// SetMetadata stores a metadata entry in the database, such as when it was
+// built or the version of its source, replacing any earlier value for the same
+// key. Metadata is written to the extension block after the hash tables, so it
+// isn't a record: it doesn't show up when iterating, and other cdb
+// implementations ignore it. When appending to a database, its existing
+// metadata is kept.
+//
+// SetMetadata returns ErrTooMuchData if the entry would take the database
+// past the size limit.
+func (cdb *Writer) SetMetadata(key, value string) error {
+	err := cdb.checkWritable()
+	if err != nil {
+		return err
+	}
+
+	old, replaced := cdb.metadata[key]
+	if cdb.metadata == nil {
+		cdb.metadata = make(map[string]string)
+	}
+
+	cdb.metadata[key] = value
+	err = cdb.checkFinalSize(cdb.bufferedOffset, cdb.estimatedFooterSize, cdb.records)
+	if err != nil {
+		if replaced {
+			cdb.metadata[key] = old
+		} else {
+			delete(cdb.metadata, key)
+		}
+	}
+
+	return err
+}
+
+// Metadata returns the metadata stored in the database with
+// Writer.SetMetadata, or an empty map if it doesn't have any. Like bloom
+// filters, metadata is only read if the size of the underlying io.ReaderAt is
+// known.
+func (cdb *CDB) Metadata() map[string]string {
+	metadata := make(map[string]string, len(cdb.metadata))
+	for k, v := range cdb.metadata {
+		metadata[k] = v
+	}
+
+	return metadata
+}
+
+// buildMetadata encodes the metadata for the extension block, as a series of
+// entries sorted by key, each a key length and value length (little-endian
+// uint32s) followed by the key and value.
+func (cdb *Writer) buildMetadata() []byte {
+	keys := make([]string, 0, len(cdb.metadata))
+	for k := range cdb.metadata {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	buf := make([]byte, 0, metadataSize(cdb.metadata))
+	for _, k := range keys {
+		v := cdb.metadata[k]
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(k)))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(v)))
+		buf = append(buf, k...)
+		buf = append(buf, v...)
+	}
+
+	return buf
+}
+
+// metadataSize returns the size of the encoded metadata.
+func metadataSize(metadata map[string]string) int64 {
+	var size int64
+	for k, v := range metadata {
+		size += 8 + int64(len(k)) + int64(len(v))
+	}
+
+	return size
+}
+
+// parseMetadata decodes the metadata section.
+func parseMetadata(data []byte) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, ErrCorrupt
+		}
+
+		keyLength := uint64(binary.LittleEndian.Uint32(data))
+		valueLength := uint64(binary.LittleEndian.Uint32(data[4:]))
+		data = data[8:]
+		if keyLength+valueLength > uint64(len(data)) {
+			return nil, ErrCorrupt
+		}
+
+		metadata[string(data[:keyLength])] = string(data[keyLength : keyLength+valueLength])
+		data = data[keyLength+valueLength:]
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,57 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.SetMetadata("version", "1"))
+	require.NoError(t, writer.SetMetadata("version", "2"))
+	require.NoError(t, writer.SetMetadata("built", "2024-01-01"))
+	require.NoError(t, writer.SetMetadata("empty", ""))
+	require.NoError(t, writer.Close())
+
+	db, err := cdb.Open(f.Name())
+	require.NoError(t, err)
+
+	expected := map[string]string{"version": "2", "built": "2024-01-01", "empty": ""}
+	assert.Equal(t, expected, db.Metadata())
+
+	// Metadata isn't a record.
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	db.Close()
+
+	// Appending keeps the existing metadata.
+	writer, err = cdb.OpenForAppend(f.Name())
+	require.NoError(t, err)
+	require.NoError(t, writer.SetMetadata("version", "3"))
+	db, err = writer.Freeze()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expected["version"] = "3"
+	assert.Equal(t, expected, db.Metadata())
+}
+
+func TestNoMetadata(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Empty(t, db.Metadata())
+}
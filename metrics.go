@@ -0,0 +1,60 @@
+package cdb
+
+import (
+	"io"
+)
+
+// MetricsSink receives metrics from a CDB opened with WithMetrics, for example
+// to export them to Prometheus. Implementations must be safe for concurrent
+// use, and should be cheap, since they're called synchronously.
+type MetricsSink interface {
+	// ObserveGet is called after each lookup, with whether the key was found
+	// and the number of hash table slots that were probed.
+	ObserveGet(found bool, probes int)
+	// ObserveRead is called after each read from the underlying
+	// io.ReaderAt, with the number of bytes read.
+	ObserveRead(bytes int)
+}
+
+// WithMetrics causes a CDB to report metrics for lookups and reads to m.
+func WithMetrics(m MetricsSink) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// metricsReader reports the bytes read from an io.ReaderAt to a MetricsSink.
+type metricsReader struct {
+	io.ReaderAt
+	metrics MetricsSink
+}
+
+func (mr metricsReader) ReadAt(b []byte, off int64) (int, error) {
+	n, err := mr.ReaderAt.ReadAt(b, off)
+	mr.metrics.ObserveRead(n)
+	return n, err
+}
+
+// ReadAtv reports a batch of reads as a single read, if the underlying reader
+// is a ReaderAtv.
+func (mr metricsReader) ReadAtv(bufs [][]byte, offsets []int64) (int, error) {
+	rv, ok := mr.ReaderAt.(ReaderAtv)
+	if !ok {
+		return readEach(mr, bufs, offsets)
+	}
+
+	n, err := rv.ReadAtv(bufs, offsets)
+	mr.metrics.ObserveRead(n)
+	return n, err
+}
+
+func (cdb *CDB) observeGet(found bool, p *probe) {
+	if cdb.opts.metrics != nil {
+		cdb.opts.metrics.ObserveGet(found, int(p.seen))
+	}
+
+	if cdb.tracing != nil {
+		cdb.tracing.Found = found
+		cdb.tracing.Probes = int(p.seen)
+	}
+}
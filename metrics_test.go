@@ -0,0 +1,56 @@
+package cdb_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testMetrics struct {
+	sync.Mutex
+	hits, misses int
+	probes       int
+	bytes        int
+}
+
+func (m *testMetrics) ObserveGet(found bool, probes int) {
+	m.Lock()
+	defer m.Unlock()
+
+	if found {
+		m.hits++
+	} else {
+		m.misses++
+	}
+
+	m.probes += probes
+}
+
+func (m *testMetrics) ObserveRead(bytes int) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.bytes += bytes
+}
+
+func TestMetrics(t *testing.T) {
+	m := &testMetrics{}
+	db, err := cdb.Open("./test/test.cdb", cdb.WithMetrics(m))
+	require.NoError(t, err)
+	assert.Equal(t, 2048, m.bytes)
+
+	for _, record := range expectedRecords {
+		_, err := db.Get(record[0])
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, len(expectedRecords)-1, m.hits)
+	assert.Equal(t, 1, m.misses)
+	assert.True(t, m.probes >= len(expectedRecords)-1)
+	assert.True(t, m.bytes > 2048)
+
+	require.NoError(t, db.Close())
+}
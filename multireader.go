@@ -0,0 +1,122 @@
+package cdb
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+// SizedReaderAt is an io.ReaderAt that knows how big it is, such as an
+// io.SectionReader, a bytes.Reader, or a strings.Reader.
+type SizedReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// MultiReaderAt presents several segments, one after another, as a single
+// io.ReaderAt, so that a database stored in chunks, such as the parts of a
+// multipart upload to an object store, can be read without putting the
+// chunks back together first. Reads that cross from one segment to the next
+// are split between them.
+//
+// A MultiReaderAt is safe for concurrent use as long as the segments are.
+type MultiReaderAt struct {
+	segments []SizedReaderAt
+
+	// ends holds the offset just past the end of each segment.
+	ends []int64
+}
+
+// NewMultiReaderAt returns a MultiReaderAt over the given segments, in order.
+// The size of each segment is read once, up front, so segments mustn't change
+// size afterwards.
+func NewMultiReaderAt(segments ...SizedReaderAt) *MultiReaderAt {
+	mr := &MultiReaderAt{
+		segments: segments,
+		ends:     make([]int64, len(segments)),
+	}
+
+	end := int64(0)
+	for i, segment := range segments {
+		end += segment.Size()
+		mr.ends[i] = end
+	}
+
+	return mr
+}
+
+// NewFromSegments opens a database stored in the given segments, one after
+// another, using a MultiReaderAt. If any of the segments are io.Closers, Close
+// closes them. The hash function and options are the same as for New.
+func NewFromSegments(segments []SizedReaderAt, hash func([]byte) uint32, opts ...Option) (*CDB, error) {
+	return New(NewMultiReaderAt(segments...), hash, opts...)
+}
+
+// ReadAt reads len(b) bytes starting at off, from as many segments as it
+// takes.
+func (mr *MultiReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+
+	read := 0
+	i := sort.Search(len(mr.ends), func(i int) bool { return mr.ends[i] > off })
+	for len(b) > 0 {
+		if i >= len(mr.segments) {
+			return read, io.EOF
+		}
+
+		start := int64(0)
+		if i > 0 {
+			start = mr.ends[i-1]
+		}
+
+		chunk := b
+		if int64(len(chunk)) > mr.ends[i]-off {
+			chunk = chunk[:mr.ends[i]-off]
+		}
+
+		// A segment is allowed to return io.EOF along with the last of its
+		// bytes, which isn't the end of the whole.
+		n, err := mr.segments[i].ReadAt(chunk, off-start)
+		read += n
+		if n < len(chunk) {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return read, err
+		}
+
+		off += int64(n)
+		b = b[n:]
+		i++
+	}
+
+	return read, nil
+}
+
+// Size returns the total size of the segments.
+func (mr *MultiReaderAt) Size() int64 {
+	if len(mr.ends) == 0 {
+		return 0
+	}
+
+	return mr.ends[len(mr.ends)-1]
+}
+
+// Close closes every segment that's an io.Closer, and returns the first
+// error, if any.
+func (mr *MultiReaderAt) Close() error {
+	var firstErr error
+	for _, segment := range mr.segments {
+		if closer, ok := segment.(io.Closer); ok {
+			err := closer.Close()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
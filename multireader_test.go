@@ -0,0 +1,74 @@
+package cdb_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeCounter struct {
+	*bytes.Reader
+	closes *int
+}
+
+func (cc closeCounter) Close() error {
+	*cc.closes++
+	return nil
+}
+
+func TestMultiReaderAt(t *testing.T) {
+	data, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	// Chop the file into uneven chunks, including an empty one.
+	closes := 0
+	var segments []cdb.SizedReaderAt
+	for off, size := 0, 1; off < len(data); size *= 3 {
+		end := off + size
+		if end > len(data) {
+			end = len(data)
+		}
+
+		segments = append(segments, closeCounter{bytes.NewReader(data[off:end]), &closes})
+		if off == 0 {
+			segments = append(segments, bytes.NewReader(nil))
+		}
+
+		off = end
+	}
+
+	require.True(t, len(segments) > 3)
+	mr := cdb.NewMultiReaderAt(segments...)
+	assert.Equal(t, int64(len(data)), mr.Size())
+
+	for _, span := range [][2]int{{0, len(data)}, {0, 1}, {1, 5}, {3, 40}, {len(data) - 3, len(data)}} {
+		buf := make([]byte, span[1]-span[0])
+		n, err := mr.ReadAt(buf, int64(span[0]))
+		require.NoError(t, err)
+		assert.Equal(t, len(buf), n)
+		assert.Equal(t, data[span[0]:span[1]], buf)
+	}
+
+	buf := make([]byte, 10)
+	n, err := mr.ReadAt(buf, int64(len(data)-4))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, data[len(data)-4:], buf[:4])
+
+	db, err := cdb.NewFromSegments(segments, nil)
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	require.NoError(t, db.Close())
+	assert.Equal(t, len(segments)-1, closes)
+}
@@ -0,0 +1,154 @@
+package cdb
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+)
+
+// Namespace is a logical table inside a database, made up of the records
+// whose keys start with a given prefix. It adds the prefix to keys when
+// looking them up, and strips it from keys when iterating, so that several
+// tables can share one file without each caller having to remember to
+// prefix its keys. Records outside the namespace can't be read through it.
+//
+// A Namespace is safe for concurrent use, like the CDB it belongs to.
+type Namespace struct {
+	db     *CDB
+	prefix []byte
+}
+
+// NamespaceWriter is the Writer side of a Namespace: it adds a prefix to the
+// keys of the records written through it.
+type NamespaceWriter struct {
+	w      *Writer
+	prefix []byte
+}
+
+// Namespace returns the namespace of records whose keys start with prefix.
+// If the database was opened with WithKeyNormalizer, the prefix is added to
+// keys before they're normalized, so normalizing it shouldn't change it.
+// Closing the database closes every namespace of it.
+//
+// A namespace should use a prefix that isn't a prefix of any other, such as
+// one ending with a separator like "users/", or records from one namespace
+// will be visible in the other.
+//
+// With WithKeyHMAC or WithHashedKeys, the stored keys are digests, so there's
+// no way to tell which records are in a namespace, and Namespace returns
+// os.ErrInvalid. Records written through a NamespaceWriter can still be read
+// by adding the prefix to keys and looking them up in the database directly.
+func (cdb *CDB) Namespace(prefix []byte) (*Namespace, error) {
+	if cdb.opts.keyHMAC != nil || cdb.opts.hashedKeyBits != 0 {
+		return nil, os.ErrInvalid
+	}
+
+	prefix = append([]byte(nil), prefix...)
+	return &Namespace{
+		db: cdb.View(func(key []byte) bool {
+			return bytes.HasPrefix(key, prefix)
+		}),
+		prefix: prefix,
+	}, nil
+}
+
+// Namespace returns a NamespaceWriter that adds prefix to the keys of the
+// records written through it. Records can still be written to the Writer
+// directly, or to other namespaces, and they all end up in the same file.
+func (cdb *Writer) Namespace(prefix []byte) *NamespaceWriter {
+	return &NamespaceWriter{w: cdb, prefix: append([]byte(nil), prefix...)}
+}
+
+// Prefix returns the namespace's prefix.
+func (ns *Namespace) Prefix() []byte {
+	return ns.prefix
+}
+
+// Get returns the value for a given key in the namespace, or nil if it
+// can't be found.
+func (ns *Namespace) Get(key []byte) ([]byte, error) {
+	return ns.db.Get(ns.key(key))
+}
+
+// GetAll returns every value stored for a given key in the namespace, like
+// CDB.GetAll.
+func (ns *Namespace) GetAll(key []byte) ([][]byte, error) {
+	return ns.db.GetAll(ns.key(key))
+}
+
+// GetInto copies the value for a given key in the namespace into dst, like
+// CDB.GetInto.
+func (ns *Namespace) GetInto(key, dst []byte) (int, bool, error) {
+	return ns.db.GetInto(ns.key(key), dst)
+}
+
+// Scan calls visit with every value stored for a given key in the namespace,
+// like CDB.Scan.
+func (ns *Namespace) Scan(key []byte, visit func(value []byte) (stop bool, err error)) error {
+	return ns.db.Scan(ns.key(key), visit)
+}
+
+// Each calls fn with the key, without the prefix, and value of each record in
+// the namespace, in the order they're stored. If fn returns an error, Each
+// stops and returns it.
+func (ns *Namespace) Each(fn func(key, value []byte) error) error {
+	iter := ns.db.Iter()
+	for iter.Next() {
+		err := fn(iter.Key()[len(ns.prefix):], iter.Value())
+		if err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+// EachKey calls fn with the key, without the prefix, of each record in the
+// namespace, skipping over the values as CDB.EachKey does.
+func (ns *Namespace) EachKey(fn func(key []byte) error) error {
+	return ns.db.EachKey(func(key []byte) error {
+		return fn(key[len(ns.prefix):])
+	})
+}
+
+// key returns key with the namespace's prefix.
+func (ns *Namespace) key(key []byte) []byte {
+	return namespaced(ns.prefix, key)
+}
+
+// Prefix returns the namespace's prefix.
+func (nw *NamespaceWriter) Prefix() []byte {
+	return nw.prefix
+}
+
+// Put adds a key/value pair to the namespace, like Writer.Put.
+func (nw *NamespaceWriter) Put(key, value []byte) error {
+	return nw.w.Put(namespaced(nw.prefix, key), value)
+}
+
+// PutWithExpiry adds a key/value pair to the namespace that expires at the
+// given time, like Writer.PutWithExpiry.
+func (nw *NamespaceWriter) PutWithExpiry(key, value []byte, expires time.Time) error {
+	return nw.w.PutWithExpiry(namespaced(nw.prefix, key), value, expires)
+}
+
+// PutReader adds a key to the namespace with a value streamed from r, like
+// Writer.PutReader.
+func (nw *NamespaceWriter) PutReader(key []byte, valueLength uint32, r io.Reader) error {
+	return nw.w.PutReader(namespaced(nw.prefix, key), valueLength, r)
+}
+
+// Delete removes any records previously added to the namespace with the
+// given key, like Writer.Delete.
+func (nw *NamespaceWriter) Delete(key []byte) error {
+	return nw.w.Delete(namespaced(nw.prefix, key))
+}
+
+// namespaced returns a new slice with prefix followed by key.
+func namespaced(prefix, key []byte) []byte {
+	buf := make([]byte, len(prefix)+len(key))
+	copy(buf, prefix)
+	copy(buf[len(prefix):], key)
+	return buf
+}
@@ -0,0 +1,98 @@
+package cdb_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace(t *testing.T) {
+	writer := cdb.NewMem(cdb.WithExpiry())
+	users := writer.Namespace([]byte("users/"))
+	groups := writer.Namespace([]byte("groups/"))
+
+	require.NoError(t, users.Put([]byte("alice"), []byte("1")))
+	require.NoError(t, users.PutReader([]byte("bob"), 1, strings.NewReader("2")))
+	require.NoError(t, users.PutWithExpiry([]byte("carol"), []byte("3"), time.Now().Add(-time.Hour)))
+	require.NoError(t, groups.Put([]byte("alice"), []byte("admins")))
+	require.NoError(t, groups.Put([]byte("staff"), []byte("x")))
+	require.NoError(t, groups.Delete([]byte("staff")))
+	require.NoError(t, writer.Put([]byte("alice"), []byte("top level")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	ns, err := db.Namespace([]byte("users/"))
+	require.NoError(t, err)
+	assert.Equal(t, "users/", string(ns.Prefix()))
+
+	value, err := ns.Get([]byte("alice"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(value))
+
+	groupsNS, err := db.Namespace([]byte("groups/"))
+	require.NoError(t, err)
+
+	value, err = groupsNS.Get([]byte("alice"))
+	require.NoError(t, err)
+	assert.Equal(t, "admins", string(value))
+
+	value, err = groupsNS.Get([]byte("staff"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	value, err = db.Get([]byte("alice"))
+	require.NoError(t, err)
+	assert.Equal(t, "top level", string(value))
+
+	values, err := ns.GetAll([]byte("bob"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("2")}, values)
+
+	buf := make([]byte, 1)
+	n, found, err := ns.GetInto([]byte("bob"), buf)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "2", string(buf[:n]))
+
+	// Expired records are skipped, and records outside the namespace aren't
+	// visited.
+	got := make(map[string]string)
+	require.NoError(t, ns.Each(func(key, value []byte) error {
+		got[string(key)] = string(value)
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"alice": "1", "bob": "2"}, got)
+
+	var keys []string
+	require.NoError(t, ns.EachKey(func(key []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	}))
+	assert.Equal(t, []string{"alice", "bob"}, keys)
+}
+
+func TestNamespaceDigestedKeys(t *testing.T) {
+	for name, opt := range map[string]cdb.Option{
+		"hmac":   cdb.WithKeyHMAC([]byte("secret")),
+		"hashed": cdb.WithHashedKeys(64),
+	} {
+		writer := cdb.NewMem(opt)
+		require.NoError(t, writer.Namespace([]byte("users/")).Put([]byte("alice"), []byte("1")))
+
+		db, err := writer.Freeze()
+		require.NoError(t, err)
+
+		_, err = db.Namespace([]byte("users/"))
+		assert.Equal(t, os.ErrInvalid, err, name)
+
+		value, err := db.Get([]byte("users/alice"))
+		require.NoError(t, err)
+		assert.Equal(t, "1", string(value), name)
+	}
+}
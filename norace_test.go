@@ -0,0 +1,5 @@
+//go:build !race
+
+package cdb_test
+
+const raceEnabled = false
@@ -0,0 +1,48 @@
+package cdb
+
+import (
+	"bytes"
+)
+
+// WithKeyNormalizer causes every key to be passed through fn before it's used:
+// when writing, by Put, PutReader and Delete, and when reading, by Get and
+// GetInto. Registering the same normalizer on both sides guarantees that the
+// write and read paths agree, for example on case.
+//
+// The normalizer must be deterministic and idempotent, since keys read back
+// from the database (by Iter, for example) have already been normalized, and
+// may be normalized again when they're looked up. It must not modify key in
+// place.
+func WithKeyNormalizer(fn func(key []byte) []byte) Option {
+	return func(o *options) {
+		o.normalizer = fn
+	}
+}
+
+// LowercaseKeys is a normalizer for use with WithKeyNormalizer, which maps
+// keys to lower case, treating them as UTF-8.
+func LowercaseKeys(key []byte) []byte {
+	return bytes.ToLower(key)
+}
+
+// normalizeKey returns key as it's stored: with the normalizer applied, if
+// there is one, and then replaced by its HMAC or hash, as storedKey does.
+func (o *options) normalizeKey(key []byte) []byte {
+	return o.storedKey(o.applyNormalizer(key))
+}
+
+// applyNormalizer applies the normalizer, if there is one. Validators see keys
+// at this point, before they're replaced by a digest.
+func (o *options) applyNormalizer(key []byte) []byte {
+	if o.normalizer != nil {
+		key = o.normalizer(key)
+	}
+
+	return key
+}
+
+// storedKey replaces a normalized key with its HMAC, if WithKeyHMAC is set,
+// and with its hash, if WithHashedKeys is set.
+func (o *options) storedKey(key []byte) []byte {
+	return o.compactKey(o.hashKey(key))
+}
@@ -0,0 +1,51 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyNormalizer(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	normalize := cdb.WithKeyNormalizer(cdb.LowercaseKeys)
+	writer, err := cdb.NewWriter(f, nil, normalize)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Put([]byte("Foo"), []byte("bar")))
+	require.NoError(t, writer.Put([]byte("BAZ"), []byte("quux")))
+	require.NoError(t, writer.Put([]byte("Deleted"), []byte("x")))
+	require.NoError(t, writer.Delete([]byte("DELETED")))
+	require.NoError(t, writer.Close())
+
+	db, err := cdb.Open(f.Name(), normalize)
+	require.NoError(t, err)
+	assertRecords(t, db, [][2]string{{"foo", "bar"}, {"baz", "quux"}})
+
+	for _, key := range []string{"foo", "FOO", "fOo"} {
+		value, err := db.Get([]byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, "bar", string(value), key)
+
+		buf := make([]byte, 8)
+		n, found, err := db.GetInto([]byte(key), buf)
+		require.NoError(t, err)
+		assert.True(t, found, key)
+		assert.Equal(t, "bar", string(buf[:n]), key)
+	}
+
+	// Without the normalizer, only the normalized form matches.
+	raw, err := cdb.Open(f.Name())
+	require.NoError(t, err)
+
+	value, err := raw.Get([]byte("FOO"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
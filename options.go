@@ -0,0 +1,90 @@
+package cdb
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"time"
+)
+
+// An Option configures how a database is read or written. Options that only
+// apply to reading are ignored when writing, and vice versa.
+type Option func(*options)
+
+type options struct {
+	pinTables  bool
+	validators []func(key, value []byte) error
+	progress   func(Progress)
+	compressor Compressor
+	loadFactor float64
+	normalizer func(key []byte) []byte
+	aead       cipher.AEAD
+	keyHMAC    []byte
+	decoder    func(key, value []byte) ([]byte, error)
+
+	bloomBitsPerKey int
+	header          bool
+	recordIndex     bool
+	grouping        Grouping
+	alignment       uint32
+	maxSize         int64
+	maxKeySize      int64
+	maxValueSize    int64
+	maxRecordSize   int64
+
+	byteOrder       binary.ByteOrder
+	detectByteOrder bool
+
+	concurrentSync bool
+	syncPolicy     SyncPolicy
+	expiry         bool
+	showExpired    bool
+	locking        bool
+
+	preallocatedEntries int64
+	hashedKeyBits       int
+
+	probeWindow int
+	filePool    int
+	metrics     MetricsSink
+	trace       func(TraceEvent)
+	bufferPool  BufferPool
+
+	slowReadThreshold time.Duration
+	slowRead          func(SlowRead)
+}
+
+func buildOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithPinnedTables causes the hash tables to be read into memory when the
+// database is opened, so that every Get only needs to read the record itself.
+// The hash tables use 16 bytes per record; use TablesSize to check the cost
+// before opening a database this way.
+func WithPinnedTables() Option {
+	return func(o *options) {
+		o.pinTables = true
+	}
+}
+
+// DefaultLoadFactor is the fraction of hash table slots that are filled by
+// default. It matches djb's cdbmake, which makes each table twice as long as
+// the number of records in it.
+const DefaultLoadFactor = 0.5
+
+// WithLoadFactor sets the fraction of hash table slots that a Writer fills,
+// which must be greater than 0 and at most 1; other values are ignored. Lower
+// load factors make for shorter probe chains, and so faster lookups, at the
+// cost of larger hash tables: each record takes 8/f bytes of table space.
+func WithLoadFactor(f float64) Option {
+	return func(o *options) {
+		if f > 0 && f <= 1 {
+			o.loadFactor = f
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package cdb_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReader counts calls to ReadAt.
+type countingReader struct {
+	io.ReaderAt
+	reads int64
+}
+
+func (cr *countingReader) ReadAt(b []byte, off int64) (int, error) {
+	atomic.AddInt64(&cr.reads, 1)
+	return cr.ReaderAt.ReadAt(b, off)
+}
+
+func TestPinnedTables(t *testing.T) {
+	f, err := os.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader := &countingReader{ReaderAt: f}
+	db, err := cdb.New(reader, nil, cdb.WithPinnedTables())
+	require.NoError(t, err)
+	assert.EqualValues(t, 16*(len(expectedRecords)-1), db.TablesSize())
+
+	for _, record := range expectedRecords {
+		msg := "while fetching " + string(record[0])
+
+		reader.reads = 0
+		value, err := db.Get(record[0])
+		require.NoError(t, err, msg)
+		assert.Equal(t, string(record[1]), string(value), msg)
+
+		// Only the record itself (header, then key and value) should be read.
+		// 'snush' is skipped, since it collides with 'playwright'.
+		if record[1] != nil && string(record[0]) != "snush" {
+			assert.EqualValues(t, 2, reader.reads, msg)
+		}
+	}
+
+	stats, err := db.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, len(expectedRecords)-1, stats.Records)
+}
+
+func TestLoadFactor(t *testing.T) {
+	for _, f := range []float64{0.25, cdb.DefaultLoadFactor, 1} {
+		file, err := ioutil.TempFile("", "test-cdb")
+		require.NoError(t, err)
+		defer os.Remove(file.Name())
+
+		writer, err := cdb.NewWriter(file, nil, cdb.WithLoadFactor(f))
+		require.NoError(t, err)
+
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("x")))
+		}
+
+		db, err := writer.Freeze()
+		require.NoError(t, err)
+
+		stats, err := db.Stats()
+		require.NoError(t, err)
+		assert.Equal(t, 1000, stats.Records)
+
+		slots := 0
+		for _, ts := range stats.Tables {
+			slots += ts.Slots
+			assert.True(t, ts.Slots >= ts.Entries)
+		}
+
+		assert.InDelta(t, 1000/f, slots, 256, "load factor %v", f)
+
+		for i := 0; i < 1000; i++ {
+			value, err := db.Get([]byte(strconv.Itoa(i)))
+			require.NoError(t, err)
+			assert.Equal(t, "x", string(value))
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package cdb
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Overlay layers in-memory changes over a database, without modifying it, so
+// that it can be patched between full rebuilds. Lookups and iteration see the
+// union of the two: keys that have been put in the overlay replace any
+// records for them in the base database, and keys deleted from it are
+// hidden. Flatten writes the result out as a new database.
+//
+// An Overlay is safe for concurrent use. Keys are normalized with the base
+// database's WithKeyNormalizer, if it has one.
+type Overlay struct {
+	base *CDB
+
+	mu sync.RWMutex
+	// changes holds the value for each key that's been put, or nil for each
+	// that's been deleted.
+	changes map[string][]byte
+}
+
+// NewOverlay creates an empty overlay over base.
+func NewOverlay(base *CDB) *Overlay {
+	return &Overlay{base: base, changes: make(map[string][]byte)}
+}
+
+// Base returns the underlying database.
+func (o *Overlay) Base() *CDB {
+	return o.base
+}
+
+// Put sets the value for a key, replacing every record for it in the base
+// database, and any earlier value in the overlay. The key and value are
+// copied.
+func (o *Overlay) Put(key, value []byte) {
+	key = o.base.opts.normalizeKey(key)
+	value = append(make([]byte, 0, len(value)), value...)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.changes[string(key)] = value
+}
+
+// Delete hides every record for a key, in the base database and in the
+// overlay.
+func (o *Overlay) Delete(key []byte) {
+	key = o.base.opts.normalizeKey(key)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.changes[string(key)] = nil
+}
+
+// Get returns the value for a key, from the overlay if it's been put or
+// deleted there, and otherwise from the base database. As with CDB.Get, it
+// returns nil if the key isn't found.
+func (o *Overlay) Get(key []byte) ([]byte, error) {
+	key = o.base.opts.normalizeKey(key)
+
+	o.mu.RLock()
+	value, changed := o.changes[string(key)]
+	o.mu.RUnlock()
+	if changed {
+		if value == nil {
+			return nil, nil
+		}
+
+		return append([]byte(nil), value...), nil
+	}
+
+	return o.base.get(o.base.hash(key), key)
+}
+
+// Each calls fn for each record in the overlay: first the records in the base
+// database, in the order they're stored, except those for keys that have been
+// put or deleted in the overlay, then the keys that have been put, sorted.
+// Changes made while Each is running aren't visible to it. If fn returns an
+// error, Each stops and returns it.
+func (o *Overlay) Each(fn func(key, value []byte) error) error {
+	return o.each(func(key, value []byte, expires time.Time) error {
+		return fn(key, value)
+	})
+}
+
+// each is Each, but also passes fn the expiration time of each record from the
+// base database. Records put in the overlay never expire.
+func (o *Overlay) each(fn func(key, value []byte, expires time.Time) error) error {
+	changes := o.snapshot()
+	iter := o.base.Iter()
+	for iter.Next() {
+		if _, changed := changes[string(iter.Key())]; changed {
+			continue
+		}
+
+		err := fn(iter.Key(), iter.Value(), iter.expires)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(changes))
+	for key, value := range changes {
+		if value != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		err := fn([]byte(key), changes[key], time.Time{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flatten writes every record in the overlay to w, in the order Each visits
+// them. Keys are written as the base database stores them, after any
+// normalization, without normalizing them again, so w should use the same
+// key options as the base database. Records that have expired in the base
+// database are skipped, and the rest keep their expiration times, if w uses
+// WithExpiry too. Flatten does not finalize w; the caller must call Close or
+// Freeze once it returns.
+func (o *Overlay) Flatten(w *Writer) error {
+	return o.each(w.putStored)
+}
+
+// snapshot returns a copy of the changes. The values are never modified, so
+// they don't need to be copied.
+func (o *Overlay) snapshot() map[string][]byte {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	changes := make(map[string][]byte, len(o.changes))
+	for key, value := range o.changes {
+		changes[key] = value
+	}
+
+	return changes
+}
@@ -0,0 +1,82 @@
+package cdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlay(t *testing.T) {
+	writer := cdb.NewMem()
+	require.NoError(t, writer.Put([]byte("a"), []byte("1")))
+	require.NoError(t, writer.Put([]byte("b"), []byte("2")))
+	require.NoError(t, writer.Put([]byte("b"), []byte("3")))
+	require.NoError(t, writer.Put([]byte("c"), []byte("4")))
+	base, err := writer.Freeze()
+	require.NoError(t, err)
+
+	overlay := cdb.NewOverlay(base)
+	overlay.Put([]byte("b"), []byte("5"))
+	overlay.Delete([]byte("c"))
+	overlay.Put([]byte("e"), []byte("7"))
+	overlay.Put([]byte("d"), []byte("6"))
+	overlay.Delete([]byte("missing"))
+
+	expected := map[string]string{"a": "1", "b": "5", "c": "", "d": "6", "e": "7", "missing": ""}
+	for key, value := range expected {
+		got, err := overlay.Get([]byte(key))
+		require.NoError(t, err)
+		if value == "" {
+			assert.Nil(t, got, key)
+		} else {
+			assert.Equal(t, value, string(got), key)
+		}
+	}
+
+	// The base database is unchanged.
+	value, err := base.Get([]byte("c"))
+	require.NoError(t, err)
+	assert.Equal(t, "4", string(value))
+
+	var records [][2]string
+	err = overlay.Each(func(key, value []byte) error {
+		records = append(records, [2]string{string(key), string(value)})
+		return nil
+	})
+	require.NoError(t, err)
+
+	expectedRecords := [][2]string{{"a", "1"}, {"b", "5"}, {"d", "6"}, {"e", "7"}}
+	assert.Equal(t, expectedRecords, records)
+
+	writer = cdb.NewMem()
+	require.NoError(t, overlay.Flatten(writer))
+	flat, err := writer.Freeze()
+	require.NoError(t, err)
+
+	records = nil
+	iter := flat.Iter()
+	for iter.Next() {
+		records = append(records, [2]string{string(iter.Key()), string(iter.Value())})
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, expectedRecords, records)
+}
+
+func TestOverlayFlattenKeepsExpiry(t *testing.T) {
+	base, expires := newExpiringDB(t)
+	overlay := cdb.NewOverlay(base)
+	overlay.Put([]byte("added"), []byte("3"))
+
+	w := cdb.NewMem(cdb.WithExpiry())
+	require.NoError(t, overlay.Flatten(w))
+
+	db, err := w.Freeze()
+	require.NoError(t, err)
+	assertExpiry(t, db, "forever", time.Time{})
+	assertExpiry(t, db, "expiring", expires)
+	assertExpiry(t, db, "added", time.Time{})
+}
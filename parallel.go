@@ -0,0 +1,178 @@
+package cdb
+
+import (
+	"context"
+	"sync"
+)
+
+// GetMany looks up each of keys using a pool of concurrency goroutines, and
+// calls fn with each key, its value, and whether it was found. Since the
+// lookups happen concurrently, fn may be called from multiple goroutines at
+// once, and the keys are not visited in any particular order.
+//
+// If any lookup fails, or fn returns an error, GetMany stops issuing new
+// lookups and returns the first error once the in-flight lookups have
+// finished. If ctx is cancelled, it returns ctx.Err() the same way. This makes
+// GetMany a good fit for use inside an errgroup.
+func (cdb *CDB) GetMany(ctx context.Context, keys [][]byte, concurrency int,
+	fn func(key, value []byte, found bool) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	work := make(chan []byte)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				value, err := cdb.Get(key)
+				if err == nil {
+					err = fn(key, value, value != nil)
+				}
+
+				if err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, key := range keys {
+		select {
+		case work <- key:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
+
+// EachParallel calls fn for each record in the database, scanning n
+// non-overlapping regions of the data section concurrently; it's useful for
+// full scans where fn, rather than I/O, is the bottleneck. fn may be called
+// from up to n goroutines at once, and while the records in each region are
+// visited in order, the regions are interleaved.
+//
+// The regions are aligned on record boundaries found in the hash tables, so
+// EachParallel reads the tables before it starts. If fn returns an error,
+// EachParallel stops every scan and returns the first error once they've
+// finished.
+func (cdb *CDB) EachParallel(n int, fn func(key, value []byte) error) error {
+	if n < 1 {
+		n = 1
+	}
+
+	boundaries, err := cdb.splitData(n)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	done := make(chan struct{})
+	for i := 0; i+1 < len(boundaries); i++ {
+		wg.Add(1)
+		go func(start, end uint32) {
+			defer wg.Done()
+			iter := &Iterator{db: cdb, pos: start, endPos: end}
+			for iter.Next() {
+				err := fn(iter.Key(), iter.Value())
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(done)
+					})
+					return
+				}
+
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+
+			if iter.Err() != nil {
+				once.Do(func() {
+					firstErr = iter.Err()
+					close(done)
+				})
+			}
+		}(boundaries[i], boundaries[i+1])
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// splitData divides the data section into at most n regions of roughly equal
+// size, and returns the offsets of the boundaries between them, including the
+// start and end of the data section. Each boundary is the offset of a record,
+// as recorded in the hash tables.
+func (cdb *CDB) splitData(n int) ([]uint32, error) {
+	start, end := cdb.firstRecord(), cdb.index[0].offset
+	size := uint64(end - start)
+
+	// For each of the n equal divisions of the data section, find the first
+	// record that starts inside it.
+	first := make([]uint32, n)
+	for i := range first {
+		first[i] = end
+	}
+
+	err := cdb.acquire(opScan)
+	if err != nil {
+		return nil, err
+	}
+	defer cdb.release(opScan)
+
+	for _, table := range cdb.index {
+		err := cdb.scanTable(table, func(slot, hash, offset uint32) {
+			if offset < start || offset >= end {
+				return
+			}
+
+			i := uint64(offset-start) * uint64(n) / size
+			if offset < first[i] {
+				first[i] = offset
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	boundaries := []uint32{start}
+	for _, offset := range first[1:] {
+		if offset > boundaries[len(boundaries)-1] && offset < end {
+			boundaries = append(boundaries, offset)
+		}
+	}
+
+	return append(boundaries, end), nil
+}
@@ -0,0 +1,133 @@
+package cdb_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMany(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	keys := make([][]byte, 0, len(expectedRecords))
+	expected := make(map[string]string)
+	for _, record := range expectedRecords {
+		keys = append(keys, record[0])
+		if record[1] != nil {
+			expected[string(record[0])] = string(record[1])
+		}
+	}
+
+	var mu sync.Mutex
+	found := make(map[string]string)
+	missing := 0
+	err = db.GetMany(context.Background(), keys, 4, func(key, value []byte, ok bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if ok {
+			found[string(key)] = string(value)
+		} else {
+			missing++
+		}
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, expected, found)
+	assert.Equal(t, 1, missing)
+}
+
+func TestGetManyError(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	keys := make([][]byte, 0, 1000)
+	for i := 0; i < cap(keys); i++ {
+		keys = append(keys, expectedRecords[i%len(expectedRecords)][0])
+	}
+
+	expectedErr := errors.New("stop")
+	err = db.GetMany(context.Background(), keys, 4, func(key, value []byte, ok bool) error {
+		if string(key) == "crystal" {
+			return expectedErr
+		}
+
+		return nil
+	})
+
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestGetManyCancelled(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	keys := [][]byte{[]byte("foo"), []byte("bar")}
+	err = db.GetMany(ctx, keys, 2, func(key, value []byte, ok bool) error {
+		return nil
+	})
+
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestEachParallel(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte(strconv.Itoa(i*2))))
+	}
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	for _, n := range []int{0, 1, 3, 8, 5000} {
+		var mu sync.Mutex
+		seen := make(map[string]int)
+		err := db.EachParallel(n, func(key, value []byte) error {
+			i, _ := strconv.Atoi(string(key))
+			assert.Equal(t, strconv.Itoa(i*2), string(value))
+
+			mu.Lock()
+			seen[string(key)]++
+			mu.Unlock()
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1000, len(seen), "n = %d", n)
+		for key, count := range seen {
+			assert.Equal(t, 1, count, "key %s, n = %d", key, n)
+		}
+	}
+}
+
+func TestEachParallelError(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	errBoom := errors.New("boom")
+	err = db.EachParallel(4, func(key, value []byte) error {
+		return errBoom
+	})
+
+	assert.Equal(t, errBoom, err)
+}
@@ -0,0 +1,293 @@
+package cdb
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ParallelWriter wraps a Writer, so that any number of goroutines can add
+// records at the same time. Records are partitioned by the hash table they
+// belong to, and each of the 256 partitions is buffered in a temporary file of
+// its own, behind its own lock; goroutines only contend when they write to the
+// same partition. When the ParallelWriter is finalized, the partitions are
+// copied into the Writer one after another, and their offsets fixed up, before
+// the Writer itself is finalized.
+//
+// Because the records are grouped by partition, iterating over the finished
+// database doesn't visit them in the order they were added, although records
+// with the same key keep their relative order if they're added from the same
+// goroutine.
+//
+// Keys and values are normalized, validated, and encoded on the calling
+// goroutine, so any functions passed as options to the Writer, such as with
+// WithValidator, WithKeyNormalizer, or WithCompression, must be safe for
+// concurrent use. Progress, if reported, is only reported once the partitions
+// are being copied.
+type ParallelWriter struct {
+	writer     *Writer
+	tempDir    string
+	partitions [256]partition
+
+	// mu is held for reading by Put, and for writing while finalizing, so
+	// that nothing is added to a partition after it's been copied.
+	mu     sync.RWMutex
+	closed bool
+
+	// size and records count the data across all the partitions, so that
+	// limits on the size of the finished database can be checked up front.
+	size    int64
+	records int64
+}
+
+// A partition holds buffered records for one of the hash tables. The entry
+// offsets are relative to the start of the partition's file.
+type partition struct {
+	mu      sync.Mutex
+	file    *os.File
+	buf     *bufio.Writer
+	entries []entry
+	size    int64
+	err     error
+}
+
+// NewParallelWriter wraps w for concurrent use, with temporary files in
+// tempDir. If tempDir is empty, the default directory for temporary files is
+// used. The Writer must not be used directly until the ParallelWriter is
+// finalized. Records already added to it are kept, ahead of any added with
+// the ParallelWriter.
+func NewParallelWriter(w *Writer, tempDir string) *ParallelWriter {
+	return &ParallelWriter{writer: w, tempDir: tempDir}
+}
+
+// Put adds a key/value pair to the database. It's safe to call from multiple
+// goroutines at once. If the amount of data written would exceed the limit,
+// Put returns ErrTooMuchData.
+func (pw *ParallelWriter) Put(key, value []byte) error {
+	key = pw.writer.opts.applyNormalizer(key)
+	stored := pw.writer.opts.storedKey(key)
+	return pw.put(pw.writer.hash(stored), key, stored, value, time.Time{})
+}
+
+// PutHashed is like Put, but uses a hash for the key computed in advance, as
+// with Writer.PutHashed.
+func (pw *ParallelWriter) PutHashed(hash uint32, key, value []byte) error {
+	key = pw.writer.opts.applyNormalizer(key)
+	return pw.put(hash, key, pw.writer.opts.storedKey(key), value, time.Time{})
+}
+
+// PutWithExpiry is like Put, but the record expires at the given time, as
+// with Writer.PutWithExpiry.
+func (pw *ParallelWriter) PutWithExpiry(key, value []byte, expires time.Time) error {
+	key = pw.writer.opts.applyNormalizer(key)
+	stored := pw.writer.opts.storedKey(key)
+	return pw.put(pw.writer.hash(stored), key, stored, value, expires)
+}
+
+// put adds a record under the stored key, calling validators with key, as
+// Writer.putHashed does.
+func (pw *ParallelWriter) put(hash uint32, key, stored, value []byte, expires time.Time) error {
+	w := pw.writer
+	err := w.opts.checkLimits(int64(len(stored)), int64(len(value)))
+	if err != nil {
+		return err
+	}
+
+	err = w.validate(key, value)
+	if err != nil {
+		return err
+	}
+
+	key = stored
+
+	value, err = w.encodeValue(key, value, expires)
+	if err != nil {
+		return err
+	}
+
+	pw.mu.RLock()
+	defer pw.mu.RUnlock()
+	if pw.closed {
+		return ErrWriterClosed
+	}
+
+	// With WithAlignment, the padding isn't known until the partition is
+	// locked, so the most it could be is reserved up front.
+	entrySize := int64(8 + len(key) + len(value))
+	reserved := entrySize
+	if w.opts.alignment > 0 {
+		reserved += int64(w.opts.alignment) - 1
+	}
+
+	err = pw.reserve(reserved)
+	if err != nil {
+		return err
+	}
+
+	p := &pw.partitions[hash&0xff]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pad := w.opts.align(p.size) - p.size
+	err = p.write(pw.tempDir, w, pad, key, value)
+	if err != nil {
+		pw.unreserve(reserved)
+		return err
+	}
+
+	p.entries = append(p.entries, entry{hash: hash, offset: uint32(p.size + pad)})
+	p.size += pad + entrySize
+	return nil
+}
+
+// reserve counts a record of entrySize bytes towards the size of the finished
+// database, and returns ErrTooMuchData if it doesn't fit.
+func (pw *ParallelWriter) reserve(entrySize int64) error {
+	w := pw.writer
+	size := atomic.AddInt64(&pw.size, entrySize)
+	records := atomic.AddInt64(&pw.records, 1)
+
+	err := w.checkFinalSize(w.bufferedOffset+size,
+		w.estimatedFooterSize+records*w.footerSizePerEntry(), w.records+records)
+	if err != nil {
+		pw.unreserve(entrySize)
+	}
+
+	return err
+}
+
+func (pw *ParallelWriter) unreserve(entrySize int64) {
+	atomic.AddInt64(&pw.size, -entrySize)
+	atomic.AddInt64(&pw.records, -1)
+}
+
+// write appends a record to the partition's file, after pad bytes of
+// padding, creating the file if necessary. Once a write fails, the file can't
+// be trusted, so every later write returns the same error.
+func (p *partition) write(tempDir string, w *Writer, pad int64, key, value []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	if p.file == nil {
+		f, err := ioutil.TempFile(tempDir, "cdb-parallel")
+		if err != nil {
+			return err
+		}
+
+		p.file = f
+		p.buf = bufio.NewWriterSize(f, 65536)
+	}
+
+	_, err := p.buf.Write(make([]byte, pad))
+	if err == nil {
+		err = writeTuple(p.buf, w.opts.order(), uint32(len(key)), uint32(len(value)))
+	}
+
+	if err == nil {
+		_, err = p.buf.Write(key)
+	}
+
+	if err == nil {
+		_, err = p.buf.Write(value)
+	}
+
+	p.err = err
+	return err
+}
+
+// Close copies the partitions into the Writer, then closes it, which
+// finalizes the database. The temporary files are removed whether or not it
+// succeeds.
+func (pw *ParallelWriter) Close() error {
+	err := pw.merge()
+	if err != nil {
+		return err
+	}
+
+	return pw.writer.Close()
+}
+
+// Freeze copies the partitions into the Writer, then freezes it, which
+// finalizes the database and opens it for reads. The temporary files are
+// removed whether or not it succeeds.
+func (pw *ParallelWriter) Freeze() (*CDB, error) {
+	err := pw.merge()
+	if err != nil {
+		return nil, err
+	}
+
+	return pw.writer.Freeze()
+}
+
+// merge copies each partition, in order, to the end of the Writer's data
+// section, and adds its entries to the corresponding hash table.
+func (pw *ParallelWriter) merge() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if pw.closed {
+		return ErrWriterClosed
+	}
+
+	pw.closed = true
+	defer pw.removeTempFiles()
+
+	w := pw.writer
+	for i := range pw.partitions {
+		p := &pw.partitions[i]
+		if p.err != nil {
+			return p.err
+		} else if p.file == nil {
+			continue
+		}
+
+		err := p.buf.Flush()
+		if err != nil {
+			return err
+		}
+
+		// Partitions are padded relative to their own start, so they need
+		// to start on the boundary too.
+		err = w.writePadding()
+		if err != nil {
+			return err
+		}
+
+		_, err = p.file.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.CopyN(w.bufferedWriter, p.file, p.size)
+		if err != nil {
+			return err
+		}
+
+		base := uint32(w.bufferedOffset)
+		for _, e := range p.entries {
+			w.entries[i] = append(w.entries[i], entry{hash: e.hash, offset: base + e.offset})
+		}
+
+		w.bufferedOffset += p.size
+		w.estimatedFooterSize += int64(len(p.entries)) * w.footerSizePerEntry()
+		w.records += int64(len(p.entries))
+		w.reportProgress(PhaseWriting)
+	}
+
+	return nil
+}
+
+func (pw *ParallelWriter) removeTempFiles() {
+	for i := range pw.partitions {
+		p := &pw.partitions[i]
+		if p.file != nil {
+			p.file.Close()
+			os.Remove(p.file.Name())
+			p.file = nil
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cdb-parallel-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f, err := ioutil.TempFile("", "cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	w, err := cdb.NewWriter(f, nil, cdb.WithHeader())
+	require.NoError(t, err)
+	require.NoError(t, w.Put([]byte("before"), []byte("direct")))
+
+	pw := cdb.NewParallelWriter(w, dir)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := []byte(strconv.Itoa(g*500 + i))
+				assert.NoError(t, pw.Put(key, append([]byte("value-"), key...)))
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	db, err := pw.Freeze()
+	require.NoError(t, err)
+
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 4001, n)
+	assert.NoError(t, db.VerifyHeader())
+
+	for i := 0; i < 4000; i++ {
+		key := strconv.Itoa(i)
+		value, err := db.Get([]byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, "value-"+key, string(value))
+	}
+
+	value, err := db.Get([]byte("before"))
+	require.NoError(t, err)
+	assert.Equal(t, "direct", string(value))
+
+	// The temporary files are cleaned up, and nothing more can be added.
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Equal(t, cdb.ErrWriterClosed, pw.Put([]byte("after"), []byte("closed")))
+}
+
+func TestParallelWriterMaxSize(t *testing.T) {
+	f, err := ioutil.TempFile("", "cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	w, err := cdb.NewWriter(f, nil, cdb.WithMaxSize(8192))
+	require.NoError(t, err)
+
+	pw := cdb.NewParallelWriter(w, "")
+	value := make([]byte, 1000)
+	for i := 0; ; i++ {
+		err = pw.Put([]byte(strconv.Itoa(i)), value)
+		if err != nil {
+			break
+		}
+	}
+
+	assert.Equal(t, cdb.ErrTooMuchData, err)
+	require.NoError(t, pw.Close())
+
+	info, err := os.Stat(f.Name())
+	require.NoError(t, err)
+	assert.True(t, info.Size() <= 8192)
+}
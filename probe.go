@@ -0,0 +1,159 @@
+package cdb
+
+import (
+	"sync/atomic"
+)
+
+// AutoProbeWindow can be passed to WithProbeWindow to size the window based on
+// the probe lengths seen so far.
+const AutoProbeWindow = -1
+
+// The largest window used by AutoProbeWindow.
+const maxAutoProbeWindow = 64
+
+// WithProbeWindow sets the number of hash table slots read at once when a
+// lookup has to probe past its first slot. By default, each slot is read
+// individually, which is ideal for local files; on high-latency readers, such
+// as those over a network, reading several slots at once saves round trips
+// for keys with long probe chains.
+//
+// If slots is AutoProbeWindow, the window is tuned automatically from the
+// lengths of recently observed probe chains.
+func WithProbeWindow(slots int) Option {
+	return func(o *options) {
+		o.probeWindow = slots
+	}
+}
+
+// probeTuning tracks a moving average of probe lengths, for AutoProbeWindow.
+type probeTuning struct {
+	// average is the average probe length, in sixteenths of a slot.
+	average uint32
+}
+
+func (pt *probeTuning) observe(length uint32) {
+	// This isn't a strictly accurate average under concurrent updates, but
+	// it doesn't need to be.
+	old := atomic.LoadUint32(&pt.average)
+	sample := length * 16
+	atomic.StoreUint32(&pt.average, old-old/8+sample/8)
+}
+
+func (pt *probeTuning) window() uint32 {
+	// Read twice the average chain length, rounded up.
+	window := (atomic.LoadUint32(&pt.average) + 7) / 8
+	if window < 2 {
+		window = 2
+	} else if window > maxAutoProbeWindow {
+		window = maxAutoProbeWindow
+	}
+
+	return window
+}
+
+// probe walks the chain of hash table slots for a hash, returning the offsets
+// of records with a matching hash.
+type probe struct {
+	cdb   *CDB
+	hash  uint32
+	table table
+	slot  uint32
+	seen  uint32
+
+	// buf holds prefetched slots, starting at bufSlot.
+	buf     []byte
+	bufSlot uint32
+
+	// If scratch is set, it's used for reading individual slots.
+	scratch []byte
+}
+
+func (cdb *CDB) newProbe(hash uint32) probe {
+	table := cdb.index[hash&0xff]
+	p := probe{cdb: cdb, hash: hash, table: table}
+	if table.length > 0 {
+		p.slot = (hash >> 8) % table.length
+	}
+
+	return p
+}
+
+// next returns the offset of the next record in the chain with a matching
+// hash. It returns false once the chain ends.
+func (p *probe) next() (uint32, bool, error) {
+	for p.seen < p.table.length {
+		slotHash, offset, err := p.readSlot()
+		if err != nil {
+			return 0, false, err
+		}
+
+		p.seen++
+		p.slot = (p.slot + 1) % p.table.length
+
+		// An empty slot means the key doesn't exist. Slots are empty if their
+		// offset is zero, since no record can start inside the index; a key
+		// can legitimately hash to zero.
+		if offset == 0 {
+			break
+		} else if slotHash == p.hash {
+			return offset, true, nil
+		}
+	}
+
+	p.finish()
+	return 0, false, nil
+}
+
+// finish records the length of the probe, once it's over.
+func (p *probe) finish() {
+	if p.cdb.tuning != nil && p.seen > 0 {
+		p.cdb.tuning.observe(p.seen)
+	}
+}
+
+func (p *probe) readSlot() (uint32, uint32, error) {
+	window := p.window()
+	if window <= 1 || p.seen == 0 {
+		return p.cdb.readSlot(p.table.offset+(8*p.slot), p.scratch)
+	}
+
+	// Refill the buffer if the current slot isn't in it. We take care not to
+	// read past the end of the table, where the window would wrap around.
+	if p.buf == nil || p.slot < p.bufSlot || p.slot >= p.bufSlot+uint32(len(p.buf)/8) {
+		n := p.table.length - p.slot
+		if n > window {
+			n = window
+		}
+
+		if remaining := p.table.length - p.seen; n > remaining {
+			n = remaining
+		}
+
+		if cap(p.buf) < int(8*n) {
+			p.buf = make([]byte, 8*window)
+		}
+
+		p.buf = p.buf[:8*n]
+		p.bufSlot = p.slot
+		err := p.cdb.readTables(p.buf, p.table.offset+(8*p.slot))
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	slot := p.buf[8*(p.slot-p.bufSlot):]
+	order := p.cdb.opts.order()
+	return order.Uint32(slot), order.Uint32(slot[4:]), nil
+}
+
+func (p *probe) window() uint32 {
+	if p.cdb.tables != nil {
+		return 1
+	} else if p.cdb.tuning != nil {
+		return p.cdb.tuning.window()
+	} else if p.cdb.opts.probeWindow > 1 {
+		return uint32(p.cdb.opts.probeWindow)
+	}
+
+	return 1
+}
@@ -0,0 +1,65 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collidingHash puts every key in the same table, with lots of collisions.
+func collidingHash(data []byte) uint32 {
+	return (fnvHash(data)%16)<<8 | 1
+}
+
+func testProbeWindow(t *testing.T, opts ...cdb.Option) int64 {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, collidingHash)
+	require.NoError(t, err)
+
+	for i := 0; i < 200; i++ {
+		key := []byte(strconv.Itoa(i))
+		require.NoError(t, writer.Put(key, key))
+	}
+
+	require.NoError(t, writer.Close())
+
+	f, err = os.Open(f.Name())
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader := &countingReader{ReaderAt: f}
+	db, err := cdb.New(reader, collidingHash, opts...)
+	require.NoError(t, err)
+
+	reader.reads = 0
+	for i := 0; i < 400; i++ {
+		key := []byte(strconv.Itoa(i))
+		value, err := db.Get(key)
+		require.NoError(t, err)
+
+		if i < 200 {
+			assert.Equal(t, string(key), string(value))
+		} else {
+			assert.Nil(t, value)
+		}
+	}
+
+	return reader.reads
+}
+
+func TestProbeWindow(t *testing.T) {
+	unbuffered := testProbeWindow(t)
+	windowed := testProbeWindow(t, cdb.WithProbeWindow(16))
+	auto := testProbeWindow(t, cdb.WithProbeWindow(cdb.AutoProbeWindow))
+
+	assert.True(t, windowed < unbuffered/2, "%d reads with a window, %d without", windowed, unbuffered)
+	assert.True(t, auto < unbuffered/2, "%d reads with an automatic window, %d without", auto, unbuffered)
+}
@@ -0,0 +1,72 @@
+package cdb
+
+// Phase is a stage in building a database, for progress reporting.
+type Phase int
+
+const (
+	// PhaseWriting means records are being added with Put.
+	PhaseWriting Phase = iota
+	// PhaseCompacting means deleted records are being removed from the data
+	// section, during finalization.
+	PhaseCompacting
+	// PhaseGrouping means the records are being reordered, as set by
+	// WithGrouping, during finalization.
+	PhaseGrouping
+	// PhaseTables means the hash tables are being written, during
+	// finalization.
+	PhaseTables
+	// PhaseIndex means the index at the head of the file is being written.
+	PhaseIndex
+	// PhaseDone means the database has been finalized.
+	PhaseDone
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseWriting:
+		return "writing"
+	case PhaseCompacting:
+		return "compacting"
+	case PhaseGrouping:
+		return "grouping"
+	case PhaseTables:
+		return "tables"
+	case PhaseIndex:
+		return "index"
+	case PhaseDone:
+		return "done"
+	default:
+		return "unknown"
+	}
+}
+
+// Progress describes how far along a Writer is.
+type Progress struct {
+	// Phase is the current stage of the build.
+	Phase Phase
+	// Records is the number of records written so far.
+	Records int64
+	// Bytes is the number of bytes written so far, including the space
+	// reserved for the index.
+	Bytes int64
+}
+
+// WithProgress registers fn to be called as a Writer makes progress: after
+// each record is written, and at the start of each phase of finalization. The
+// hook is called synchronously, so it should be cheap; for example, updating a
+// few metrics is fine.
+func WithProgress(fn func(Progress)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+func (cdb *Writer) reportProgress(phase Phase) {
+	if cdb.opts.progress != nil {
+		cdb.opts.progress(Progress{
+			Phase:   phase,
+			Records: cdb.records,
+			Bytes:   cdb.bufferedOffset,
+		})
+	}
+}
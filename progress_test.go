@@ -0,0 +1,46 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	var reports []cdb.Progress
+	writer, err := cdb.NewWriter(f, nil, cdb.WithProgress(func(p cdb.Progress) {
+		reports = append(reports, p)
+	}))
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value")))
+	}
+
+	require.NoError(t, writer.Delete([]byte("3")))
+	require.NoError(t, writer.Close())
+
+	require.Len(t, reports, 14)
+	for i, p := range reports[:10] {
+		assert.Equal(t, cdb.PhaseWriting, p.Phase)
+		assert.EqualValues(t, i+1, p.Records)
+		assert.EqualValues(t, cdb.DataOffset+(i+1)*14, p.Bytes)
+	}
+
+	phases := []cdb.Phase{cdb.PhaseCompacting, cdb.PhaseTables, cdb.PhaseIndex, cdb.PhaseDone}
+	for i, p := range reports[10:] {
+		assert.Equal(t, phases[i], p.Phase, p.Phase.String())
+		assert.EqualValues(t, 9, p.Records)
+	}
+
+	assert.EqualValues(t, cdb.DataOffset+9*14+9*16, reports[13].Bytes)
+}
@@ -0,0 +1,59 @@
+package cdb
+
+import (
+	"io"
+	"sync"
+)
+
+// NewFromReadSeeker opens a database read from rs, for storage that only
+// supports reading and seeking, such as some remote filesystem clients. Since
+// every read involves a seek, reads are serialized by a mutex, so the
+// returned CDB is safe for concurrent use, but won't read from rs in parallel;
+// prefer New if the storage supports io.ReaderAt.
+//
+// If rs is an io.Closer, Close closes it. The hash function and options are
+// the same as for New.
+func NewFromReadSeeker(rs io.ReadSeeker, hash func([]byte) uint32, opts ...Option) (*CDB, error) {
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(&seekReaderAt{rs: rs, size: size}, hash, opts...)
+}
+
+// seekReaderAt adapts an io.ReadSeeker to io.ReaderAt.
+type seekReaderAt struct {
+	mu   sync.Mutex
+	rs   io.ReadSeeker
+	size int64
+}
+
+func (sr *seekReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	_, err := sr.rs.Seek(off, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(sr.rs, b)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+func (sr *seekReaderAt) Size() int64 {
+	return sr.size
+}
+
+func (sr *seekReaderAt) Close() error {
+	if closer, ok := sr.rs.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package cdb_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readSeeker hides everything but Read and Seek.
+type readSeeker struct {
+	io.ReadSeeker
+}
+
+func TestNewFromReadSeeker(t *testing.T) {
+	data, err := os.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	db, err := cdb.NewFromReadSeeker(readSeeker{bytes.NewReader(data)}, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, record := range expectedRecords {
+				value, err := db.Get(record[0])
+				assert.NoError(t, err)
+				assert.Equal(t, string(record[1]), string(value))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	n := 0
+	iter := db.Iter()
+	for iter.Next() {
+		n++
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, len(expectedRecords)-1, n)
+}
+
+func TestNewFromReadSeekerTruncated(t *testing.T) {
+	data, err := os.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	_, err = cdb.NewFromReadSeeker(readSeeker{bytes.NewReader(data[:len(data)-8])}, nil)
+	assert.True(t, errors.Is(err, cdb.ErrCorrupt))
+}
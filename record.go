@@ -0,0 +1,261 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrOffsetOutOfRange is returned by GetAt for an offset outside the data
+// section.
+var ErrOffsetOutOfRange = errors.New("offset is outside the data section")
+
+// DataOffset is the offset of the first record in a database, immediately
+// after the index. The records run from DataOffset to DataOffset plus
+// CDB.DataSize, and each record is immediately followed by the next one,
+// unless the database was written WithAlignment, in which case there may be
+// padding before each record.
+const DataOffset = indexSize
+
+// RecordReader reads individual records from a database, given their offsets.
+// It is intended for tooling that needs to work with the raw layout of a
+// file, such as scrubbers or patchers; to look up keys, use CDB.
+type RecordReader struct {
+	reader io.ReaderAt
+	order  binary.ByteOrder
+}
+
+// Record describes a single record in a database. Only the record header has
+// been read; the key and value are read on demand.
+//
+// For records read through a CDB, Value undoes any encoding applied to the
+// value, such as compression. ValueLength and ValueReader always refer to the
+// value as it's stored in the file.
+type Record struct {
+	reader      io.ReaderAt
+	decode      func(key, value []byte) ([]byte, error)
+	offset      uint32
+	keyLength   uint32
+	valueLength uint32
+
+	// end is the offset the record must end by: the end of the data section
+	// for records read through a CDB, or the size of the file, if it's known,
+	// for records read with a RecordReader. Zero means there's no bound.
+	end int64
+
+	// next is the offset of the following record, if it's been worked out
+	// from the database's alignment.
+	next uint32
+}
+
+// NewRecordReader creates a RecordReader for the database read from r. It
+// doesn't read or validate the index, so it can be used on files with a
+// corrupt or missing index. Record headers are read in the byte order set
+// with WithByteOrder, or little-endian by default; other options are ignored.
+func NewRecordReader(r io.ReaderAt, opts ...Option) *RecordReader {
+	o := buildOptions(opts)
+	return &RecordReader{reader: r, order: o.order()}
+}
+
+// ReadRecord reads the header of the record at offset. The header isn't
+// checked against the size of the file, so that the lengths of a corrupt
+// record can still be inspected; Key and Value return a *CorruptError instead
+// if the record runs past the end of the file.
+func (rr *RecordReader) ReadRecord(offset uint32) (Record, error) {
+	keyLength, valueLength, err := readTuple(rr.reader, rr.order, offset)
+	if err != nil {
+		return Record{}, err
+	}
+
+	rec := Record{
+		reader:      rr.reader,
+		offset:      offset,
+		keyLength:   keyLength,
+		valueLength: valueLength,
+	}
+
+	if size, ok := readerSize(rr.reader); ok {
+		rec.end = size
+	}
+
+	return rec, nil
+}
+
+// Offset returns the offset of the record in the file.
+func (rec Record) Offset() uint32 {
+	return rec.offset
+}
+
+// KeyLength returns the length of the record's key.
+func (rec Record) KeyLength() uint32 {
+	return rec.keyLength
+}
+
+// ValueLength returns the length of the record's value.
+func (rec Record) ValueLength() uint32 {
+	return rec.valueLength
+}
+
+// Len returns the total length of the record, including the header.
+func (rec Record) Len() uint32 {
+	return 8 + rec.keyLength + rec.valueLength
+}
+
+// NextOffset returns the offset where the next record (or the first hash
+// table, if this is the last one) starts. That's immediately after the
+// record, unless the database was written WithAlignment.
+func (rec Record) NextOffset() uint32 {
+	if rec.next != 0 {
+		return rec.next
+	}
+
+	return rec.offset + rec.Len()
+}
+
+// check returns a *CorruptError if the record runs past its end, so that a
+// corrupt header can't cause a huge allocation.
+func (rec Record) check() error {
+	length := 8 + int64(rec.keyLength) + int64(rec.valueLength)
+	if rec.end > 0 && int64(rec.offset)+length > rec.end {
+		return corruptAt(ErrCorruptRecord, int64(rec.offset))
+	}
+
+	return nil
+}
+
+// KeyReader returns a reader over the record's key.
+func (rec Record) KeyReader() *io.SectionReader {
+	return io.NewSectionReader(rec.reader, int64(rec.offset+8), int64(rec.keyLength))
+}
+
+// ValueReader returns a reader over the record's value.
+func (rec Record) ValueReader() *io.SectionReader {
+	return io.NewSectionReader(rec.reader, int64(rec.offset+8+rec.keyLength), int64(rec.valueLength))
+}
+
+// Key reads the record's key.
+func (rec Record) Key() ([]byte, error) {
+	err := rec.check()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, rec.keyLength)
+	_, err = rec.reader.ReadAt(buf, int64(rec.offset+8))
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// Value reads the record's value.
+func (rec Record) Value() ([]byte, error) {
+	err := rec.check()
+	if err != nil {
+		return nil, err
+	}
+
+	if rec.decode == nil {
+		buf := make([]byte, rec.valueLength)
+		_, err := rec.reader.ReadAt(buf, int64(rec.offset+8+rec.keyLength))
+		if err != nil {
+			return nil, err
+		}
+
+		return buf, nil
+	}
+
+	// Decoding may need the key, so read it along with the value.
+	buf := make([]byte, uint64(rec.keyLength)+uint64(rec.valueLength))
+	_, err = rec.reader.ReadAt(buf, int64(rec.offset+8))
+	if err != nil {
+		return nil, err
+	}
+
+	return rec.decode(buf[:rec.keyLength], buf[rec.keyLength:])
+}
+
+// GetAt reads the header of the record at offset, which must be the offset of
+// the start of a record, such as one returned by Record.Offset. Offsets are
+// stable for the lifetime of a file, so they can be used to build secondary
+// indexes over a database. GetAt returns ErrOffsetOutOfRange if offset is
+// outside the data section, but otherwise can't check that a record actually
+// starts there.
+func (cdb *CDB) GetAt(offset uint32) (Record, error) {
+	err := cdb.acquire(opGet)
+	if err != nil {
+		return Record{}, err
+	}
+	defer cdb.release(opGet)
+
+	if offset < DataOffset || offset >= cdb.index[0].offset {
+		return Record{}, ErrOffsetOutOfRange
+	}
+
+	return cdb.readRecord(offset)
+}
+
+// EachRecord calls fn for each record in the database, in the order they're
+// stored. If fn returns an error, EachRecord stops and returns it.
+func (cdb *CDB) EachRecord(fn func(rec Record) error) error {
+	err := cdb.acquire(opScan)
+	if err != nil {
+		return err
+	}
+	defer cdb.release(opScan)
+
+	end := cdb.index[0].offset
+	for offset := cdb.firstRecord(); offset < end; {
+		rec, err := cdb.readRecord(offset)
+		if err != nil {
+			return err
+		}
+
+		offset = rec.NextOffset()
+		expired, err := cdb.recordExpired(rec)
+		if err != nil {
+			return err
+		} else if expired {
+			continue
+		}
+
+		if cdb.filter != nil {
+			key, err := rec.Key()
+			if err != nil {
+				return err
+			} else if cdb.hidden(key) {
+				continue
+			}
+		}
+
+		err = fn(rec)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cdb *CDB) readRecord(offset uint32) (Record, error) {
+	keyLength, valueLength, err := cdb.readHeader(offset, nil)
+	if err != nil {
+		return Record{}, err
+	}
+
+	rec := Record{
+		reader:      cdb.reader,
+		offset:      offset,
+		keyLength:   keyLength,
+		valueLength: valueLength,
+		end:         int64(cdb.index[0].offset),
+		next:        cdb.nextRecord(offset + 8 + keyLength + valueLength),
+	}
+
+	if cdb.opts.encodesValues() {
+		rec.decode = cdb.decodeValue
+	}
+
+	return rec, nil
+}
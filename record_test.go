@@ -0,0 +1,182 @@
+package cdb_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordReader(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	f, err := os.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer f.Close()
+
+	rr := cdb.NewRecordReader(f)
+	end := uint32(cdb.DataOffset + db.DataSize())
+
+	n := 0
+	for offset := uint32(cdb.DataOffset); offset < end; n++ {
+		rec, err := rr.ReadRecord(offset)
+		require.NoError(t, err)
+		assert.Equal(t, offset, rec.Offset())
+		assert.EqualValues(t, len(expectedRecords[n][0]), rec.KeyLength())
+		assert.EqualValues(t, len(expectedRecords[n][1]), rec.ValueLength())
+		assert.Equal(t, 8+rec.KeyLength()+rec.ValueLength(), rec.Len())
+
+		key, err := rec.Key()
+		require.NoError(t, err)
+		assert.Equal(t, string(expectedRecords[n][0]), string(key))
+
+		value, err := rec.Value()
+		require.NoError(t, err)
+		assert.Equal(t, string(expectedRecords[n][1]), string(value))
+
+		streamed, err := ioutil.ReadAll(rec.ValueReader())
+		require.NoError(t, err)
+		assert.Equal(t, string(expectedRecords[n][1]), string(streamed))
+
+		streamed, err = ioutil.ReadAll(rec.KeyReader())
+		require.NoError(t, err)
+		assert.Equal(t, string(expectedRecords[n][0]), string(streamed))
+
+		offset = rec.NextOffset()
+	}
+
+	assert.Equal(t, len(expectedRecords)-1, n)
+}
+
+func TestRecordReaderBigEndian(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.cdb")
+	writer, err := cdb.Create(path, cdb.WithByteOrder(binary.BigEndian))
+	require.NoError(t, err)
+
+	var records [][][]byte
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, writer.Put(record[0], record[1]))
+			records = append(records, record)
+		}
+	}
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	defer db.Close()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	rr := cdb.NewRecordReader(f, cdb.WithByteOrder(binary.BigEndian))
+	offset := uint32(cdb.DataOffset)
+	for _, record := range records {
+		rec, err := rr.ReadRecord(offset)
+		require.NoError(t, err)
+		assert.EqualValues(t, len(record[0]), rec.KeyLength())
+		assert.EqualValues(t, len(record[1]), rec.ValueLength())
+
+		key, err := rec.Key()
+		require.NoError(t, err)
+		assert.Equal(t, string(record[0]), string(key))
+
+		offset = rec.NextOffset()
+	}
+
+	assert.Equal(t, uint32(cdb.DataOffset+db.DataSize()), offset)
+}
+
+func TestEachRecord(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	var offsets []uint32
+	err = db.EachRecord(func(rec cdb.Record) error {
+		n := len(offsets)
+		key, err := rec.Key()
+		require.NoError(t, err)
+		assert.Equal(t, string(expectedRecords[n][0]), string(key))
+
+		value, err := rec.Value()
+		require.NoError(t, err)
+		assert.Equal(t, string(expectedRecords[n][1]), string(value))
+
+		offsets = append(offsets, rec.Offset())
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, len(expectedRecords)-1, len(offsets))
+
+	// The offsets should be usable to fetch the records again.
+	for n, offset := range offsets {
+		rec, err := db.GetAt(offset)
+		require.NoError(t, err)
+
+		key, err := rec.Key()
+		require.NoError(t, err)
+		assert.Equal(t, string(expectedRecords[n][0]), string(key))
+	}
+}
+
+func TestEachRecordStops(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	n := 0
+	err = db.EachRecord(func(rec cdb.Record) error {
+		n++
+		if n == 3 {
+			return io.EOF
+		}
+
+		return nil
+	})
+
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 3, n)
+}
+
+func TestGetAtOutOfRange(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	_, err = db.GetAt(0)
+	assert.Equal(t, cdb.ErrOffsetOutOfRange, err)
+
+	_, err = db.GetAt(uint32(cdb.DataOffset + db.DataSize()))
+	assert.Equal(t, cdb.ErrOffsetOutOfRange, err)
+}
+
+func TestRecordReaderCorruptLength(t *testing.T) {
+	data, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	// Claim a value of nearly 4GB for the first record.
+	binary.LittleEndian.PutUint32(data[cdb.DataOffset+4:], 0xfffffff0)
+	path := filepath.Join(t.TempDir(), "corrupt.cdb")
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	rec, err := cdb.NewRecordReader(f).ReadRecord(cdb.DataOffset)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0xfffffff0, rec.ValueLength())
+
+	_, err = rec.Key()
+	assert.True(t, errors.Is(err, cdb.ErrCorruptRecord))
+
+	_, err = rec.Value()
+	assert.True(t, errors.Is(err, cdb.ErrCorruptRecord))
+}
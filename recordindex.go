@@ -0,0 +1,159 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrRecordOutOfRange is returned by CDB.Record for a record number outside
+// the database.
+var ErrRecordOutOfRange = errors.New("record number is out of range")
+
+// WithRecordIndex makes the offset of every record available up front, so
+// that Count, Record, and ReverseIter don't need to read the hash tables
+// first. When writing, the offsets are stored in the file, at a cost of four
+// bytes per record. When reading, they're loaded when the database is opened,
+// either from the file or, if it doesn't have them, from the hash tables.
+//
+// Without this option, the offsets are loaded the first time they're needed,
+// and kept for the lifetime of the CDB.
+func WithRecordIndex() Option {
+	return func(o *options) {
+		o.recordIndex = true
+	}
+}
+
+// recordIndex holds the offsets of the records in a database, in the order
+// they're stored. It's shared by every view of a database.
+type recordIndex struct {
+	once    sync.Once
+	offsets []uint32
+	err     error
+
+	// If the file has a record index, section is the offset of its data in
+	// the file, and length is the number of bytes.
+	section int64
+	length  uint32
+}
+
+// Count returns the number of records in the database, like Len, but only
+// reads the hash tables the first time it's called, if at all.
+func (cdb *CDB) Count() (int, error) {
+	offsets, err := cdb.recordOffsets()
+	return len(offsets), err
+}
+
+// Record reads the header of the nth record in the database, counting from
+// zero in the order the records are stored, and returns ErrRecordOutOfRange
+// if there aren't that many. Like GetAt, it sees the whole database, even
+// through a view, and includes expired records.
+func (cdb *CDB) Record(n int) (Record, error) {
+	offsets, err := cdb.recordOffsets()
+	if err != nil {
+		return Record{}, err
+	} else if n < 0 || n >= len(offsets) {
+		return Record{}, ErrRecordOutOfRange
+	}
+
+	return cdb.GetAt(offsets[n])
+}
+
+// ReverseIter creates an Iterator that visits the records in the database in
+// the opposite order to Iter, starting with the last one.
+func (cdb *CDB) ReverseIter() *Iterator {
+	offsets, err := cdb.recordOffsets()
+	iter := &Iterator{db: cdb, err: err, reverse: true, offsets: offsets, remaining: len(offsets)}
+	if len(offsets) > 0 {
+		iter.pos = offsets[len(offsets)-1]
+	}
+
+	return iter
+}
+
+// recordOffsets returns the offsets of the records, loading them if they
+// haven't been already.
+func (cdb *CDB) recordOffsets() ([]uint32, error) {
+	ri := cdb.records
+	ri.once.Do(func() {
+		err := cdb.acquire(opScan)
+		if err != nil {
+			ri.err = err
+			return
+		}
+		defer cdb.release(opScan)
+
+		if ri.length > 0 {
+			ri.offsets, ri.err = cdb.readRecordIndex()
+		} else {
+			ri.offsets, ri.err = cdb.scanRecordOffsets()
+		}
+	})
+
+	return ri.offsets, ri.err
+}
+
+// readRecordIndex reads the offsets stored in the file by WithRecordIndex,
+// and checks that they're in order and inside the data section.
+func (cdb *CDB) readRecordIndex() ([]uint32, error) {
+	ri := cdb.records
+	if ri.length%4 != 0 {
+		return nil, corruptAt(ErrCorruptIndex, ri.section)
+	}
+
+	buf := make([]byte, ri.length)
+	_, err := cdb.reader.ReadAt(buf, ri.section)
+	if err != nil {
+		return nil, readError(ErrCorruptIndex, ri.section, err)
+	}
+
+	offsets := make([]uint32, ri.length/4)
+	prev, dataEnd := uint32(0), cdb.index[0].offset
+	for i := range offsets {
+		offset := binary.LittleEndian.Uint32(buf[i*4:])
+		if offset < indexSize || offset >= dataEnd || offset <= prev {
+			return nil, corruptAt(ErrCorruptIndex, ri.section+int64(i)*4)
+		}
+
+		offsets[i] = offset
+		prev = offset
+	}
+
+	return offsets, nil
+}
+
+// scanRecordOffsets collects the offsets of the records from the hash tables.
+func (cdb *CDB) scanRecordOffsets() ([]uint32, error) {
+	var offsets []uint32
+	for _, table := range cdb.index {
+		err := cdb.scanTable(table, func(slot, hash, offset uint32) {
+			offsets = append(offsets, offset)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets, nil
+}
+
+// buildRecordIndex returns the offsets of the records written so far, in
+// order, for the extension block.
+func (cdb *Writer) buildRecordIndex() []byte {
+	offsets := make([]uint32, 0, cdb.records)
+	for _, entries := range cdb.entries {
+		for _, entry := range entries {
+			offsets = append(offsets, entry.offset)
+		}
+	}
+
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	buf := make([]byte, 4*len(offsets))
+	for i, offset := range offsets {
+		binary.LittleEndian.PutUint32(buf[i*4:], offset)
+	}
+
+	return buf
+}
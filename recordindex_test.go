@@ -0,0 +1,105 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func iterKeys(t *testing.T, iter *cdb.Iterator) []string {
+	var keys []string
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+
+	require.NoError(t, iter.Err())
+	return keys
+}
+
+func TestRecordAccess(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer db.Close()
+
+	n, err := db.Count()
+	require.NoError(t, err)
+	expected, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, expected, n)
+
+	keys := iterKeys(t, db.Iter())
+	require.Len(t, keys, n)
+	for i, expected := range keys {
+		rec, err := db.Record(i)
+		require.NoError(t, err)
+
+		key, err := rec.Key()
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(key))
+	}
+
+	_, err = db.Record(n)
+	assert.Equal(t, cdb.ErrRecordOutOfRange, err)
+	_, err = db.Record(-1)
+	assert.Equal(t, cdb.ErrRecordOutOfRange, err)
+
+	reversed := iterKeys(t, db.ReverseIter())
+	require.Len(t, reversed, n)
+	for i := range keys {
+		assert.Equal(t, keys[i], reversed[n-1-i])
+	}
+}
+
+func TestWithRecordIndex(t *testing.T) {
+	build := func(opts ...cdb.Option) string {
+		f, err := ioutil.TempFile("", "test-cdb")
+		require.NoError(t, err)
+
+		writer, err := cdb.NewWriter(f, nil, opts...)
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value")))
+		}
+
+		require.NoError(t, writer.Close())
+		return f.Name()
+	}
+
+	plain := build()
+	defer os.Remove(plain)
+	indexed := build(cdb.WithRecordIndex())
+	defer os.Remove(indexed)
+
+	// The offsets are stored in an extension block after the hash tables.
+	plainInfo, err := os.Stat(plain)
+	require.NoError(t, err)
+	indexedInfo, err := os.Stat(indexed)
+	require.NoError(t, err)
+	assert.Equal(t, plainInfo.Size()+8+8+8+4*100, indexedInfo.Size())
+
+	for _, opts := range [][]cdb.Option{nil, {cdb.WithRecordIndex()}} {
+		db, err := cdb.Open(indexed, opts...)
+		require.NoError(t, err)
+
+		n, err := db.Count()
+		require.NoError(t, err)
+		assert.Equal(t, 100, n)
+
+		rec, err := db.Record(42)
+		require.NoError(t, err)
+		key, err := rec.Key()
+		require.NoError(t, err)
+		assert.Equal(t, "42", string(key))
+
+		keys := iterKeys(t, db.ReverseIter())
+		require.Len(t, keys, 100)
+		assert.Equal(t, "99", keys[0])
+		assert.Equal(t, "0", keys[99])
+		db.Close()
+	}
+}
@@ -0,0 +1,89 @@
+package cdb
+
+import (
+	"io"
+	"math"
+)
+
+// Recover salvages the records from a damaged database read from r, such as
+// one whose Writer was never finalized, and writes them to a new database on
+// w. It returns the number of records recovered.
+//
+// If the index in r is intact, the records it describes are copied in order;
+// otherwise, Recover ignores the index and hash tables entirely, and scans
+// the data section from the start until it reaches a record that doesn't fit
+// in the file. In that case, a few spurious records may be recovered from the
+// start of any hash tables that were written, so the result should be checked
+// before it's used. Padding from WithAlignment is skipped if the index is
+// intact, since the alignment is stored after the hash tables.
+//
+// The options are used to read r: WithByteOrder or WithDetectedByteOrder for
+// files that aren't little-endian, and WithAlignment for aligned files whose
+// index is damaged. Other options are ignored.
+//
+// Values are copied as they're stored, so options like WithCompression carry
+// over. The new database uses the default hash function and the same byte
+// order as r, and w is closed, if it's an io.Closer, once it's written.
+func Recover(r io.ReaderAt, w io.WriteSeeker, opts ...Option) (int, error) {
+	size, sizeKnown := readerSize(r)
+	end := int64(math.MaxUint32)
+	if sizeKnown && size < end {
+		end = size
+	}
+
+	// If the index looks right, trust it to say where the data ends, and
+	// look for the alignment after the hash tables. The rest of the extension
+	// block doesn't matter here, so errors reading it are ignored.
+	src := &CDB{reader: r, opts: buildOptions(opts), records: &recordIndex{}}
+	if src.readIndex(size, sizeKnown) == nil && src.checkIndex(size, sizeKnown) == nil {
+		end = int64(src.index[0].offset)
+		src.readExtensions(size, sizeKnown)
+	}
+
+	order := src.opts.order()
+	dst, err := NewWriter(w, nil, WithByteOrder(order))
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	header := make([]byte, 8)
+	for offset := int64(indexSize); offset+8 <= end; n++ {
+		keyLength, valueLength, err := readTupleInto(r, order, uint32(offset), header)
+		if err != nil {
+			break
+		}
+
+		recordEnd := offset + 8 + int64(keyLength) + int64(valueLength)
+		if recordEnd > end || !readable(r, recordEnd) {
+			break
+		}
+
+		key := make([]byte, keyLength)
+		_, err = r.ReadAt(key, offset+8)
+		if err != nil {
+			break
+		}
+
+		value := io.NewSectionReader(r, offset+8+int64(keyLength), int64(valueLength))
+		err = dst.PutReader(key, valueLength, value)
+		if err != nil {
+			return n, err
+		}
+
+		offset = src.opts.align(recordEnd)
+	}
+
+	return n, dst.Close()
+}
+
+// readable returns true if the byte before end can be read from r, which
+// means a record ending there is entirely present.
+func readable(r io.ReaderAt, end int64) bool {
+	if end <= indexSize {
+		return true
+	}
+
+	_, err := r.ReadAt(make([]byte, 1), end-1)
+	return err == nil
+}
@@ -0,0 +1,124 @@
+package cdb_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecover(t *testing.T) {
+	data, err := os.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	for name, damaged := range map[string][]byte{
+		"intact": data,
+		// A database whose Writer was never finalized has an empty index,
+		// and no hash tables.
+		"unfinalized": append(make([]byte, cdb.DataOffset), data[cdb.DataOffset:mustDataEnd(t, data)]...),
+		// The same, but cut off partway through the last record.
+		"truncated": append(make([]byte, cdb.DataOffset), data[cdb.DataOffset:mustDataEnd(t, data)-3]...),
+	} {
+		path := filepath.Join(t.TempDir(), "recovered.cdb")
+		f, err := os.Create(path)
+		require.NoError(t, err)
+
+		n, err := cdb.Recover(bytes.NewReader(damaged), f)
+		require.NoError(t, err, name)
+
+		expected := expectedRecords[:len(expectedRecords)-1]
+		if name == "truncated" {
+			expected = expected[:len(expected)-1]
+		}
+
+		assert.Equal(t, len(expected), n, name)
+
+		db, err := cdb.Open(path)
+		require.NoError(t, err)
+
+		iter := db.Iter()
+		for _, record := range expected {
+			require.True(t, iter.Next(), name)
+			assert.Equal(t, string(record[0]), string(iter.Key()), name)
+			assert.Equal(t, string(record[1]), string(iter.Value()), name)
+		}
+
+		assert.False(t, iter.Next(), name)
+		require.NoError(t, iter.Err())
+		require.NoError(t, db.Close())
+	}
+}
+
+func TestRecoverAligned(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "aligned.cdb")
+	writer, err := cdb.Create(src, cdb.WithAlignment(64))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("a"), []byte("1")))
+	require.NoError(t, writer.Put([]byte("b"), []byte("2")))
+	require.NoError(t, writer.Close())
+
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "recovered.cdb")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	n, err := cdb.Recover(bytes.NewReader(data), f)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	db, err := cdb.Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	count, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	v, err := db.Get([]byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(v))
+}
+
+func TestRecoverBigEndian(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "big.cdb")
+	writer, err := cdb.Create(src, cdb.WithByteOrder(binary.BigEndian))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("a"), []byte("1")))
+	require.NoError(t, writer.Put([]byte("b"), []byte("2")))
+	require.NoError(t, writer.Close())
+
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+
+	for _, opt := range []cdb.Option{cdb.WithByteOrder(binary.BigEndian), cdb.WithDetectedByteOrder()} {
+		path := filepath.Join(t.TempDir(), "recovered.cdb")
+		f, err := os.Create(path)
+		require.NoError(t, err)
+
+		n, err := cdb.Recover(bytes.NewReader(data), f, opt)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		db, err := cdb.Open(path, cdb.WithByteOrder(binary.BigEndian))
+		require.NoError(t, err)
+
+		v, err := db.Get([]byte("b"))
+		require.NoError(t, err)
+		assert.Equal(t, "2", string(v))
+		require.NoError(t, db.Close())
+	}
+}
+
+func mustDataEnd(t *testing.T, data []byte) int {
+	db, err := cdb.FromBytes(data)
+	require.NoError(t, err)
+
+	return int(cdb.DataOffset + db.DataSize())
+}
@@ -0,0 +1,189 @@
+package cdb
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader keeps a database at a path open, and swaps in a new one whenever
+// Reload is called, or whenever Watch notices the file has changed. This is
+// useful for services that serve data from a periodically rebuilt file: the
+// new file should be written elsewhere and renamed into place, and the
+// Reloader picks it up without interrupting reads.
+//
+// The database that's replaced is closed once every reader using it has
+// finished. A Reloader is safe for concurrent use by any number of goroutines.
+type Reloader struct {
+	path string
+	opts []Option
+
+	current atomic.Pointer[generation]
+
+	mu      sync.Mutex
+	info    os.FileInfo
+	closed  bool
+	retired sync.WaitGroup
+}
+
+// generation is one version of the database, and a count of the readers
+// using it.
+type generation struct {
+	db   *CDB
+	refs int64
+}
+
+// NewReloader opens the database at path with the given options; each new
+// version is opened the same way.
+func NewReloader(path string, opts ...Option) (*Reloader, error) {
+	r := &Reloader{path: path, opts: opts}
+	err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload opens the database at the path again, and swaps it in for the
+// current one, which is closed in the background once it's no longer in use.
+// If the new database can't be opened, the current one is kept, and the error
+// is returned.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.reload()
+}
+
+func (r *Reloader) reload() error {
+	if r.closed {
+		return ErrClosed
+	}
+
+	f, err := openReadFile(r.path, buildOptions(r.opts))
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	db, err := New(f, nil, r.opts...)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.info = info
+	old := r.current.Swap(&generation{db: db})
+	if old != nil {
+		r.retired.Add(1)
+		go func() {
+			defer r.retired.Done()
+			old.close()
+		}()
+	}
+
+	return nil
+}
+
+// Watch checks the path for changes every interval, and reloads the database
+// when the file has been replaced or modified, until ctx is done. Since a
+// file might be caught partway through being written, errors from reloading
+// don't stop Watch; instead, they're passed to onError, if it's not nil, and
+// the reload is tried again at the next interval.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		err := r.reloadIfChanged()
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+func (r *Reloader) reloadIfChanged() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+
+	if os.SameFile(info, r.info) && info.Size() == r.info.Size() && info.ModTime().Equal(r.info.ModTime()) {
+		return nil
+	}
+
+	return r.reload()
+}
+
+// Acquire returns the current database, and a function to call once the
+// caller has finished with it; until then, the database won't be closed, even
+// if it's replaced. Once the Reloader is closed, the returned database is
+// closed as well, and reads from it return ErrClosed.
+func (r *Reloader) Acquire() (*CDB, func()) {
+	for {
+		g := r.current.Load()
+		atomic.AddInt64(&g.refs, 1)
+
+		// If the database was swapped out before we registered, it might
+		// already be closing, so try again with the new one.
+		if r.current.Load() == g {
+			return g.db, func() { atomic.AddInt64(&g.refs, -1) }
+		}
+
+		atomic.AddInt64(&g.refs, -1)
+	}
+}
+
+// Get returns the value for a given key from the current database, or nil if
+// it can't be found.
+func (r *Reloader) Get(key []byte) ([]byte, error) {
+	db, release := r.Acquire()
+	defer release()
+
+	return db.Get(key)
+}
+
+// Close closes the current database, after waiting for every reader to finish
+// with it and with any databases it replaced. Further calls to Reload fail
+// with ErrClosed.
+func (r *Reloader) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+
+	r.closed = true
+	r.mu.Unlock()
+
+	err := r.current.Load().close()
+	r.retired.Wait()
+	return err
+}
+
+// close waits for the generation's readers to finish, then closes the
+// database.
+func (g *generation) close() error {
+	for atomic.LoadInt64(&g.refs) > 0 {
+		time.Sleep(drainInterval)
+	}
+
+	return g.db.CloseContext(context.Background())
+}
@@ -0,0 +1,113 @@
+package cdb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replaceDB atomically replaces the database at path with one containing a
+// single record.
+func replaceDB(t *testing.T, path, key, value string) {
+	tmp := path + ".tmp"
+	writer, err := cdb.Create(tmp)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte(key), []byte(value)))
+	require.NoError(t, writer.Close())
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+// waitFor fails the test if fn doesn't return true within a second.
+func waitFor(t *testing.T, fn func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !fn() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reload.cdb")
+	replaceDB(t, path, "foo", "v1")
+
+	r, err := cdb.NewReloader(path)
+	require.NoError(t, err)
+
+	value, err := r.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(value))
+
+	old, release := r.Acquire()
+	replaceDB(t, path, "foo", "v2")
+	require.NoError(t, r.Reload())
+
+	value, err = r.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(value))
+
+	// The old database stays open until it's released.
+	value, err = old.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(value))
+
+	release()
+	waitFor(t, func() bool {
+		_, err := old.Get([]byte("foo"))
+		return err == cdb.ErrClosed
+	})
+
+	require.NoError(t, r.Close())
+	_, err = r.Get([]byte("foo"))
+	assert.Equal(t, cdb.ErrClosed, err)
+	assert.Equal(t, cdb.ErrClosed, r.Reload())
+}
+
+func TestReloaderKeepsCurrentOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reload.cdb")
+	replaceDB(t, path, "foo", "v1")
+
+	r, err := cdb.NewReloader(path)
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, os.WriteFile(path+".tmp", []byte("garbage"), 0644))
+	require.NoError(t, os.Rename(path+".tmp", path))
+	assert.Error(t, r.Reload())
+
+	value, err := r.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(value))
+}
+
+func TestReloaderWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reload.cdb")
+	replaceDB(t, path, "foo", "v1")
+
+	r, err := cdb.NewReloader(path)
+	require.NoError(t, err)
+	defer r.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.Watch(ctx, time.Millisecond, nil)
+	}()
+
+	replaceDB(t, path, "foo", "v2")
+	waitFor(t, func() bool {
+		value, err := r.Get([]byte("foo"))
+		return err == nil && string(value) == "v2"
+	})
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
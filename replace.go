@@ -0,0 +1,93 @@
+package cdb
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrValueLength is returned by ReplaceInPlace if the new value isn't the
+// same length as the old one.
+var ErrValueLength = errors.New("new value must be the same length as the old one")
+
+// ReplaceInPlace overwrites the value for the first record with the given key,
+// without rebuilding the file. The new value must take up exactly as much
+// space as the old one, or ReplaceInPlace returns ErrValueLength; if the key
+// can't be found, it returns ErrNotFound. The underlying reader must also be
+// an io.WriterAt, such as an *os.File opened for writing; otherwise,
+// ReplaceInPlace returns os.ErrInvalid.
+//
+// For databases written WithExpiry, the record keeps its expiration time. For
+// databases written WithCompression, the new value is stored compressed if
+// that makes it the right length, and as-is otherwise. For databases written
+// WithEncryption, the new value is encrypted with a fresh nonce.
+//
+// Readers may see a partially written value while ReplaceInPlace is running,
+// including readers in other processes, so it's only suitable for databases
+// that aren't being read from concurrently, or for values where that's
+// acceptable.
+func (cdb *CDB) ReplaceInPlace(key, newValue []byte) error {
+	w, ok := cdb.writerAt()
+	if !ok {
+		return os.ErrInvalid
+	}
+
+	key = cdb.opts.normalizeKey(key)
+	if cdb.hidden(key) {
+		return ErrNotFound
+	}
+
+	err := cdb.acquire(opGet)
+	if err != nil {
+		return err
+	}
+	defer cdb.release(opGet)
+
+	stored, offset, err := cdb.lookup(cdb.hash(key), key)
+	if err != nil {
+		return err
+	} else if stored == nil {
+		return ErrNotFound
+	}
+
+	var prefix []byte
+	if cdb.opts.expiry {
+		prefix = stored[:expiryPrefixSize]
+	}
+
+	encoded, err := cdb.opts.compressValue(newValue)
+	if err != nil {
+		return err
+	}
+
+	if cdb.opts.compressor != nil && len(prefix)+cdb.opts.sealedSize(len(encoded)) != len(stored) {
+		encoded = append([]byte{0}, newValue...)
+	}
+
+	encoded, err = cdb.opts.sealValue(key, encoded)
+	if err != nil {
+		return err
+	}
+
+	if len(prefix)+len(encoded) != len(stored) {
+		return ErrValueLength
+	}
+
+	_, err = w.WriteAt(append(prefix, encoded...), int64(offset)+8+int64(len(key)))
+	return err
+}
+
+// writerAt returns the underlying reader as an io.WriterAt, if it is one.
+func (cdb *CDB) writerAt() (io.WriterAt, bool) {
+	r := cdb.reader
+	if mr, ok := r.(metricsReader); ok {
+		r = mr.ReaderAt
+	}
+
+	if sr, ok := r.(slowReader); ok {
+		r = sr.ReaderAt
+	}
+
+	w, ok := r.(io.WriterAt)
+	return w, ok
+}
@@ -0,0 +1,74 @@
+package cdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceInPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replace.cdb")
+	writer, err := cdb.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("counter"), []byte("0001")))
+	require.NoError(t, writer.Put([]byte("other"), []byte("xyz")))
+	require.NoError(t, writer.Close())
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+
+	db, err := cdb.New(f, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, db.ReplaceInPlace([]byte("counter"), []byte("0002")))
+	assert.Equal(t, cdb.ErrValueLength, db.ReplaceInPlace([]byte("counter"), []byte("3")))
+	assert.Equal(t, cdb.ErrNotFound, db.ReplaceInPlace([]byte("missing"), []byte("0003")))
+	require.NoError(t, db.Close())
+
+	db, err = cdb.Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	value, err := db.Get([]byte("counter"))
+	require.NoError(t, err)
+	assert.Equal(t, "0002", string(value))
+
+	value, err = db.Get([]byte("other"))
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", string(value))
+
+	// The file was opened read-only.
+	assert.Error(t, db.ReplaceInPlace([]byte("counter"), []byte("0003")))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	db, err = cdb.FromBytes(data)
+	require.NoError(t, err)
+	assert.Equal(t, os.ErrInvalid, db.ReplaceInPlace([]byte("counter"), []byte("0003")))
+}
+
+func TestReplaceInPlaceExpiry(t *testing.T) {
+	writer := cdb.NewMem(cdb.WithExpiry(), cdb.WithCompression(cdb.Gzip))
+	require.NoError(t, writer.PutWithExpiry([]byte("foo"), []byte("bar"), time.Now().Add(-time.Hour)))
+	require.NoError(t, writer.Put([]byte("foo"), []byte("baz")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	// The first record has expired, so the second one is replaced.
+	require.NoError(t, db.ReplaceInPlace([]byte("foo"), []byte("qux")))
+
+	value, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "qux", string(value))
+
+	expired := db.Expired()
+	require.True(t, expired.Next())
+	assert.Equal(t, "bar", string(expired.Value()))
+}
@@ -0,0 +1,108 @@
+package resp
+
+// matchGlob reports whether s matches a glob-style pattern, as used by the
+// MATCH option of SCAN: '*' matches any run of bytes, '?' matches any one
+// byte, "[...]" matches one byte from a class such as "[abc]", "[a-z]" or
+// "[^0-9]", and '\' matches the byte after it literally.
+//
+// It backtracks only to the most recent '*', which is enough to find a match
+// if there is one, so that patterns with many stars don't take exponential
+// time.
+func matchGlob(pattern, s []byte) bool {
+	p, i := 0, 0
+	star, starMatch := -1, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				star, starMatch = p, i
+				p++
+				continue
+			case '?':
+				p++
+				i++
+				continue
+			case '[':
+				if ok, next := matchClass(pattern, p, s[i]); ok {
+					p = next
+					i++
+					continue
+				}
+			case '\\':
+				c, next := literal(pattern, p)
+				if c == s[i] {
+					p = next
+					i++
+					continue
+				}
+			default:
+				if pattern[p] == s[i] {
+					p++
+					i++
+					continue
+				}
+			}
+		}
+
+		// On a mismatch, let the last star match one more byte, and try
+		// again from there.
+		if star < 0 {
+			return false
+		}
+
+		starMatch++
+		p, i = star+1, starMatch
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// matchClass reports whether c is in the class that starts with the '[' at
+// pattern[p], and returns the position after the class. A class that isn't
+// closed runs to the end of the pattern.
+func matchClass(pattern []byte, p int, c byte) (bool, int) {
+	p++
+	negate := p < len(pattern) && pattern[p] == '^'
+	if negate {
+		p++
+	}
+
+	matched := false
+	for p < len(pattern) && pattern[p] != ']' {
+		lo, next := literal(pattern, p)
+		hi := lo
+		if next+1 < len(pattern) && pattern[next] == '-' && pattern[next+1] != ']' {
+			hi, next = literal(pattern, next+1)
+		}
+
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+
+		if lo <= c && c <= hi {
+			matched = true
+		}
+
+		p = next
+	}
+
+	if p < len(pattern) {
+		p++
+	}
+
+	return matched != negate, p
+}
+
+// literal returns the byte at pattern[p], or the byte after it if it's a
+// backslash, and the position after that.
+func literal(pattern []byte, p int) (byte, int) {
+	if pattern[p] == '\\' && p+1 < len(pattern) {
+		return pattern[p+1], p + 2
+	}
+
+	return pattern[p], p + 1
+}
@@ -0,0 +1,47 @@
+package resp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		match      bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "anything", true},
+		{"user:*", "user:1", true},
+		{"user:*", "users:1", false},
+		{"*:1", "user:1", true},
+		{"*:1", "user:12", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[c-a]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"[abc", "b", true},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.match, matchGlob([]byte(c.pattern), []byte(c.s)), "%q against %q", c.pattern, c.s)
+	}
+
+	// This would take a very long time with naive backtracking.
+	pattern := strings.Repeat("*a", 30) + "b"
+	assert.False(t, matchGlob([]byte(pattern), []byte(strings.Repeat("a", 100))))
+}
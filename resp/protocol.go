@@ -0,0 +1,139 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// The most arguments a command can have, and the longest argument, so
+	// that a bad request can't make the server allocate without bound.
+	maxArgs       = 1 << 20
+	maxBulkLength = 64 << 20
+)
+
+// A protocolError means the client sent something that isn't RESP. The
+// connection is closed after replying, since there's no way to tell where the
+// next command starts.
+type protocolError string
+
+func (e protocolError) Error() string {
+	return "Protocol error: " + string(e)
+}
+
+// readCommand reads the next command from r. Clients send commands as arrays
+// of bulk strings, but a line of words separated by spaces is accepted too,
+// as Redis does, so that the server can be tried out with telnet.
+func readCommand(r *bufio.Reader) ([][]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		return bytes.Fields(append([]byte(nil), line...)), nil
+	}
+
+	n, err := parseLength(line[1:], maxArgs)
+	if err != nil {
+		return nil, protocolError("invalid multibulk length")
+	}
+
+	args := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		} else if len(line) == 0 || line[0] != '$' {
+			return nil, protocolError("expected '$'")
+		}
+
+		size, err := parseLength(line[1:], maxBulkLength)
+		if err != nil {
+			return nil, protocolError("invalid bulk length")
+		}
+
+		buf := make([]byte, size+2)
+		_, err = io.ReadFull(r, buf)
+		if err != nil {
+			return nil, err
+		} else if !bytes.HasSuffix(buf, []byte("\r\n")) {
+			return nil, protocolError("expected CRLF after bulk string")
+		}
+
+		args = append(args, buf[:size])
+	}
+
+	return args, nil
+}
+
+// readLine reads a line, without the line ending. The line is only valid
+// until the next read from r.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, protocolError("line too long")
+	} else if err != nil {
+		return nil, err
+	}
+
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+
+	return line, nil
+}
+
+func parseLength(b []byte, max int) (int, error) {
+	n, err := strconv.Atoi(string(b))
+	if err != nil {
+		return 0, err
+	} else if n < 0 || n > max {
+		return 0, strconv.ErrRange
+	}
+
+	return n, nil
+}
+
+func writeSimple(w *bufio.Writer, s string) {
+	w.WriteByte('+')
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// writeError writes an error reply, which must fit on one line.
+func writeError(w *bufio.Writer, msg string) {
+	w.WriteByte('-')
+	w.WriteString(strings.NewReplacer("\r", " ", "\n", " ").Replace(msg))
+	w.WriteString("\r\n")
+}
+
+func writeInt(w *bufio.Writer, n int64) {
+	w.WriteByte(':')
+	w.WriteString(strconv.FormatInt(n, 10))
+	w.WriteString("\r\n")
+}
+
+// writeBulk writes b as a bulk string, or the null bulk string if b is nil.
+func writeBulk(w *bufio.Writer, b []byte) {
+	if b == nil {
+		w.WriteString("$-1\r\n")
+		return
+	}
+
+	w.WriteByte('$')
+	w.WriteString(strconv.Itoa(len(b)))
+	w.WriteString("\r\n")
+	w.Write(b)
+	w.WriteString("\r\n")
+}
+
+func writeArray(w *bufio.Writer, n int) {
+	w.WriteByte('*')
+	w.WriteString(strconv.Itoa(n))
+	w.WriteString("\r\n")
+}
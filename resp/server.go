@@ -0,0 +1,428 @@
+// Package resp serves lookups from cdb databases over the Redis protocol
+// (RESP), so that code written against a Redis client can read static
+// datasets directly. The server is read-only, and supports these commands:
+//
+//	GET key
+//	MGET key [key ...]
+//	EXISTS key [key ...]
+//	SCAN cursor [MATCH pattern] [COUNT count]
+//	PING [message]
+//	ECHO message
+//	QUIT
+//
+// A server can serve several databases at once. Lookups check each of them
+// in turn, and the first one with the key wins, while SCAN visits every
+// record in each of them, one after another. The server replies in RESP2,
+// which every Redis client supports.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/colinmarc/cdb"
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe once the server has
+// been closed.
+var ErrServerClosed = errors.New("server closed")
+
+// The number of records SCAN looks at when no COUNT is given, which is the
+// same as in Redis.
+const defaultScanCount = 10
+
+// Server serves the databases at a list of paths over RESP. Each database is
+// held by a cdb.Reloader, so it can be swapped for a new version of the file
+// with Reload, without interrupting clients.
+type Server struct {
+	dbs []*cdb.Reloader
+
+	mu        sync.Mutex
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer opens the databases at paths with the given options, in order of
+// precedence for lookups. It returns os.ErrInvalid if there are no paths.
+func NewServer(paths []string, opts ...cdb.Option) (*Server, error) {
+	if len(paths) == 0 {
+		return nil, os.ErrInvalid
+	}
+
+	s := &Server{
+		listeners: make(map[net.Listener]bool),
+		conns:     make(map[net.Conn]bool),
+	}
+
+	for _, path := range paths {
+		r, err := cdb.NewReloader(path, opts...)
+		if err != nil {
+			for _, r := range s.dbs {
+				r.Close()
+			}
+
+			return nil, err
+		}
+
+		s.dbs = append(s.dbs, r)
+	}
+
+	return s, nil
+}
+
+// Reload opens every database again, as cdb.Reloader.Reload does, so that a
+// new version of a file that's been renamed into place is picked up. If some
+// of them can't be opened, they're left as they were, the rest are still
+// reloaded, and the first error is returned.
+func (s *Server) Reload() error {
+	var firstErr error
+	for _, r := range s.dbs {
+		err := r.Reload()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// ListenAndServe listens on the TCP address addr, and serves connections
+// from it until the server is closed.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve accepts connections from l, and serves each of them in its own
+// goroutine, until the server is closed or accepting fails. It closes l
+// before returning, and returns ErrServerClosed if the server was closed.
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+	if !s.track(l, nil) {
+		return ErrServerClosed
+	}
+	defer s.untrack(l, nil)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+
+			return err
+		}
+
+		if !s.track(nil, conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops the server: it closes every listener and connection, waits for
+// the commands in progress to finish, then closes the databases, and returns
+// the first error, if any.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+
+	s.mu.Unlock()
+	s.wg.Wait()
+
+	var firstErr error
+	for _, r := range s.dbs {
+		err := r.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// track registers a listener or connection, so that Close can close it, or
+// returns false if the server is already closed.
+func (s *Server) track(l net.Listener, conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	if l != nil {
+		s.listeners[l] = true
+	}
+
+	if conn != nil {
+		s.conns[conn] = true
+		s.wg.Add(1)
+	}
+
+	return true
+}
+
+func (s *Server) untrack(l net.Listener, conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.listeners, l)
+	if conn != nil {
+		delete(s.conns, conn)
+		s.wg.Done()
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// serveConn reads commands from conn and replies to them, until the client
+// quits or disconnects. Replies are buffered until there are no more commands
+// waiting to be read, so that pipelined commands are answered in bulk.
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.untrack(nil, conn)
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if perr, ok := err.(protocolError); ok {
+				writeError(w, "ERR "+perr.Error())
+				w.Flush()
+			}
+
+			return
+		} else if len(args) == 0 {
+			continue
+		}
+
+		quit := s.dispatch(w, args)
+		if quit || r.Buffered() == 0 {
+			err = w.Flush()
+			if err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// dispatch runs a command and writes the reply, and returns true if the
+// client asked to close the connection.
+func (s *Server) dispatch(w *bufio.Writer, args [][]byte) bool {
+	name := strings.ToLower(string(args[0]))
+	switch name {
+	case "get":
+		if len(args) != 2 {
+			break
+		}
+
+		value, err := s.get(args[1])
+		if err != nil {
+			writeError(w, "ERR "+err.Error())
+		} else {
+			writeBulk(w, value)
+		}
+
+		return false
+	case "mget":
+		if len(args) < 2 {
+			break
+		}
+
+		// Look everything up first, so that an error can be returned
+		// instead of the array.
+		values := make([][]byte, len(args)-1)
+		for i, key := range args[1:] {
+			var err error
+			values[i], err = s.get(key)
+			if err != nil {
+				writeError(w, "ERR "+err.Error())
+				return false
+			}
+		}
+
+		writeArray(w, len(values))
+		for _, value := range values {
+			writeBulk(w, value)
+		}
+
+		return false
+	case "exists":
+		if len(args) < 2 {
+			break
+		}
+
+		n := int64(0)
+		for _, key := range args[1:] {
+			value, err := s.get(key)
+			if err != nil {
+				writeError(w, "ERR "+err.Error())
+				return false
+			} else if value != nil {
+				n++
+			}
+		}
+
+		writeInt(w, n)
+		return false
+	case "scan":
+		if len(args) < 2 {
+			break
+		}
+
+		s.scan(w, args[1:])
+		return false
+	case "ping":
+		if len(args) == 1 {
+			writeSimple(w, "PONG")
+			return false
+		} else if len(args) == 2 {
+			writeBulk(w, args[1])
+			return false
+		}
+	case "echo":
+		if len(args) == 2 {
+			writeBulk(w, args[1])
+			return false
+		}
+	case "quit":
+		writeSimple(w, "OK")
+		return true
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		return false
+	}
+
+	writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+	return false
+}
+
+// get returns the value for key in the first database that has it, or nil if
+// none of them do.
+func (s *Server) get(key []byte) ([]byte, error) {
+	for _, r := range s.dbs {
+		value, err := r.Get(key)
+		if err != nil || value != nil {
+			return value, err
+		}
+	}
+
+	return nil, nil
+}
+
+// scan runs SCAN. The cursor is the index of a database in the high 32 bits,
+// and a cursor for cdb.CDB.EachFrom within it in the low 32 bits, so zero
+// starts at the beginning of the first database, as it should. Like in Redis,
+// COUNT is the number of records to look at, so fewer keys than that are
+// returned if some don't match the pattern.
+//
+// Cursors are offsets into the files, so a scan that's in progress when a
+// database is reloaded may fail, or return unexpected keys, unless the file
+// is unchanged.
+func (s *Server) scan(w *bufio.Writer, args [][]byte) {
+	cursor, err := strconv.ParseUint(string(args[0]), 10, 64)
+	if err != nil {
+		writeError(w, "ERR invalid cursor")
+		return
+	}
+
+	count := defaultScanCount
+	var pattern []byte
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			writeError(w, "ERR syntax error")
+			return
+		}
+
+		switch strings.ToLower(string(args[i])) {
+		case "match":
+			pattern = args[i+1]
+		case "count":
+			count, err = strconv.Atoi(string(args[i+1]))
+			if err != nil || count < 1 {
+				writeError(w, "ERR value is not an integer or out of range")
+				return
+			}
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	db, offset := cursor>>32, uint32(cursor)
+	visited := 0
+	var keys [][]byte
+	for db < uint64(len(s.dbs)) && visited < count {
+		current, release := s.dbs[db].Acquire()
+		next, err := current.EachFrom(offset, func(key, value []byte) error {
+			visited++
+			if pattern == nil || matchGlob(pattern, key) {
+				keys = append(keys, key)
+			}
+
+			if visited >= count {
+				return cdb.ErrStop
+			}
+
+			return nil
+		})
+		release()
+
+		if err != nil {
+			writeError(w, "ERR "+err.Error())
+			return
+		}
+
+		if next == 0 {
+			db, offset = db+1, 0
+		} else {
+			offset = next
+		}
+	}
+
+	if db >= uint64(len(s.dbs)) {
+		cursor = 0
+	} else {
+		cursor = db<<32 | uint64(offset)
+	}
+
+	writeArray(w, 2)
+	writeBulk(w, []byte(strconv.FormatUint(cursor, 10)))
+	writeArray(w, len(keys))
+	for _, key := range keys {
+		writeBulk(w, key)
+	}
+}
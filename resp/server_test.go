@@ -0,0 +1,232 @@
+package resp_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/colinmarc/cdb/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// client speaks just enough RESP to test the server.
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) send(args ...string) {
+	fmt.Fprintf(c.conn, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.conn, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// reply reads a reply. Simple strings and errors are returned as strings,
+// with errors prefixed by "-", bulk strings as []byte, or nil for the null
+// bulk string, integers as int64, and arrays as []interface{}.
+func (c *client) reply(t *testing.T) interface{} {
+	line, err := c.r.ReadString('\n')
+	require.NoError(t, err)
+	line = strings.TrimSuffix(line, "\r\n")
+
+	switch line[0] {
+	case '+':
+		return line[1:]
+	case '-':
+		return line
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		require.NoError(t, err)
+		return n
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		require.NoError(t, err)
+		if n < 0 {
+			return nil
+		}
+
+		buf := make([]byte, n+2)
+		_, err = io.ReadFull(c.r, buf)
+		require.NoError(t, err)
+		return buf[:n]
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		require.NoError(t, err)
+		values := make([]interface{}, n)
+		for i := range values {
+			values[i] = c.reply(t)
+		}
+
+		return values
+	default:
+		t.Fatalf("unexpected reply %q", line)
+		return nil
+	}
+}
+
+func (c *client) do(t *testing.T, args ...string) interface{} {
+	c.send(args...)
+	return c.reply(t)
+}
+
+func writeDB(t *testing.T, path string, records map[string]string) {
+	writer, err := cdb.Create(path)
+	require.NoError(t, err)
+	for k, v := range records {
+		require.NoError(t, writer.Put([]byte(k), []byte(v)))
+	}
+
+	require.NoError(t, writer.Close())
+}
+
+func startServer(t *testing.T, paths ...string) (*resp.Server, net.Listener, chan error) {
+	s, err := resp.NewServer(paths)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Serve(l)
+	}()
+
+	return s, l, errs
+}
+
+func dial(t *testing.T, l net.Listener) *client {
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	return &client{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	first, second := filepath.Join(dir, "first.cdb"), filepath.Join(dir, "second.cdb")
+	writeDB(t, first, map[string]string{"foo": "1", "empty": ""})
+	writeDB(t, second, map[string]string{"foo": "2", "bar": "3"})
+
+	s, l, errs := startServer(t, first, second)
+	c := dial(t, l)
+
+	assert.Equal(t, "PONG", c.do(t, "PING"))
+	assert.Equal(t, []byte("hi"), c.do(t, "ping", "hi"))
+	assert.Equal(t, []byte("hi"), c.do(t, "ECHO", "hi"))
+
+	assert.Equal(t, []byte("1"), c.do(t, "GET", "foo"))
+	assert.Equal(t, []byte("3"), c.do(t, "GET", "bar"))
+	assert.Equal(t, []byte(""), c.do(t, "GET", "empty"))
+	assert.Nil(t, c.do(t, "GET", "missing"))
+
+	assert.Equal(t, []interface{}{[]byte("1"), nil, []byte("3")}, c.do(t, "MGET", "foo", "missing", "bar"))
+	assert.Equal(t, int64(2), c.do(t, "EXISTS", "foo", "missing", "bar"))
+
+	assert.Equal(t, "-ERR wrong number of arguments for 'get' command", c.do(t, "GET"))
+	assert.Equal(t, "-ERR unknown command 'SET'", c.do(t, "SET", "foo", "bar"))
+	assert.Equal(t, "-ERR invalid cursor", c.do(t, "SCAN", "x"))
+	assert.Equal(t, "-ERR syntax error", c.do(t, "SCAN", "0", "COUNT"))
+
+	// Commands can be pipelined, or sent inline.
+	c.send("GET", "foo")
+	c.send("GET", "bar")
+	fmt.Fprintf(c.conn, "EXISTS foo\r\n")
+	assert.Equal(t, []byte("1"), c.reply(t))
+	assert.Equal(t, []byte("3"), c.reply(t))
+	assert.Equal(t, int64(1), c.reply(t))
+
+	// Replace the first database, and reload.
+	next := filepath.Join(dir, "next.cdb")
+	writeDB(t, next, map[string]string{"foo": "new"})
+	require.NoError(t, os.Rename(next, first))
+	require.NoError(t, s.Reload())
+	assert.Equal(t, []byte("new"), c.do(t, "GET", "foo"))
+
+	assert.Equal(t, "OK", c.do(t, "QUIT"))
+	_, err = c.r.ReadByte()
+	assert.Equal(t, io.EOF, err)
+
+	require.NoError(t, s.Close())
+	assert.Equal(t, resp.ErrServerClosed, <-errs)
+}
+
+func TestServerScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var paths, expected []string
+	for i := 0; i < 3; i++ {
+		records := make(map[string]string)
+		for j := 0; j < 25; j++ {
+			key := fmt.Sprintf("key:%d:%d", i, j)
+			records[key] = "x"
+			expected = append(expected, key)
+		}
+
+		records[fmt.Sprintf("other:%d", i)] = "y"
+		path := filepath.Join(dir, strconv.Itoa(i))
+		writeDB(t, path, records)
+		paths = append(paths, path)
+	}
+
+	s, l, _ := startServer(t, paths...)
+	defer s.Close()
+	c := dial(t, l)
+
+	var keys []string
+	cursor := "0"
+	for {
+		reply := c.do(t, "SCAN", cursor, "MATCH", "key:*", "COUNT", "7").([]interface{})
+		require.Len(t, reply, 2)
+
+		for _, key := range reply[1].([]interface{}) {
+			keys = append(keys, string(key.([]byte)))
+		}
+
+		cursor = string(reply[0].([]byte))
+		if cursor == "0" {
+			break
+		}
+	}
+
+	sort.Strings(keys)
+	sort.Strings(expected)
+	assert.Equal(t, expected, keys)
+}
+
+func TestServerProtocolError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.cdb")
+	writeDB(t, path, map[string]string{"foo": "bar"})
+
+	s, l, _ := startServer(t, path)
+	defer s.Close()
+	c := dial(t, l)
+
+	fmt.Fprintf(c.conn, "*1\r\n+GET\r\n")
+	assert.Equal(t, "-ERR Protocol error: expected '$'", c.reply(t))
+	_, err = c.r.ReadByte()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestNewServerNoPaths(t *testing.T) {
+	_, err := resp.NewServer(nil)
+	assert.Equal(t, os.ErrInvalid, err)
+}
@@ -0,0 +1,70 @@
+package cdb
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// SampleKeys returns the keys of n records chosen at random, without reading
+// the whole database: it picks slots in the hash tables at random, and reads
+// the records in the ones that aren't empty. Since every record has exactly
+// one slot, each record is equally likely to be chosen, and none is chosen
+// twice. The same seed picks the same records from the same file.
+//
+// As when iterating, records hidden by a view or that have expired are
+// skipped. If n is more than the number of records, the key of every record is
+// returned, but that takes longer than iterating over them.
+func (cdb *CDB) SampleKeys(n int, seed int64) ([][]byte, error) {
+	err := cdb.acquire(opScan)
+	if err != nil {
+		return nil, err
+	}
+	defer cdb.release(opScan)
+
+	// ends holds the total number of slots in each table and the ones
+	// before it, so that a slot can be chosen across all of them.
+	var ends [256]int64
+	total := int64(0)
+	for i, table := range cdb.index {
+		total += int64(table.length)
+		ends[i] = total
+	}
+
+	var keys [][]byte
+	if n <= 0 || total == 0 {
+		return keys, nil
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	seen := make(map[int64]bool)
+	scratch := make([]byte, 8)
+	for len(keys) < n && int64(len(seen)) < total {
+		s := rnd.Int63n(total)
+		if seen[s] {
+			continue
+		}
+
+		seen[s] = true
+		i := sort.Search(len(ends), func(i int) bool { return ends[i] > s })
+		table := cdb.index[i]
+		slot := uint32(s - (ends[i] - int64(table.length)))
+
+		_, offset, err := cdb.readSlot(table.offset+8*slot, scratch)
+		if err != nil {
+			return nil, err
+		} else if offset == 0 {
+			continue
+		}
+
+		key, _, skip, err := cdb.readKey(offset)
+		if err != nil {
+			return nil, err
+		} else if skip {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
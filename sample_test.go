@@ -0,0 +1,89 @@
+package cdb_test
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleKeys(t *testing.T) {
+	writer := cdb.NewMem()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value")))
+	}
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	keys, err := db.SampleKeys(100, 1)
+	require.NoError(t, err)
+	assert.Len(t, keys, 100)
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		i, err := strconv.Atoi(string(key))
+		require.NoError(t, err)
+		assert.True(t, i >= 0 && i < 1000)
+		assert.False(t, seen[string(key)], "sampled twice: %s", key)
+		seen[string(key)] = true
+	}
+
+	again, err := db.SampleKeys(100, 1)
+	require.NoError(t, err)
+	assert.Equal(t, keys, again)
+
+	other, err := db.SampleKeys(100, 2)
+	require.NoError(t, err)
+	assert.NotEqual(t, keys, other)
+}
+
+func TestSampleKeysAll(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer db.Close()
+
+	keys, err := db.SampleKeys(100, 1)
+	require.NoError(t, err)
+
+	var expected []string
+	iter := db.Iter()
+	for iter.Next() {
+		expected = append(expected, string(iter.Key()))
+	}
+
+	require.NoError(t, iter.Err())
+
+	var actual []string
+	for _, key := range keys {
+		actual = append(actual, string(key))
+	}
+
+	sort.Strings(expected)
+	sort.Strings(actual)
+	assert.Equal(t, expected, actual)
+}
+
+func TestSampleKeysEmpty(t *testing.T) {
+	db, err := cdb.NewMem().Freeze()
+	require.NoError(t, err)
+
+	keys, err := db.SampleKeys(10, 1)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func BenchmarkSampleKeys(b *testing.B) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(b, err)
+	defer db.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := db.SampleKeys(10, int64(i))
+		require.NoError(b, err)
+	}
+}
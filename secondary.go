@@ -0,0 +1,33 @@
+package cdb
+
+// BuildIndex builds a secondary index over src, writing it to dst. For each
+// record in src, extract is called with the key and value, and returns the
+// tokens to index it under, such as the values of a field; dst gets a record
+// mapping each token to the record's key. Duplicate tokens for the same record
+// are only written once.
+//
+// To look up the records for a token, pass it to GetAll on the index, then
+// Get each of the keys it returns from the original database.
+//
+// BuildIndex does not finalize dst; the caller must call Close or Freeze once
+// it returns.
+func BuildIndex(dst *Writer, src *CDB, extract func(key, value []byte) [][]byte) error {
+	iter := src.Iter()
+	for iter.Next() {
+		tokens := extract(iter.Key(), iter.Value())
+		seen := make(map[string]bool, len(tokens))
+		for _, token := range tokens {
+			if seen[string(token)] {
+				continue
+			}
+
+			seen[string(token)] = true
+			err := dst.Put(token, iter.Key())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return iter.Err()
+}
@@ -0,0 +1,44 @@
+package cdb_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildIndex(t *testing.T) {
+	db := makeDB(t, [][2]string{
+		{"alice", "red blue"},
+		{"bob", "blue"},
+		{"carol", "green green red"},
+	})
+
+	writer := cdb.NewMem()
+	err := cdb.BuildIndex(writer, db, func(key, value []byte) [][]byte {
+		return bytes.Fields(value)
+	})
+	require.NoError(t, err)
+
+	index, err := writer.Freeze()
+	require.NoError(t, err)
+
+	for token, expected := range map[string][]string{
+		"red":    {"alice", "carol"},
+		"blue":   {"alice", "bob"},
+		"green":  {"carol"},
+		"yellow": nil,
+	} {
+		keys, err := index.GetAll([]byte(token))
+		require.NoError(t, err)
+
+		var actual []string
+		for _, key := range keys {
+			actual = append(actual, string(key))
+		}
+
+		assert.Equal(t, expected, actual, token)
+	}
+}
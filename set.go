@@ -0,0 +1,281 @@
+package cdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoShards is returned by OpenSet if the pattern doesn't match any files.
+var ErrNoShards = errors.New("no shards found")
+
+// CDBSet represents a database that is split across several CDB files, or
+// shards, by the hash of each key. This is useful for datasets that are larger
+// than the 4GB limit for a single file. To create one, use ShardedWriter.
+//
+// Keys are assigned to shards based on the number of shards, so a set must be
+// opened with the same shards, in the same order, that it was written with.
+type CDBSet struct {
+	shards []*CDB
+}
+
+// OpenSet opens all the files matching the given glob pattern as the shards
+// of a CDBSet. The shards are ordered by filename, so the names should sort in
+// the order they were created in; CreateSet does this with a zero-padded
+// format such as "users-%03d.cdb".
+func OpenSet(pattern string, opts ...Option) (*CDBSet, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	} else if len(paths) == 0 {
+		return nil, ErrNoShards
+	}
+
+	sort.Strings(paths)
+	shards := make([]*CDB, 0, len(paths))
+	for _, path := range paths {
+		db, err := Open(path, opts...)
+		if err != nil {
+			for _, shard := range shards {
+				shard.Close()
+			}
+
+			return nil, err
+		}
+
+		shards = append(shards, db)
+	}
+
+	return NewSet(shards)
+}
+
+// NewSet creates a CDBSet from already-open shards. All of the shards must use
+// the same hash function. If there are no shards, NewSet returns
+// os.ErrInvalid.
+func NewSet(shards []*CDB) (*CDBSet, error) {
+	if len(shards) == 0 {
+		return nil, os.ErrInvalid
+	}
+
+	return &CDBSet{shards: shards}, nil
+}
+
+// Get returns the value for a given key, or nil if it can't be found.
+func (set *CDBSet) Get(key []byte) ([]byte, error) {
+	shard, hash := set.route(key)
+	return shard.GetHashed(hash, key)
+}
+
+// GetStrict returns the value for a given key, or ErrNotFound if it can't be
+// found.
+func (set *CDBSet) GetStrict(key []byte) ([]byte, error) {
+	return set.Shard(key).GetStrict(key)
+}
+
+// GetBatch looks up each of keys using a pool of concurrency goroutines, and
+// returns the values in the same order as the keys, with nil for keys that
+// can't be found. Each key is routed to its shard as it's looked up, so lookups
+// on different shards proceed in parallel. If concurrency is less than 1, it
+// defaults to the number of shards.
+//
+// If any lookup fails, GetBatch stops issuing new lookups and returns the
+// first error once the in-flight lookups have finished. If ctx is cancelled,
+// it returns ctx.Err() the same way.
+func (set *CDBSet) GetBatch(ctx context.Context, keys [][]byte, concurrency int) ([][]byte, error) {
+	if concurrency < 1 {
+		concurrency = len(set.shards)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	values := make([][]byte, len(keys))
+	work := make(chan int)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				value, err := set.Get(keys[i])
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+
+				values[i] = value
+			}
+		}()
+	}
+
+feed:
+	for i := range keys {
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	} else if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Shard returns the shard that a given key would be stored in.
+func (set *CDBSet) Shard(key []byte) *CDB {
+	shard, _ := set.route(key)
+	return shard
+}
+
+// route returns the shard for a given key, along with the key's hash.
+func (set *CDBSet) route(key []byte) (*CDB, uint32) {
+	shard := set.shards[0]
+	hash := shard.hash(shard.opts.normalizeKey(key))
+	return set.shards[shardFor(hash, len(set.shards))], hash
+}
+
+// Shards returns the shards in the set, in order.
+func (set *CDBSet) Shards() []*CDB {
+	return set.shards
+}
+
+// Close closes all the shards in the set. It returns the first error
+// encountered, if any.
+func (set *CDBSet) Close() error {
+	var err error
+	for _, shard := range set.shards {
+		if closeErr := shard.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// ShardedWriter provides an API for creating a CDBSet record by record. Each
+// record is written to one of the underlying Writers based on the hash of its
+// key.
+//
+// Close or Freeze must be called to finalize the shards, or the resulting
+// files will be invalid.
+type ShardedWriter struct {
+	shards []*Writer
+}
+
+// CreateSet creates n shards at the paths given by formatting format with the
+// index of each shard, for example "users-%03d.cdb". Existing files are
+// overwritten. If one of the shards can't be created, the ones created so far
+// are aborted. If n is less than one, CreateSet returns os.ErrInvalid.
+func CreateSet(format string, n int) (*ShardedWriter, error) {
+	if n < 1 {
+		return nil, os.ErrInvalid
+	}
+
+	shards := make([]*Writer, 0, n)
+	for i := 0; i < n; i++ {
+		writer, err := Create(fmt.Sprintf(format, i))
+		if err != nil {
+			for _, shard := range shards {
+				shard.Abort()
+			}
+
+			return nil, err
+		}
+
+		shards = append(shards, writer)
+	}
+
+	return NewShardedWriter(shards)
+}
+
+// NewShardedWriter creates a ShardedWriter that writes to the given shards.
+// All of the shards must use the same hash function. If there are no shards,
+// NewShardedWriter returns os.ErrInvalid.
+func NewShardedWriter(shards []*Writer) (*ShardedWriter, error) {
+	if len(shards) == 0 {
+		return nil, os.ErrInvalid
+	}
+
+	return &ShardedWriter{shards: shards}, nil
+}
+
+// Put adds a key/value pair to the shard for the given key. If the shard would
+// exceed the size limit, Put returns ErrTooMuchData.
+func (sw *ShardedWriter) Put(key, value []byte) error {
+	shard := sw.shards[0]
+	hash := shard.hash(shard.opts.normalizeKey(key))
+	return sw.shards[shardFor(hash, len(sw.shards))].PutHashed(hash, key, value)
+}
+
+// putStored adds a record with a key that's already been normalized to the
+// shard for it, as Writer.putStored does.
+func (sw *ShardedWriter) putStored(key, value []byte, expires time.Time) error {
+	hash := sw.shards[0].hash(key)
+	return sw.shards[shardFor(hash, len(sw.shards))].putStored(key, value, expires)
+}
+
+// Close finalizes and closes all the shards. It returns the first error
+// encountered, if any.
+func (sw *ShardedWriter) Close() error {
+	var err error
+	for _, shard := range sw.shards {
+		if closeErr := shard.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// Freeze finalizes all the shards, then opens them for reads as a CDBSet.
+func (sw *ShardedWriter) Freeze() (*CDBSet, error) {
+	shards := make([]*CDB, 0, len(sw.shards))
+	for _, shard := range sw.shards {
+		db, err := shard.Freeze()
+		if err != nil {
+			return nil, err
+		}
+
+		shards = append(shards, db)
+	}
+
+	return NewSet(shards)
+}
+
+// shardFor picks a shard for the given hash. The low bits of the hash select
+// the hash table within a file, and the high bits the slot, so the hash is
+// mixed first; otherwise all the keys in a shard would end up crowded into
+// the same few tables.
+func shardFor(hash uint32, n int) int {
+	return int(fmix32(hash) % uint32(n))
+}
+
+// fmix32 is the murmur3 finalizer, which mixes the bits of a hash so that
+// every input bit affects every output bit.
+func fmix32(hash uint32) uint32 {
+	hash ^= hash >> 16
+	hash *= 0x85ebca6b
+	hash ^= hash >> 13
+	hash *= 0xc2b2ae35
+	hash ^= hash >> 16
+
+	return hash
+}
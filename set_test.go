@@ -0,0 +1,148 @@
+package cdb_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writer, err := cdb.CreateSet(filepath.Join(dir, "shard-%03d.cdb"), 4)
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		key := []byte(strconv.Itoa(i))
+		require.NoError(t, writer.Put(key, []byte("value "+string(key))))
+	}
+
+	require.NoError(t, writer.Close())
+
+	set, err := cdb.OpenSet(filepath.Join(dir, "shard-*.cdb"))
+	require.NoError(t, err)
+	defer set.Close()
+
+	require.Len(t, set.Shards(), 4)
+	for _, shard := range set.Shards() {
+		n, err := shard.Len()
+		require.NoError(t, err)
+		assert.True(t, n > 100, "shards should be balanced")
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := []byte(strconv.Itoa(i))
+		value, err := set.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, "value "+string(key), string(value))
+
+		other, err := set.Shard(key).Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, value, other)
+	}
+
+	value, err := set.Get([]byte("not in the set"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestSetFreeze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writer, err := cdb.CreateSet(filepath.Join(dir, "shard-%d.cdb"), 3)
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords[:len(expectedRecords)-1] {
+		require.NoError(t, writer.Put(record[0], record[1]))
+	}
+
+	set, err := writer.Freeze()
+	require.NoError(t, err)
+	defer set.Close()
+
+	for _, record := range expectedRecords {
+		value, err := set.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+}
+
+func TestOpenSetNoShards(t *testing.T) {
+	_, err := cdb.OpenSet("./test/does-not-exist-*.cdb")
+	assert.Equal(t, cdb.ErrNoShards, err)
+}
+
+func TestSetNoShards(t *testing.T) {
+	_, err := cdb.NewSet(nil)
+	assert.Equal(t, os.ErrInvalid, err)
+
+	_, err = cdb.NewShardedWriter(nil)
+	assert.Equal(t, os.ErrInvalid, err)
+
+	_, err = cdb.CreateSet("shard-%d.cdb", 0)
+	assert.Equal(t, os.ErrInvalid, err)
+}
+
+func TestCreateSetError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The first shard can be created, but not the second.
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "0"), 0755))
+	_, err = cdb.CreateSet(filepath.Join(dir, "%d", "shard.cdb"), 2)
+	require.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "0", "shard.cdb"))
+	assert.True(t, os.IsNotExist(err), "the first shard should be removed")
+}
+
+func TestSetGetBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writer, err := cdb.CreateSet(filepath.Join(dir, "shard-%d.cdb"), 3)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		key := []byte(strconv.Itoa(i))
+		require.NoError(t, writer.Put(key, []byte("value "+string(key))))
+	}
+
+	set, err := writer.Freeze()
+	require.NoError(t, err)
+	defer set.Close()
+
+	var keys [][]byte
+	for i := 150; i >= 0; i -= 3 {
+		keys = append(keys, []byte(strconv.Itoa(i)))
+	}
+
+	values, err := set.GetBatch(context.Background(), keys, 4)
+	require.NoError(t, err)
+	require.Len(t, values, len(keys))
+	for i, key := range keys {
+		if n, _ := strconv.Atoi(string(key)); n < 100 {
+			assert.Equal(t, "value "+string(key), string(values[i]))
+		} else {
+			assert.Nil(t, values[i])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = set.GetBatch(ctx, keys, 0)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,65 @@
+package cdb
+
+import (
+	"io"
+	"time"
+)
+
+// SlowRead describes a read from the underlying io.ReaderAt that took longer
+// than the threshold set with WithSlowReadThreshold.
+type SlowRead struct {
+	// Offset and Length are the position and size of the read.
+	Offset int64
+	Length int
+	// Duration is how long the read took.
+	Duration time.Duration
+	// Err is the error returned by the read, if any.
+	Err error
+}
+
+// WithSlowReadThreshold causes a CDB to call fn for every read from the
+// underlying io.ReaderAt that takes at least d, which helps when tracking
+// down tail latency with network-backed readers. fn is called synchronously,
+// after the read, from whichever goroutine made it, so it must be safe for
+// concurrent use.
+func WithSlowReadThreshold(d time.Duration, fn func(SlowRead)) Option {
+	return func(o *options) {
+		o.slowReadThreshold = d
+		o.slowRead = fn
+	}
+}
+
+// slowReader reports reads from an io.ReaderAt that take too long.
+type slowReader struct {
+	io.ReaderAt
+	threshold time.Duration
+	fn        func(SlowRead)
+}
+
+func (sr slowReader) ReadAt(b []byte, off int64) (int, error) {
+	start := time.Now()
+	n, err := sr.ReaderAt.ReadAt(b, off)
+	if elapsed := time.Since(start); elapsed >= sr.threshold {
+		sr.fn(SlowRead{Offset: off, Length: len(b), Duration: elapsed, Err: err})
+	}
+
+	return n, err
+}
+
+// ReadAtv times a batch of reads as a whole, reported with the offset of the
+// first read and their total length. If the underlying reader isn't a
+// ReaderAtv, each read is timed separately.
+func (sr slowReader) ReadAtv(bufs [][]byte, offsets []int64) (int, error) {
+	rv, ok := sr.ReaderAt.(ReaderAtv)
+	if !ok || len(bufs) == 0 {
+		return readEach(sr, bufs, offsets)
+	}
+
+	start := time.Now()
+	n, err := rv.ReadAtv(bufs, offsets)
+	if elapsed := time.Since(start); elapsed >= sr.threshold {
+		sr.fn(SlowRead{Offset: offsets[0], Length: totalLength(bufs), Duration: elapsed, Err: err})
+	}
+
+	return n, err
+}
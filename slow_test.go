@@ -0,0 +1,58 @@
+package cdb_test
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delayedReader delays reads past the index.
+type delayedReader struct {
+	io.ReaderAt
+	delay time.Duration
+}
+
+func (dr delayedReader) ReadAt(b []byte, off int64) (int, error) {
+	if off >= cdb.DataOffset {
+		time.Sleep(dr.delay)
+	}
+
+	return dr.ReaderAt.ReadAt(b, off)
+}
+
+func TestSlowReadThreshold(t *testing.T) {
+	f, err := os.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var reads []cdb.SlowRead
+	db, err := cdb.New(delayedReader{f, 10 * time.Millisecond}, nil,
+		cdb.WithSlowReadThreshold(5*time.Millisecond, func(read cdb.SlowRead) {
+			mu.Lock()
+			defer mu.Unlock()
+			reads = append(reads, read)
+		}))
+	require.NoError(t, err)
+	defer f.Close()
+
+	// Reading the index isn't slow.
+	assert.Empty(t, reads)
+
+	value, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(value))
+
+	require.NotEmpty(t, reads)
+	for _, read := range reads {
+		assert.True(t, read.Offset >= cdb.DataOffset)
+		assert.True(t, read.Length > 0)
+		assert.True(t, read.Duration >= 5*time.Millisecond)
+		assert.NoError(t, read.Err)
+	}
+}
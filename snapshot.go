@@ -0,0 +1,125 @@
+package cdb
+
+import (
+	"io"
+	"math"
+	"os"
+)
+
+// Snapshot flushes the records written so far, and returns a read-only view
+// of them without finalizing the database, so that a long build can check its
+// lookups partway through. The hash tables for the view are built in memory.
+// The Writer can carry on adding records afterwards, but the view only ever
+// sees the ones it started with.
+//
+// The view reads records from the underlying stream, which must be an
+// io.ReaderAt, as for Freeze; if it isn't, Snapshot returns os.ErrInvalid.
+// Records removed with Delete aren't found by Get, but are still visited when
+// iterating, since they're only removed from the file when the database is
+// finalized. A view mustn't be used once the Writer is finalized or aborted.
+func (cdb *Writer) Snapshot() (*CDB, error) {
+	err := cdb.checkWritable()
+	if err != nil {
+		return nil, err
+	}
+
+	readerAt, ok := cdb.writer.(io.ReaderAt)
+	if !ok {
+		return nil, os.ErrInvalid
+	}
+
+	err = cdb.bufferedWriter.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	var index index
+	dataEnd := cdb.bufferedOffset
+	var tables []byte
+	order := cdb.opts.order()
+	for i, entries := range cdb.entries {
+		length := cdb.tableLength(len(entries))
+		offset := dataEnd + int64(len(tables))
+		if offset+8*int64(length) > math.MaxUint32 {
+			return nil, ErrTooMuchData
+		}
+
+		index[i] = table{offset: uint32(offset), length: length}
+		buf := make([]byte, 8*length)
+		for slot, entry := range layoutTable(entries, length) {
+			order.PutUint32(buf[slot*8:], entry.hash)
+			order.PutUint32(buf[slot*8+4:], entry.offset)
+		}
+
+		tables = append(tables, buf...)
+	}
+
+	reader := &snapshotReader{
+		index:   index.marshal(order),
+		data:    readerAt,
+		dataEnd: dataEnd,
+		tables:  tables,
+	}
+
+	db := &CDB{reader: reader, hash: cdb.hash, opts: cdb.opts, customHash: cdb.customHash}
+	err = db.init(&index)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cdb.metadata) > 0 {
+		db.metadata = make(map[string]string, len(cdb.metadata))
+		for k, v := range cdb.metadata {
+			db.metadata[k] = v
+		}
+	}
+
+	return db, nil
+}
+
+// snapshotReader presents the data section written so far as a complete
+// database, with the index and hash tables for a snapshot held in memory.
+type snapshotReader struct {
+	index   []byte
+	data    io.ReaderAt
+	dataEnd int64
+	tables  []byte
+}
+
+func (r *snapshotReader) ReadAt(b []byte, off int64) (int, error) {
+	read := 0
+	for len(b) > 0 {
+		var n int
+		switch {
+		case off < 0:
+			return read, os.ErrInvalid
+		case off < indexSize:
+			n = copy(b, r.index[off:])
+		case off < r.dataEnd:
+			chunk := b
+			if int64(len(chunk)) > r.dataEnd-off {
+				chunk = chunk[:r.dataEnd-off]
+			}
+
+			var err error
+			n, err = r.data.ReadAt(chunk, off)
+			if err != nil {
+				return read + n, err
+			}
+		case off-r.dataEnd < int64(len(r.tables)):
+			n = copy(b, r.tables[off-r.dataEnd:])
+		default:
+			return read, io.EOF
+		}
+
+		read += n
+		off += int64(n)
+		b = b[n:]
+	}
+
+	return read, nil
+}
+
+func (r *snapshotReader) Size() int64 {
+	return r.dataEnd + int64(len(r.tables))
+}
@@ -0,0 +1,119 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value "+strconv.Itoa(i))))
+	}
+
+	require.NoError(t, writer.SetMetadata("stage", "first"))
+	snap, err := writer.Snapshot()
+	require.NoError(t, err)
+
+	// Records written afterwards aren't in the snapshot.
+	for i := 100; i < 200; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value "+strconv.Itoa(i))))
+	}
+
+	require.NoError(t, writer.Delete([]byte("1")))
+
+	v, err := snap.Get([]byte("42"))
+	require.NoError(t, err)
+	assert.Equal(t, "value 42", string(v))
+
+	v, err = snap.Get([]byte("1"))
+	require.NoError(t, err)
+	assert.Equal(t, "value 1", string(v))
+
+	v, err = snap.Get([]byte("142"))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	n, err := snap.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, map[string]string{"stage": "first"}, snap.Metadata())
+
+	count := 0
+	iter := snap.Iter()
+	for iter.Next() {
+		assert.Equal(t, "value "+string(iter.Key()), string(iter.Value()))
+		count++
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, 100, count)
+
+	// A second snapshot sees the new records, and not the deleted one.
+	snap, err = writer.Snapshot()
+	require.NoError(t, err)
+
+	v, err = snap.Get([]byte("142"))
+	require.NoError(t, err)
+	assert.Equal(t, "value 142", string(v))
+
+	v, err = snap.Get([]byte("1"))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	defer db.Close()
+
+	n, err = db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 199, n)
+
+	_, err = writer.Snapshot()
+	assert.Equal(t, cdb.ErrFinalized, err)
+}
+
+func TestSnapshotEmpty(t *testing.T) {
+	writer := cdb.NewMem()
+	snap, err := writer.Snapshot()
+	require.NoError(t, err)
+
+	v, err := snap.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	iter := snap.Iter()
+	assert.False(t, iter.Next())
+	assert.NoError(t, iter.Err())
+}
+
+func TestSnapshotAligned(t *testing.T) {
+	writer := cdb.NewMem(cdb.WithAlignment(64))
+	for i := 0; i < 50; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value "+strconv.Itoa(i))))
+	}
+
+	snap, err := writer.Snapshot()
+	require.NoError(t, err)
+
+	count := 0
+	err = snap.EachRecord(func(rec cdb.Record) error {
+		assert.Zero(t, rec.Offset()%64)
+		count++
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 50, count)
+}
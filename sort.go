@@ -0,0 +1,255 @@
+package cdb
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// DefaultSortMemory is the memory budget used by SortThenBuild.
+const DefaultSortMemory = 256 << 20
+
+// The approximate per-record memory overhead of a run, on top of the size of
+// the key and value.
+const sortRecordOverhead = 48
+
+// RecordSource is a sequential source of key/value pairs. Iterator implements
+// RecordSource.
+type RecordSource interface {
+	// Next advances to the next record, returning false at the end of the
+	// source or on error.
+	Next() bool
+	// Key returns the current key.
+	Key() []byte
+	// Value returns the current value.
+	Value() []byte
+	// Err returns the error that stopped iteration, if any.
+	Err() error
+}
+
+// Sorter sorts records by key before writing them to a database. Records are
+// buffered in memory up to MemoryBudget bytes, then sorted and spilled to
+// temporary files, which are merged at the end. This makes it possible to sort
+// inputs much larger than memory.
+//
+// Records with equal keys are kept in the order they were read, with inputs
+// read in order.
+type Sorter struct {
+	// TempDir is the directory for temporary files. If empty, the default
+	// directory for temporary files is used.
+	TempDir string
+	// MemoryBudget is the approximate number of bytes of records to buffer
+	// before spilling to disk. If zero, DefaultSortMemory is used.
+	MemoryBudget int64
+}
+
+// SortThenBuild reads every record from inputs, sorts them by key using
+// temporary files in tmpDir, and then writes them to w. It uses a memory
+// budget of DefaultSortMemory; to change it, use a Sorter.
+//
+// SortThenBuild does not finalize w; the caller must call Close or Freeze once
+// it returns.
+func SortThenBuild(inputs []RecordSource, tmpDir string, w *Writer) error {
+	s := &Sorter{TempDir: tmpDir}
+	return s.Build(inputs, w)
+}
+
+// Build reads every record from inputs, sorts them by key, and then writes
+// them to w. It does not finalize w.
+func (s *Sorter) Build(inputs []RecordSource, w *Writer) error {
+	budget := s.MemoryBudget
+	if budget <= 0 {
+		budget = DefaultSortMemory
+	}
+
+	var runs []*os.File
+	defer func() {
+		for _, f := range runs {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	var records [][2][]byte
+	var size int64
+	for _, input := range inputs {
+		for input.Next() {
+			key := append([]byte(nil), input.Key()...)
+			value := append([]byte(nil), input.Value()...)
+			records = append(records, [2][]byte{key, value})
+
+			size += int64(len(key) + len(value) + sortRecordOverhead)
+			if size >= budget {
+				f, err := s.spill(records)
+				if f != nil {
+					runs = append(runs, f)
+				}
+
+				if err != nil {
+					return err
+				}
+
+				records = records[:0]
+				size = 0
+			}
+		}
+
+		if err := input.Err(); err != nil {
+			return err
+		}
+	}
+
+	// If everything fit in memory, we can skip the merge entirely.
+	if len(runs) == 0 {
+		sortRecords(records)
+		for _, record := range records {
+			err := w.Put(record[0], record[1])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if len(records) > 0 {
+		f, err := s.spill(records)
+		if f != nil {
+			runs = append(runs, f)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return mergeRuns(runs, w)
+}
+
+// spill sorts the records and writes them to a new temporary file, which is
+// left open and positioned at the start.
+func (s *Sorter) spill(records [][2][]byte) (*os.File, error) {
+	sortRecords(records)
+
+	f, err := ioutil.TempFile(s.TempDir, "cdb-sort")
+	if err != nil {
+		return nil, err
+	}
+
+	bw := bufio.NewWriterSize(f, 65536)
+	for _, record := range records {
+		err = writeTuple(bw, binary.LittleEndian, uint32(len(record[0])), uint32(len(record[1])))
+		if err == nil {
+			_, err = bw.Write(record[0])
+		}
+
+		if err == nil {
+			_, err = bw.Write(record[1])
+		}
+
+		if err != nil {
+			return f, err
+		}
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return f, err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	return f, err
+}
+
+func sortRecords(records [][2][]byte) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return bytes.Compare(records[i][0], records[j][0]) < 0
+	})
+}
+
+// run is a sorted temporary file being merged.
+type run struct {
+	index int
+	r     *bufio.Reader
+	key   []byte
+	value []byte
+}
+
+func (r *run) next() (bool, error) {
+	var header [8]byte
+	_, err := io.ReadFull(r.r, header[:])
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	keyLength := binary.LittleEndian.Uint32(header[:4])
+	valueLength := binary.LittleEndian.Uint32(header[4:])
+	buf := make([]byte, keyLength+valueLength)
+	_, err = io.ReadFull(r.r, buf)
+	if err != nil {
+		return false, err
+	}
+
+	r.key = buf[:keyLength]
+	r.value = buf[keyLength:]
+	return true, nil
+}
+
+// runHeap orders runs by their current key, breaking ties by the order the
+// runs were written in, so that the merge is stable.
+type runHeap []*run
+
+func (h runHeap) Len() int      { return len(h) }
+func (h runHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h runHeap) Less(i, j int) bool {
+	c := bytes.Compare(h[i].key, h[j].key)
+	return c < 0 || (c == 0 && h[i].index < h[j].index)
+}
+
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func mergeRuns(files []*os.File, w *Writer) error {
+	h := make(runHeap, 0, len(files))
+	for i, f := range files {
+		r := &run{index: i, r: bufio.NewReaderSize(f, 65536)}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		} else if ok {
+			h = append(h, r)
+		}
+	}
+
+	heap.Init(&h)
+	for len(h) > 0 {
+		r := h[0]
+		err := w.Put(r.key, r.value)
+		if err != nil {
+			return err
+		}
+
+		ok, err := r.next()
+		if err != nil {
+			return err
+		} else if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+
+	return nil
+}
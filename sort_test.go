@@ -0,0 +1,93 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceSource is a RecordSource over a slice of records.
+type sliceSource struct {
+	records [][][]byte
+	pos     int
+}
+
+func (s *sliceSource) Next() bool {
+	s.pos++
+	return s.pos <= len(s.records)
+}
+
+func (s *sliceSource) Key() []byte   { return s.records[s.pos-1][0] }
+func (s *sliceSource) Value() []byte { return s.records[s.pos-1][1] }
+func (s *sliceSource) Err() error    { return nil }
+
+func testSortThenBuild(t *testing.T, sorter *cdb.Sorter) {
+	src, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	var generated [][][]byte
+	for i := 500; i > 0; i-- {
+		key := []byte(strconv.Itoa(i))
+		generated = append(generated, [][]byte{key, []byte("value " + string(key))})
+	}
+
+	// Add a duplicate of an existing key, which should sort after the
+	// original.
+	generated = append(generated, [][]byte{[]byte("foo"), []byte("second")})
+
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+
+	inputs := []cdb.RecordSource{src.Iter(), &sliceSource{records: generated}}
+	require.NoError(t, sorter.Build(inputs, writer))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	var keys []string
+	var fooValues []string
+	iter := db.Iter()
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+		if string(iter.Key()) == "foo" {
+			fooValues = append(fooValues, string(iter.Value()))
+		}
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Len(t, keys, len(expectedRecords)-1+len(generated))
+	assert.True(t, sort.StringsAreSorted(keys))
+	assert.Equal(t, []string{"bar", "second"}, fooValues)
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+}
+
+func TestSortThenBuildInMemory(t *testing.T) {
+	testSortThenBuild(t, &cdb.Sorter{})
+}
+
+func TestSortThenBuildSpills(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testSortThenBuild(t, &cdb.Sorter{TempDir: dir, MemoryBudget: 1024})
+
+	leftover, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, leftover)
+}
@@ -0,0 +1,63 @@
+package cdb
+
+import "os"
+
+// Split copies every record in src into n new shards, routing each record by
+// the hash of its key in the same way as ShardedWriter, so that the shards
+// can be read as a CDBSet. This is useful for a database that's outgrown the
+// size limit for a single file, or that will soon. newWriter is called with
+// the index of each shard, from zero, to create its Writer; for example, it
+// could call Create with a path like "users-%03d.cdb".
+//
+// Like Convert, Split streams the records one at a time. Once they've all
+// been copied, the shards are finalized and closed, and the first error from
+// closing them is returned, if any. If anything fails before that, every shard
+// created so far is aborted. If n is less than one, Split returns
+// os.ErrInvalid.
+//
+// Keys are copied as they're stored in src, without normalizing them again,
+// so the shard writers should use the same key options as src, such as
+// WithKeyNormalizer, WithKeyHMAC, or WithHashedKeys. Records keep their
+// expiration times, if src and the shards both use WithExpiry.
+func Split(src *CDB, n int, newWriter func(i int) (*Writer, error)) error {
+	if n < 1 {
+		return os.ErrInvalid
+	}
+
+	shards := make([]*Writer, 0, n)
+	abort := func(err error) error {
+		for _, shard := range shards {
+			shard.Abort()
+		}
+
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		shard, err := newWriter(i)
+		if err != nil {
+			return abort(err)
+		}
+
+		shards = append(shards, shard)
+	}
+
+	sw, err := NewShardedWriter(shards)
+	if err != nil {
+		return abort(err)
+	}
+
+	iter := src.Iter()
+	for iter.Next() {
+		err := sw.putStored(iter.Key(), iter.Value(), iter.expires)
+		if err != nil {
+			return abort(err)
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return abort(err)
+	}
+
+	return sw.Close()
+}
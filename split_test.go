@@ -0,0 +1,134 @@
+package cdb_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writer := cdb.NewMem()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value "+strconv.Itoa(i))))
+	}
+
+	src, err := writer.Freeze()
+	require.NoError(t, err)
+
+	format := filepath.Join(dir, "shard-%02d.cdb")
+	err = cdb.Split(src, 4, func(i int) (*cdb.Writer, error) {
+		return cdb.Create(fmt.Sprintf(format, i))
+	})
+	require.NoError(t, err)
+
+	set, err := cdb.OpenSet(filepath.Join(dir, "shard-*.cdb"))
+	require.NoError(t, err)
+	defer set.Close()
+
+	total := 0
+	for _, shard := range set.Shards() {
+		n, err := shard.Len()
+		require.NoError(t, err)
+		assert.True(t, n > 0)
+		total += n
+	}
+
+	assert.Equal(t, 1000, total)
+	for i := 0; i < 1000; i++ {
+		v, err := set.Get([]byte(strconv.Itoa(i)))
+		require.NoError(t, err)
+		assert.Equal(t, "value "+strconv.Itoa(i), string(v))
+	}
+}
+
+func TestSplitKeyHMAC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	hmac := cdb.WithKeyHMAC([]byte("secret"))
+	writer := cdb.NewMem(hmac)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value "+strconv.Itoa(i))))
+	}
+
+	src, err := writer.Freeze()
+	require.NoError(t, err)
+
+	// The stored keys are already HMACs, so they shouldn't be hashed again.
+	format := filepath.Join(dir, "shard-%02d.cdb")
+	err = cdb.Split(src, 3, func(i int) (*cdb.Writer, error) {
+		return cdb.Create(fmt.Sprintf(format, i), hmac)
+	})
+	require.NoError(t, err)
+
+	set, err := cdb.OpenSet(filepath.Join(dir, "shard-*.cdb"), hmac)
+	require.NoError(t, err)
+	defer set.Close()
+
+	for i := 0; i < 100; i++ {
+		v, err := set.Get([]byte(strconv.Itoa(i)))
+		require.NoError(t, err)
+		assert.Equal(t, "value "+strconv.Itoa(i), string(v))
+	}
+}
+
+func TestSplitKeepsExpiry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	src, expires := newExpiringDB(t)
+	format := filepath.Join(dir, "shard-%02d.cdb")
+	err = cdb.Split(src, 2, func(i int) (*cdb.Writer, error) {
+		return cdb.Create(fmt.Sprintf(format, i), cdb.WithExpiry())
+	})
+	require.NoError(t, err)
+
+	set, err := cdb.OpenSet(filepath.Join(dir, "shard-*.cdb"), cdb.WithExpiry())
+	require.NoError(t, err)
+	defer set.Close()
+
+	assertExpiry(t, set.Shard([]byte("forever")), "forever", time.Time{})
+	assertExpiry(t, set.Shard([]byte("expiring")), "expiring", expires)
+}
+
+func TestSplitError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	src, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer src.Close()
+
+	errNoMore := errors.New("no more shards")
+	err = cdb.Split(src, 3, func(i int) (*cdb.Writer, error) {
+		if i == 2 {
+			return nil, errNoMore
+		}
+
+		return cdb.Create(filepath.Join(dir, strconv.Itoa(i)))
+	})
+	assert.Equal(t, errNoMore, err)
+
+	// The shards that were created are removed.
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, infos)
+
+	assert.Equal(t, os.ErrInvalid, cdb.Split(src, 0, nil))
+}
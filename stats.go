@@ -0,0 +1,136 @@
+package cdb
+
+// The number of slots read at a time when scanning a hash table.
+const scanChunkSlots = 4096
+
+// Stats describes the contents and hash table layout of a database.
+type Stats struct {
+	// Records is the number of records in the database.
+	Records int
+	// DataSize is the size of the data section, in bytes, including record
+	// headers.
+	DataSize int64
+	// MaxProbeLength is the number of slots that need to be read to find the
+	// hardest-to-reach record in the database.
+	MaxProbeLength int
+	// Tables contains statistics for each of the 256 hash tables.
+	Tables [256]TableStats
+}
+
+// TableStats describes a single hash table.
+type TableStats struct {
+	// Slots is the length of the hash table.
+	Slots int
+	// Entries is the number of filled slots in the hash table.
+	Entries int
+	// MaxProbeLength is the number of slots that need to be read to find the
+	// hardest-to-reach record in this table. A record in its ideal slot has a
+	// probe length of one.
+	MaxProbeLength int
+}
+
+// FillFactor returns the fraction of the table's slots that are in use.
+func (ts TableStats) FillFactor() float64 {
+	if ts.Slots == 0 {
+		return 0
+	}
+
+	return float64(ts.Entries) / float64(ts.Slots)
+}
+
+// Len returns the number of records in the database. It reads each of the
+// hash tables, but not the records themselves.
+func (cdb *CDB) Len() (int, error) {
+	err := cdb.acquire(opScan)
+	if err != nil {
+		return 0, err
+	}
+	defer cdb.release(opScan)
+
+	n := 0
+	for _, table := range cdb.index {
+		err := cdb.scanTable(table, func(slot, hash, offset uint32) {
+			n++
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// DataSize returns the size of the data section of the database, in bytes.
+// This is the file size minus the index at the head of the file and the hash
+// tables at the end.
+func (cdb *CDB) DataSize() int64 {
+	return int64(cdb.index[0].offset) - indexSize
+}
+
+// Stats reads the hash tables and computes statistics about the database. It
+// does not read the records themselves.
+func (cdb *CDB) Stats() (Stats, error) {
+	stats := Stats{DataSize: cdb.DataSize()}
+	err := cdb.acquire(opScan)
+	if err != nil {
+		return stats, err
+	}
+	defer cdb.release(opScan)
+
+	for i, table := range cdb.index {
+		ts := TableStats{Slots: int(table.length)}
+
+		err := cdb.scanTable(table, func(slot, hash, offset uint32) {
+			ideal := (hash >> 8) % table.length
+			probeLength := int((slot+table.length-ideal)%table.length) + 1
+
+			ts.Entries++
+			if probeLength > ts.MaxProbeLength {
+				ts.MaxProbeLength = probeLength
+			}
+		})
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Records += ts.Entries
+		if ts.MaxProbeLength > stats.MaxProbeLength {
+			stats.MaxProbeLength = ts.MaxProbeLength
+		}
+
+		stats.Tables[i] = ts
+	}
+
+	return stats, nil
+}
+
+// scanTable reads the given hash table in chunks, and calls fn for each slot
+// that isn't empty.
+func (cdb *CDB) scanTable(table table, fn func(slot, hash, offset uint32)) error {
+	order := cdb.opts.order()
+	buf := make([]byte, 8*scanChunkSlots)
+	for start := uint32(0); start < table.length; start += scanChunkSlots {
+		n := table.length - start
+		if n > scanChunkSlots {
+			n = scanChunkSlots
+		}
+
+		chunk := buf[:8*n]
+		err := cdb.readTables(chunk, table.offset+(8*start))
+		if err != nil {
+			return err
+		}
+
+		for i := uint32(0); i < n; i++ {
+			hash := order.Uint32(chunk[i*8:])
+			offset := order.Uint32(chunk[i*8+4:])
+			if offset == 0 {
+				continue
+			}
+
+			fn(start+i, hash, offset)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package cdb_test
+
+import (
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLen(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, len(expectedRecords)-1, n)
+}
+
+func TestDataSize(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	var expected int64
+	for _, record := range expectedRecords[:len(expectedRecords)-1] {
+		expected += int64(8 + len(record[0]) + len(record[1]))
+	}
+
+	assert.Equal(t, expected, db.DataSize())
+}
+
+func TestStats(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	stats, err := db.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, len(expectedRecords)-1, stats.Records)
+	assert.Equal(t, db.DataSize(), stats.DataSize)
+	assert.True(t, stats.MaxProbeLength >= 1)
+
+	entries := 0
+	for _, ts := range stats.Tables {
+		entries += ts.Entries
+		if ts.Slots == 0 {
+			assert.Equal(t, 0.0, ts.FillFactor())
+		} else {
+			assert.Equal(t, 0.5, ts.FillFactor())
+			assert.True(t, ts.MaxProbeLength <= ts.Slots)
+		}
+	}
+
+	assert.Equal(t, stats.Records, entries)
+}
@@ -0,0 +1,74 @@
+package cdb
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// NewStreamWriter creates a Writer that writes the finished database to dst
+// as a single sequential stream, so that dst doesn't need to support seeking:
+// it can be a pipe, an HTTP response, a gzip.Writer, or an upload to object
+// storage.
+//
+// Since the index at the head of the file depends on every record, the
+// database is staged in a temporary file in tmpDir (or the default directory
+// for temporary files, if tmpDir is empty), and copied to dst once it's
+// finalized. The temporary file is removed when the Writer is closed. If dst
+// is an io.Closer, it's closed once the copy is done.
+//
+// If Freeze is used instead of Close, the returned CDB reads from the
+// temporary file, which is removed when the CDB is closed.
+//
+// If hash is nil, it will default to the CDB hash function.
+func NewStreamWriter(dst io.Writer, tmpDir string, hash func([]byte) uint32, opts ...Option) (*Writer, error) {
+	f, err := ioutil.TempFile(tmpDir, "cdb-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	tmp := &tempFile{f}
+	writer, err := NewWriter(tmp, hash, opts...)
+	if err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	writer.sink = dst
+	return writer, nil
+}
+
+// NewWriterBuffered is like NewStreamWriter, with the default hash function
+// and directory for temporary files. It's for destinations that can only be
+// written to sequentially, which NewWriter rejects, such as a gzip.Writer or
+// a network connection.
+func NewWriterBuffered(dst io.Writer, opts ...Option) (*Writer, error) {
+	return NewStreamWriter(dst, "", nil, opts...)
+}
+
+// streamOut copies the finalized database, which is size bytes long, to the
+// sink.
+func (cdb *Writer) streamOut(size int64) error {
+	section := io.NewSectionReader(cdb.writer.(io.ReaderAt), 0, size)
+	_, err := io.Copy(cdb.sink, section)
+	if err != nil {
+		return err
+	}
+
+	if closer, ok := cdb.sink.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// tempFile is a temporary file that is removed when it's closed.
+type tempFile struct {
+	*os.File
+}
+
+func (tf *tempFile) Close() error {
+	err := tf.File.Close()
+	os.Remove(tf.Name())
+	return err
+}
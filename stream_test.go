@@ -0,0 +1,103 @@
+package cdb_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closeRecorder is a non-seekable sink that records whether it was closed.
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (cr *closeRecorder) Close() error {
+	cr.closed = true
+	return nil
+}
+
+func TestStreamWriter(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-cdb-stream")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	sink := &closeRecorder{}
+	writer, err := cdb.NewStreamWriter(sink, tmpDir, nil)
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, writer.Put(record[0], record[1]))
+		}
+	}
+
+	require.NoError(t, writer.Close())
+	assert.True(t, sink.closed)
+
+	files, err := ioutil.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, files, "the temporary file should be removed")
+
+	expected, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+	assert.Equal(t, expected, sink.Bytes())
+}
+
+func TestStreamWriterFreeze(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test-cdb-stream")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	var sink bytes.Buffer
+	writer, err := cdb.NewStreamWriter(&sink, tmpDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	value, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(value))
+
+	streamed, err := cdb.New(bytes.NewReader(sink.Bytes()), nil)
+	require.NoError(t, err)
+	value, err = streamed.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(value))
+
+	require.NoError(t, db.Close())
+	files, err := ioutil.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, files, "the temporary file should be removed")
+}
+
+func TestWriterBuffered(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	writer, err := cdb.NewWriterBuffered(gz, cdb.WithHeader())
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Close())
+
+	r, err := gzip.NewReader(&compressed)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	db, err := cdb.New(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+	value, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(value))
+
+	_, ok := db.Header()
+	assert.True(t, ok)
+}
@@ -0,0 +1,88 @@
+package cdb
+
+import (
+	"io"
+	"unsafe"
+)
+
+// StringMap wraps a CDB for lookups with string keys and values, in the style
+// of dbm, which suits databases used for configuration and other lookup
+// tables. Keys are passed to the database without being copied, and values
+// are returned without a second copy, unless the database has a decoder set
+// with WithValueDecoder.
+type StringMap struct {
+	db *CDB
+}
+
+// OpenStringMap opens an existing database at the given path, as a
+// StringMap.
+func OpenStringMap(path string, opts ...Option) (*StringMap, error) {
+	db, err := Open(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStringMap(db), nil
+}
+
+// NewStringMap wraps an open database as a StringMap.
+func NewStringMap(db *CDB) *StringMap {
+	return &StringMap{db: db}
+}
+
+// GetString returns the value for a given key, and whether it was found.
+func (m *StringMap) GetString(key string) (string, bool, error) {
+	value, err := m.db.Get(stringBytes(key))
+	if err != nil || value == nil {
+		return "", false, err
+	}
+
+	// A decoder registered with WithValueDecoder may return a buffer it goes
+	// on to reuse, so its values are copied. Otherwise, the value was
+	// allocated by this call to Get, and nothing else refers to it, so it can
+	// be used as the string's memory.
+	if m.db.opts.decoder != nil {
+		return string(value), true, nil
+	}
+
+	return bytesString(value), true, nil
+}
+
+// Has returns true if the given key is in the database. It doesn't read or
+// allocate the value, unless the database is compressed or encrypted.
+func (m *StringMap) Has(key string) (bool, error) {
+	_, found, err := m.db.GetInto(stringBytes(key), nil)
+	if err == io.ErrShortBuffer {
+		return true, nil
+	}
+
+	return found, err
+}
+
+// DB returns the underlying database.
+func (m *StringMap) DB() *CDB {
+	return m.db
+}
+
+// Close closes the underlying database.
+func (m *StringMap) Close() error {
+	return m.db.Close()
+}
+
+// PutString adds a key/value pair to the database, like Put, but with a
+// string key and value.
+func (cdb *Writer) PutString(key, value string) error {
+	return cdb.Put(stringBytes(key), stringBytes(value))
+}
+
+// stringBytes returns the bytes of s without copying them. The result must
+// not be modified.
+func stringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+// bytesString returns b as a string without copying it. b must not be
+// modified afterwards.
+func bytesString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
@@ -0,0 +1,81 @@
+package cdb_test
+
+import (
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringMap(t *testing.T) {
+	writer := cdb.NewMem()
+	require.NoError(t, writer.PutString("listen", ":8080"))
+	require.NoError(t, writer.PutString("empty", ""))
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	m := cdb.NewStringMap(db)
+	value, ok, err := m.GetString("listen")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, ":8080", value)
+
+	value, ok, err = m.GetString("empty")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "", value)
+
+	_, ok, err = m.GetString("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	for key, expected := range map[string]bool{"listen": true, "empty": true, "missing": false} {
+		ok, err := m.Has(key)
+		require.NoError(t, err)
+		assert.Equal(t, expected, ok, key)
+	}
+}
+
+func TestStringMapAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations aren't predictable with the race detector")
+	}
+
+	m, err := cdb.OpenStringMap("./test/test.cdb")
+	require.NoError(t, err)
+	defer m.Close()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		m.Has("foo")
+	})
+	assert.Equal(t, 0.0, allocs)
+
+	// Just the value itself.
+	allocs = testing.AllocsPerRun(100, func() {
+		m.GetString("foo")
+	})
+	assert.Equal(t, 1.0, allocs)
+}
+
+func TestStringMapSharedDecoderBuffer(t *testing.T) {
+	// A decoder that reuses its buffer for every value.
+	var buf []byte
+	decoder := func(key, raw []byte) ([]byte, error) {
+		buf = append(buf[:0], raw...)
+		return buf, nil
+	}
+
+	writer := cdb.NewMem(cdb.WithValueDecoder(decoder))
+	require.NoError(t, writer.PutString("a", "first"))
+	require.NoError(t, writer.PutString("b", "other"))
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	m := cdb.NewStringMap(db)
+	a, _, err := m.GetString("a")
+	require.NoError(t, err)
+	_, _, err = m.GetString("b")
+	require.NoError(t, err)
+	assert.Equal(t, "first", a)
+}
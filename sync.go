@@ -0,0 +1,125 @@
+package cdb
+
+// A syncer is a stream that can be flushed to stable storage, such as an
+// *os.File.
+type syncer interface {
+	Sync() error
+}
+
+// A SyncPolicy controls when a Writer syncs the database to stable storage.
+// Syncing only happens if the stream has a Sync method, as *os.File does.
+type SyncPolicy int64
+
+const (
+	// SyncOnClose syncs the database once it's finalized, before Close or
+	// Freeze returns. It's the default.
+	SyncOnClose SyncPolicy = 0
+	// NoSync never syncs the database, leaving it to the operating system to
+	// write it out eventually. A database that's been closed can be lost or
+	// left corrupt if the machine loses power.
+	NoSync SyncPolicy = -1
+)
+
+// SyncEvery syncs the records written so far every time another n bytes have
+// been added, as well as when the database is finalized. That bounds the
+// amount of data the operating system has waiting to be written, which keeps
+// the final sync short. If n isn't positive, it's the same as SyncOnClose.
+func SyncEvery(n int64) SyncPolicy {
+	if n <= 0 {
+		return SyncOnClose
+	}
+
+	return SyncPolicy(n)
+}
+
+// WithSyncPolicy sets when a Writer syncs the database to stable storage.
+// Without this option, it uses SyncOnClose.
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(o *options) {
+		o.syncPolicy = policy
+	}
+}
+
+// WithConcurrentSync causes a Writer to sync the data section to stable
+// storage while it writes the hash tables, when it's finalized, and then to
+// sync the tables and index once it's done; the finished database is durable
+// once Close or Freeze returns. Overlapping the sync of the data section,
+// which is most of the file, with building the tables hides much of its cost
+// on slow disks. It has no effect if the stream doesn't have a Sync method.
+func WithConcurrentSync() Option {
+	return func(o *options) {
+		o.concurrentSync = true
+	}
+}
+
+// Sync writes any buffered records to the underlying stream and, if the
+// stream has a Sync method (as *os.File does), syncs it to stable storage. The
+// database is still incomplete until it's finalized with Close or Freeze, but
+// the records written so far are durable, and visible to other processes
+// watching the file's size.
+func (cdb *Writer) Sync() error {
+	cdb.unsynced = 0
+	if cdb.bufferedWriter != nil {
+		err := cdb.bufferedWriter.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s, ok := cdb.writer.(syncer); ok {
+		return s.Sync()
+	}
+
+	return nil
+}
+
+// startSync flushes the buffered records and starts syncing them in the
+// background, if WithConcurrentSync is set. The returned function waits for
+// the sync to finish.
+func (cdb *Writer) startSync() (func() error, error) {
+	s, ok := cdb.writer.(syncer)
+	if !cdb.opts.concurrentSync || !ok {
+		return func() error { return nil }, nil
+	}
+
+	err := cdb.bufferedWriter.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Sync()
+	}()
+
+	return func() error { return <-done }, nil
+}
+
+// finishSync syncs the rest of the file, once it's finalized, unless the
+// sync policy is NoSync and WithConcurrentSync isn't set. A stream writer's
+// temporary file is never synced, since it's copied elsewhere and removed.
+func (cdb *Writer) finishSync() error {
+	s, ok := cdb.writer.(syncer)
+	if !ok || cdb.sink != nil {
+		return nil
+	} else if cdb.opts.concurrentSync || cdb.opts.syncPolicy != NoSync {
+		return s.Sync()
+	}
+
+	return nil
+}
+
+// syncEvery counts a newly written record towards the next sync, and syncs
+// the file if the policy set by SyncEvery calls for it.
+func (cdb *Writer) syncEvery(entrySize int64) error {
+	if cdb.opts.syncPolicy <= 0 {
+		return nil
+	}
+
+	cdb.unsynced += entrySize
+	if cdb.unsynced < int64(cdb.opts.syncPolicy) {
+		return nil
+	}
+
+	return cdb.Sync()
+}
@@ -0,0 +1,112 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncCounter counts calls to Sync.
+type syncCounter struct {
+	*os.File
+	syncs int32
+}
+
+func (sc *syncCounter) Sync() error {
+	atomic.AddInt32(&sc.syncs, 1)
+	return sc.File.Sync()
+}
+
+func TestSync(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	sc := &syncCounter{File: f}
+	writer, err := cdb.NewWriter(sc, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Sync())
+	assert.EqualValues(t, 1, sc.syncs)
+
+	// The record should be visible in the file.
+	info, err := f.Stat()
+	require.NoError(t, err)
+	assert.EqualValues(t, cdb.DataOffset+8+3+3, info.Size())
+
+	require.NoError(t, writer.Close())
+	assert.EqualValues(t, 2, sc.syncs, "Close should sync by default")
+}
+
+func TestNoSync(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	sc := &syncCounter{File: f}
+	writer, err := cdb.NewWriter(sc, nil, cdb.WithSyncPolicy(cdb.NoSync))
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Close())
+	assert.EqualValues(t, 0, sc.syncs)
+}
+
+func TestSyncEvery(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	sc := &syncCounter{File: f}
+	writer, err := cdb.NewWriter(sc, nil, cdb.WithSyncPolicy(cdb.SyncEvery(100)))
+	require.NoError(t, err)
+
+	// Each record is 8+2+40 bytes, so every second one crosses the limit.
+	value := make([]byte, 40)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, writer.Put([]byte{'k', byte(i)}, value))
+	}
+
+	assert.EqualValues(t, 5, sc.syncs)
+
+	// The data written so far is on disk.
+	info, err := f.Stat()
+	require.NoError(t, err)
+	assert.EqualValues(t, cdb.DataOffset+10*50, info.Size())
+
+	require.NoError(t, writer.Close())
+	assert.EqualValues(t, 6, sc.syncs)
+	assert.Equal(t, cdb.SyncOnClose, cdb.SyncEvery(0))
+}
+
+func TestConcurrentSync(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	sc := &syncCounter{File: f}
+	writer, err := cdb.NewWriter(sc, nil, cdb.WithConcurrentSync())
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, writer.Put(record[0], record[1]))
+		}
+	}
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, sc.syncs)
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+}
@@ -0,0 +1,155 @@
+package cdb
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path"
+)
+
+// ErrMemberNotFound is returned when opening a database inside an archive, if
+// the archive doesn't contain the requested member.
+var ErrMemberNotFound = errors.New("member not found in archive")
+
+// ErrMemberNotStored is returned when opening a database inside an archive, if
+// the member is compressed or otherwise can't be read in place.
+var ErrMemberNotStored = errors.New("archive member is not stored uncompressed")
+
+// OpenTar opens a database stored as the member name inside the uncompressed
+// tar file at the given path, without extracting it. Closing the returned CDB
+// closes the tar file.
+func OpenTar(tarPath, name string) (*CDB, error) {
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return nil, err
+	}
+
+	section, err := findTarMember(f, name)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return New(sectionCloser{section, f}, nil)
+}
+
+// NewFromTar opens a database stored as the member name inside the
+// uncompressed tar archive read from r. The member is read in place, using its
+// offset and size inside the archive.
+//
+// The hash argument behaves as it does for New.
+func NewFromTar(r io.ReaderAt, name string, hash func([]byte) uint32) (*CDB, error) {
+	section, err := findTarMember(r, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(section, hash)
+}
+
+// OpenZip opens a database stored as the member name inside the zip file at
+// the given path, without extracting it. The member must have been stored
+// without compression. Closing the returned CDB closes the zip file.
+func OpenZip(zipPath, name string) (*CDB, error) {
+	f, err := os.Open(zipPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	section, err := findZipMember(f, info.Size(), name)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return New(sectionCloser{section, f}, nil)
+}
+
+// NewFromZip opens a database stored as the member name inside the zip
+// archive read from r, which is size bytes long. The member must have been
+// stored without compression, so that it can be read in place.
+//
+// The hash argument behaves as it does for New.
+func NewFromZip(r io.ReaderAt, size int64, name string, hash func([]byte) uint32) (*CDB, error) {
+	section, err := findZipMember(r, size, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(section, hash)
+}
+
+func findTarMember(r io.ReaderAt, name string) (*io.SectionReader, error) {
+	// The tar reader leaves the underlying stream positioned at the start of
+	// the member's data after reading its header, so we can use the position
+	// to find the offset of the member.
+	archive := io.NewSectionReader(r, 0, math.MaxInt64)
+	tr := tar.NewReader(archive)
+	name = path.Clean(name)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, ErrMemberNotFound
+		} else if err != nil {
+			return nil, err
+		}
+
+		if path.Clean(header.Name) != name {
+			continue
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			return nil, ErrMemberNotStored
+		}
+
+		offset, err := archive.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+
+		return io.NewSectionReader(r, offset, header.Size), nil
+	}
+}
+
+func findZipMember(r io.ReaderAt, size int64, name string) (*io.SectionReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	name = path.Clean(name)
+	for _, f := range zr.File {
+		if path.Clean(f.Name) != name {
+			continue
+		}
+
+		if f.Method != zip.Store || f.Flags&0x1 != 0 {
+			return nil, ErrMemberNotStored
+		}
+
+		offset, err := f.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+
+		return io.NewSectionReader(r, offset, int64(f.UncompressedSize64)), nil
+	}
+
+	return nil, ErrMemberNotFound
+}
+
+// sectionCloser lets a CDB close the archive file a member was opened from.
+type sectionCloser struct {
+	*io.SectionReader
+	io.Closer
+}
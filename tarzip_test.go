@@ -0,0 +1,135 @@
+package cdb_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testArchivedGet(t *testing.T, db *cdb.CDB) {
+	for _, record := range expectedRecords {
+		msg := "while fetching " + string(record[0])
+
+		value, err := db.Get(record[0])
+		require.NoError(t, err, msg)
+		assert.Equal(t, string(record[1]), string(value), msg)
+	}
+}
+
+func makeTar(t *testing.T) []byte {
+	data, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	members := []struct {
+		name string
+		body []byte
+	}{
+		{"README", []byte("this is not a database")},
+		{"data/test.cdb", data},
+	}
+
+	for _, m := range members {
+		err = tw.WriteHeader(&tar.Header{
+			Name:     m.name,
+			Mode:     0644,
+			Size:     int64(len(m.body)),
+			Typeflag: tar.TypeReg,
+		})
+		require.NoError(t, err)
+
+		_, err = tw.Write(m.body)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func makeZip(t *testing.T, method uint16) []byte {
+	data, err := ioutil.ReadFile("./test/test.cdb")
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+
+	w, err := zw.Create("README")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("this is not a database"))
+	require.NoError(t, err)
+
+	w, err = zw.CreateHeader(&zip.FileHeader{Name: "data/test.cdb", Method: method})
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestNewFromTar(t *testing.T) {
+	archive := makeTar(t)
+
+	db, err := cdb.NewFromTar(bytes.NewReader(archive), "./data/test.cdb", nil)
+	require.NoError(t, err)
+	testArchivedGet(t, db)
+
+	_, err = cdb.NewFromTar(bytes.NewReader(archive), "missing.cdb", nil)
+	assert.Equal(t, cdb.ErrMemberNotFound, err)
+}
+
+func TestOpenTar(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(makeTar(t))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	db, err := cdb.OpenTar(f.Name(), "data/test.cdb")
+	require.NoError(t, err)
+	testArchivedGet(t, db)
+	require.NoError(t, db.Close())
+}
+
+func TestNewFromZip(t *testing.T) {
+	archive := makeZip(t, zip.Store)
+
+	db, err := cdb.NewFromZip(bytes.NewReader(archive), int64(len(archive)), "data/test.cdb", nil)
+	require.NoError(t, err)
+	testArchivedGet(t, db)
+
+	_, err = cdb.NewFromZip(bytes.NewReader(archive), int64(len(archive)), "missing.cdb", nil)
+	assert.Equal(t, cdb.ErrMemberNotFound, err)
+}
+
+func TestNewFromZipCompressed(t *testing.T) {
+	archive := makeZip(t, zip.Deflate)
+
+	_, err := cdb.NewFromZip(bytes.NewReader(archive), int64(len(archive)), "data/test.cdb", nil)
+	assert.Equal(t, cdb.ErrMemberNotStored, err)
+}
+
+func TestOpenZip(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(makeZip(t, zip.Store))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	db, err := cdb.OpenZip(f.Name(), "data/test.cdb")
+	require.NoError(t, err)
+	testArchivedGet(t, db)
+	require.NoError(t, db.Close())
+}
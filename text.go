@@ -0,0 +1,304 @@
+package cdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Encoding describes how keys or values are represented in a text format.
+type Encoding int
+
+const (
+	// Raw uses keys or values as they are.
+	Raw Encoding = iota
+	// Hex uses the hexadecimal encoding of keys or values.
+	Hex
+	// Base64 uses the standard base64 encoding of keys or values.
+	Base64
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case Raw:
+		return "raw"
+	case Hex:
+		return "hex"
+	case Base64:
+		return "base64"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// Encode represents b as a string in the encoding, as ExportCSV and
+// ExportJSONLines do.
+func (e Encoding) Encode(b []byte) string {
+	switch e {
+	case Hex:
+		return hex.EncodeToString(b)
+	case Base64:
+		return base64.StdEncoding.EncodeToString(b)
+	default:
+		return string(b)
+	}
+}
+
+// Decode reverses Encode, as ImportCSV and ImportJSONLines do.
+func (e Encoding) Decode(s string) ([]byte, error) {
+	switch e {
+	case Hex:
+		return hex.DecodeString(s)
+	case Base64:
+		return base64.StdEncoding.DecodeString(s)
+	default:
+		return []byte(s), nil
+	}
+}
+
+// TextOptions configures ImportCSV, ExportCSV, ImportJSONLines, and
+// ExportJSONLines. A nil *TextOptions uses the defaults.
+type TextOptions struct {
+	// KeyColumn and ValueColumn are the zero-based CSV columns for the key and
+	// value. If both are zero, the key is in the first column and the value is
+	// in the second.
+	KeyColumn, ValueColumn int
+	// Header indicates that the first row of a CSV file is a header. It's
+	// skipped when importing, and written when exporting.
+	Header bool
+
+	// KeyField and ValueField are the JSON object fields for the key and
+	// value. They default to "key" and "value".
+	KeyField, ValueField string
+
+	// KeyEncoding and ValueEncoding are the encodings for keys and values.
+	// They default to Raw.
+	KeyEncoding, ValueEncoding Encoding
+}
+
+func (o *TextOptions) columns() (int, int) {
+	if o == nil || (o.KeyColumn == 0 && o.ValueColumn == 0) {
+		return 0, 1
+	}
+
+	return o.KeyColumn, o.ValueColumn
+}
+
+func (o *TextOptions) fields() (string, string) {
+	key, value := "key", "value"
+	if o != nil && o.KeyField != "" {
+		key = o.KeyField
+	}
+
+	if o != nil && o.ValueField != "" {
+		value = o.ValueField
+	}
+
+	return key, value
+}
+
+func (o *TextOptions) encodings() (Encoding, Encoding) {
+	if o == nil {
+		return Raw, Raw
+	}
+
+	return o.KeyEncoding, o.ValueEncoding
+}
+
+// ImportCSV reads CSV records from r, and adds each one to w as a key/value
+// pair. Rows may have any number of columns, as long as they include the key
+// and value columns.
+//
+// ImportCSV does not finalize w; the caller must call Close or Freeze once it
+// returns.
+func ImportCSV(w *Writer, r io.Reader, opts *TextOptions) error {
+	keyColumn, valueColumn := opts.columns()
+	keyEncoding, valueEncoding := opts.encodings()
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if row == 1 && opts != nil && opts.Header {
+			continue
+		}
+
+		if keyColumn >= len(record) || valueColumn >= len(record) {
+			return fmt.Errorf("row %d: expected at least %d columns", row, max(keyColumn, valueColumn)+1)
+		}
+
+		key, err := keyEncoding.Decode(record[keyColumn])
+		if err != nil {
+			return fmt.Errorf("row %d: decoding key: %w", row, err)
+		}
+
+		value, err := valueEncoding.Decode(record[valueColumn])
+		if err != nil {
+			return fmt.Errorf("row %d: decoding value: %w", row, err)
+		}
+
+		err = w.Put(key, value)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ExportCSV writes each record in db to w as a CSV row, with the key and value
+// in their configured columns. Any other columns are left empty.
+func ExportCSV(w io.Writer, db *CDB, opts *TextOptions) error {
+	keyColumn, valueColumn := opts.columns()
+	keyEncoding, valueEncoding := opts.encodings()
+
+	cw := csv.NewWriter(w)
+	row := make([]string, max(keyColumn, valueColumn)+1)
+	if opts != nil && opts.Header {
+		row[keyColumn], row[valueColumn] = "key", "value"
+		err := cw.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	iter := db.Iter()
+	for iter.Next() {
+		row[keyColumn] = keyEncoding.Encode(iter.Key())
+		row[valueColumn] = valueEncoding.Encode(iter.Value())
+		err := cw.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	if iter.Err() != nil {
+		return iter.Err()
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportJSONLines reads a stream of JSON objects from r, one per line, and
+// adds each one to w as a key/value pair. Other fields are ignored, and so
+// are blank lines.
+//
+// With the Raw encoding, the key must be a JSON string. The value may be a
+// JSON string, in which case its contents are stored, or any other JSON value
+// except null, in which case its JSON text is stored.
+//
+// ImportJSONLines does not finalize w; the caller must call Close or Freeze
+// once it returns.
+func ImportJSONLines(w *Writer, r io.Reader, opts *TextOptions) error {
+	keyField, valueField := opts.fields()
+	keyEncoding, valueEncoding := opts.encodings()
+
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if len(bytes.TrimSpace(text)) > 0 {
+			key, value, lineErr := parseJSONLine(text, keyField, valueField, keyEncoding, valueEncoding)
+			if lineErr != nil {
+				return fmt.Errorf("line %d: %w", line, lineErr)
+			}
+
+			putErr := w.Put(key, value)
+			if putErr != nil {
+				return putErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// parseJSONLine decodes the key and value from one line of JSON.
+func parseJSONLine(text []byte, keyField, valueField string, keyEncoding, valueEncoding Encoding) ([]byte, []byte, error) {
+	var object map[string]json.RawMessage
+	err := json.Unmarshal(text, &object)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rawKey, ok := object[keyField]
+	if !ok {
+		return nil, nil, fmt.Errorf("missing field %q", keyField)
+	}
+
+	// Unmarshaling null into a string leaves it as it was, rather than
+	// failing, so null has to be checked for separately.
+	var keyText string
+	if isJSONNull(rawKey) || json.Unmarshal(rawKey, &keyText) != nil {
+		return nil, nil, fmt.Errorf("key is not a string")
+	}
+
+	key, err := keyEncoding.Decode(keyText)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding key: %w", err)
+	}
+
+	rawValue, ok := object[valueField]
+	if !ok {
+		return nil, nil, fmt.Errorf("missing field %q", valueField)
+	} else if isJSONNull(rawValue) {
+		return nil, nil, fmt.Errorf("value is null")
+	}
+
+	var valueText string
+	if json.Unmarshal(rawValue, &valueText) == nil {
+		value, err := valueEncoding.Decode(valueText)
+		if err != nil {
+			return nil, nil, fmt.Errorf("decoding value: %w", err)
+		}
+
+		return key, value, nil
+	} else if valueEncoding == Raw {
+		return key, bytes.TrimSpace(rawValue), nil
+	}
+
+	return nil, nil, fmt.Errorf("value is not a string")
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// ExportJSONLines writes each record in db to w as a JSON object on its own
+// line, with the key and value as strings. With the Raw encoding, any invalid
+// UTF-8 in keys and values is replaced, so Hex or Base64 should be used for
+// binary data.
+func ExportJSONLines(w io.Writer, db *CDB, opts *TextOptions) error {
+	keyField, valueField := opts.fields()
+	keyEncoding, valueEncoding := opts.encodings()
+
+	enc := json.NewEncoder(w)
+	iter := db.Iter()
+	for iter.Next() {
+		err := enc.Encode(map[string]string{
+			keyField:   keyEncoding.Encode(iter.Key()),
+			valueField: valueEncoding.Encode(iter.Value()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
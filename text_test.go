@@ -0,0 +1,144 @@
+package cdb_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func importText(t *testing.T, fn func(w *cdb.Writer) error) *cdb.CDB {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+	require.NoError(t, fn(writer))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	return db
+}
+
+func assertRecords(t *testing.T, db *cdb.CDB, expected [][2]string) {
+	var actual [][2]string
+	iter := db.Iter()
+	for iter.Next() {
+		actual = append(actual, [2]string{string(iter.Key()), string(iter.Value())})
+	}
+
+	require.NoError(t, iter.Err())
+	assert.Equal(t, expected, actual)
+}
+
+func TestImportCSV(t *testing.T) {
+	input := "id,name,value\n1,foo,666f6f\n2,\"b,ar\",\n"
+	opts := &cdb.TextOptions{KeyColumn: 1, ValueColumn: 2, Header: true, ValueEncoding: cdb.Hex}
+	db := importText(t, func(w *cdb.Writer) error {
+		return cdb.ImportCSV(w, strings.NewReader(input), opts)
+	})
+
+	assertRecords(t, db, [][2]string{{"foo", "foo"}, {"b,ar", ""}})
+
+	var buf bytes.Buffer
+	require.NoError(t, cdb.ExportCSV(&buf, db, opts))
+	assert.Equal(t, ",key,value\n,foo,666f6f\n,\"b,ar\",\n", buf.String())
+}
+
+func TestImportCSVMissingColumn(t *testing.T) {
+	importText(t, func(w *cdb.Writer) error {
+		err := cdb.ImportCSV(w, strings.NewReader("foo,bar\nbaz\n"), nil)
+		assert.EqualError(t, err, "row 2: expected at least 2 columns")
+		return nil
+	})
+}
+
+func TestEncoding(t *testing.T) {
+	for e, encoded := range map[cdb.Encoding]string{
+		cdb.Raw:    "\x00hi",
+		cdb.Hex:    "006869",
+		cdb.Base64: "AGhp",
+	} {
+		assert.Equal(t, encoded, e.Encode([]byte("\x00hi")), e.String())
+
+		decoded, err := e.Decode(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, "\x00hi", string(decoded), e.String())
+	}
+}
+
+func TestImportErrorsWrap(t *testing.T) {
+	opts := &cdb.TextOptions{ValueEncoding: cdb.Base64}
+	var corrupt base64.CorruptInputError
+
+	err := cdb.ImportCSV(cdb.NewMem(), strings.NewReader("foo,!!!\n"), opts)
+	assert.True(t, errors.As(err, &corrupt), "%v", err)
+
+	err = cdb.ImportJSONLines(cdb.NewMem(), strings.NewReader(`{"key": "foo", "value": "!!!"}`+"\n"), opts)
+	assert.True(t, errors.As(err, &corrupt), "%v", err)
+
+	var syntax *json.SyntaxError
+	err = cdb.ImportJSONLines(cdb.NewMem(), strings.NewReader("{\n"), nil)
+	assert.True(t, errors.As(err, &syntax), "%v", err)
+}
+
+func TestImportJSONLines(t *testing.T) {
+	input := `{"key": "foo", "value": "bar", "other": 1}
+{"key": "baz", "value": {"nested": [1, 2]}}
+{"key": "empty", "value": ""}
+`
+
+	db := importText(t, func(w *cdb.Writer) error {
+		return cdb.ImportJSONLines(w, strings.NewReader(input), nil)
+	})
+
+	assertRecords(t, db, [][2]string{
+		{"foo", "bar"},
+		{"baz", `{"nested": [1, 2]}`},
+		{"empty", ""},
+	})
+
+	opts := &cdb.TextOptions{KeyField: "k", ValueField: "v", ValueEncoding: cdb.Base64}
+	var buf bytes.Buffer
+	require.NoError(t, cdb.ExportJSONLines(&buf, db, opts))
+	assert.Equal(t, `{"k":"foo","v":"YmFy"}
+{"k":"baz","v":"eyJuZXN0ZWQiOiBbMSwgMl19"}
+{"k":"empty","v":""}
+`, buf.String())
+
+	roundTripped := importText(t, func(w *cdb.Writer) error {
+		return cdb.ImportJSONLines(w, &buf, opts)
+	})
+
+	assertRecords(t, roundTripped, [][2]string{
+		{"foo", "bar"},
+		{"baz", `{"nested": [1, 2]}`},
+		{"empty", ""},
+	})
+}
+
+func TestImportJSONLinesErrors(t *testing.T) {
+	cases := map[string]string{
+		"{\"key\": \"foo\", \"value\": \"bar\"}\n\n{\"key\": \"baz\", \"value\": null}\n": "line 3: value is null",
+		"{\"key\": null, \"value\": \"bar\"}\n":                                           "line 1: key is not a string",
+		"{\"key\": \"foo\"}\n":                                                            `line 1: missing field "value"`,
+		"{\"key\": \"foo\",\n\"value\": \"bar\"}\n":                                       "line 1: unexpected end of JSON input",
+	}
+
+	for input, expected := range cases {
+		writer := cdb.NewMem()
+		err := cdb.ImportJSONLines(writer, strings.NewReader(input), nil)
+		if assert.Error(t, err, input) {
+			assert.Equal(t, expected, err.Error(), input)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimeSeriesSet represents a directory of databases that each hold a snapshot
+// of the data for one period of time, such as a day or an hour. Each file is
+// named for the start of its period, using a time layout such as
+// "2006-01-02.cdb" for daily files or "2006-01-02T15.cdb" for hourly ones, and
+// holds the records for that period; a key can appear in any number of them.
+//
+// Lookups probe the files from newest to oldest, so they're cheapest for keys
+// that were written recently. Writing each file with WithBloomFilter makes
+// probing the files that don't have a key much cheaper.
+//
+// A TimeSeriesSet is safe for concurrent use by any number of goroutines.
+type TimeSeriesSet struct {
+	dir    string
+	layout string
+	opts   []Option
+
+	mu         sync.RWMutex
+	partitions []timePartition
+}
+
+// A timePartition is one of the files in a TimeSeriesSet, and the start of
+// the period it covers.
+type timePartition struct {
+	start time.Time
+	name  string
+	db    *CDB
+}
+
+// OpenTimeSeriesSet opens every file in dir whose name matches layout, a time
+// layout as used by time.Parse, with the given options. The times in the
+// names are interpreted as UTC, unless the layout includes a time zone. Files
+// whose names don't match, such as files that are still being written under a
+// temporary name, are ignored; adding a new period is a matter of writing its
+// file elsewhere, renaming it into dir, and calling Reload.
+func OpenTimeSeriesSet(dir, layout string, opts ...Option) (*TimeSeriesSet, error) {
+	ts := &TimeSeriesSet{dir: dir, layout: layout, opts: opts}
+	err := ts.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return ts, nil
+}
+
+// Path returns the path of the file for the period that includes t. It's
+// useful for writing new files with the right names.
+func (ts *TimeSeriesSet) Path(t time.Time) string {
+	return filepath.Join(ts.dir, t.UTC().Format(ts.layout))
+}
+
+// Reload scans the directory again, opening any new files and closing the
+// ones that have been removed. If a new file can't be opened, the set is left
+// as it was, and the error is returned.
+func (ts *TimeSeriesSet) Reload() error {
+	infos, err := ioutil.ReadDir(ts.dir)
+	if err != nil {
+		return err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	open := make(map[string]*CDB, len(ts.partitions))
+	for _, p := range ts.partitions {
+		open[p.name] = p.db
+	}
+
+	var partitions, added []timePartition
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		start, err := time.Parse(ts.layout, info.Name())
+		if err != nil {
+			continue
+		}
+
+		db, ok := open[info.Name()]
+		if ok {
+			delete(open, info.Name())
+		} else {
+			db, err = Open(filepath.Join(ts.dir, info.Name()), ts.opts...)
+			if err != nil {
+				for _, p := range added {
+					p.db.Close()
+				}
+
+				return err
+			}
+		}
+
+		p := timePartition{start: start, name: info.Name(), db: db}
+		partitions = append(partitions, p)
+		if !ok {
+			added = append(added, p)
+		}
+	}
+
+	// Whatever's left in open has been removed from the directory.
+	for _, db := range open {
+		db.Close()
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].start.Before(partitions[j].start)
+	})
+
+	ts.partitions = partitions
+	return nil
+}
+
+// Partitions returns the start of the period covered by each file, from
+// oldest to newest.
+func (ts *TimeSeriesSet) Partitions() []time.Time {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	starts := make([]time.Time, len(ts.partitions))
+	for i, p := range ts.partitions {
+		starts[i] = p.start
+	}
+
+	return starts
+}
+
+// Get returns the value for a given key as of time t: the value in the newest
+// file for a period starting at or before t that has the key. It returns nil
+// if none of them do.
+func (ts *TimeSeriesSet) Get(key []byte, t time.Time) ([]byte, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	n := sort.Search(len(ts.partitions), func(i int) bool {
+		return ts.partitions[i].start.After(t)
+	})
+
+	return ts.probe(key, n)
+}
+
+// GetLatest returns the value for a given key in the newest file that has
+// it, or nil if none of them do.
+func (ts *TimeSeriesSet) GetLatest(key []byte) ([]byte, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	return ts.probe(key, len(ts.partitions))
+}
+
+// probe looks for key in the first n partitions, from newest to oldest.
+func (ts *TimeSeriesSet) probe(key []byte, n int) ([]byte, error) {
+	for i := n - 1; i >= 0; i-- {
+		value, err := ts.partitions[i].db.Get(key)
+		if err != nil || value != nil {
+			return value, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Close closes every file in the set, and returns the first error, if any.
+func (ts *TimeSeriesSet) Close() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var firstErr error
+	for _, p := range ts.partitions {
+		err := p.db.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	ts.partitions = nil
+	return firstErr
+}
@@ -0,0 +1,98 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTimePartition(t *testing.T, path string, records map[string]string) {
+	writer, err := cdb.Create(path, cdb.WithBloomFilter(10))
+	require.NoError(t, err)
+	for k, v := range records {
+		require.NoError(t, writer.Put([]byte(k), []byte(v)))
+	}
+
+	require.NoError(t, writer.Close())
+}
+
+func TestTimeSeriesSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	layout := "2006-01-02.cdb"
+	writeTimePartition(t, filepath.Join(dir, "2024-03-01.cdb"), map[string]string{"a": "1", "b": "1", "empty": ""})
+	writeTimePartition(t, filepath.Join(dir, "2024-03-02.cdb"), map[string]string{"a": "2"})
+	writeTimePartition(t, filepath.Join(dir, "2024-03-03.cdb.tmp"), map[string]string{"a": "ignored"})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a database"), 0644))
+
+	ts, err := cdb.OpenTimeSeriesSet(dir, layout)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	assert.Equal(t, []time.Time{day(1), day(2)}, ts.Partitions())
+
+	get := func(key string, at time.Time) string {
+		v, err := ts.Get([]byte(key), at)
+		require.NoError(t, err)
+		if v == nil {
+			return "<nil>"
+		}
+
+		return string(v)
+	}
+
+	assert.Equal(t, "<nil>", get("a", day(1).Add(-time.Second)))
+	assert.Equal(t, "1", get("a", day(1)))
+	assert.Equal(t, "1", get("a", day(1).Add(23*time.Hour)))
+	assert.Equal(t, "2", get("a", day(2)))
+	assert.Equal(t, "2", get("a", day(10)))
+	assert.Equal(t, "1", get("b", day(10)))
+	assert.Equal(t, "", get("empty", day(10)))
+	assert.Equal(t, "<nil>", get("c", day(10)))
+
+	v, err := ts.GetLatest([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(v))
+
+	// New files are picked up, and removed ones dropped, on Reload.
+	assert.Equal(t, filepath.Join(dir, "2024-03-03.cdb"), ts.Path(day(3).Add(5*time.Hour)))
+	require.NoError(t, os.Rename(filepath.Join(dir, "2024-03-03.cdb.tmp"), ts.Path(day(3))))
+	require.NoError(t, os.Remove(filepath.Join(dir, "2024-03-01.cdb")))
+	require.NoError(t, ts.Reload())
+
+	assert.Equal(t, []time.Time{day(2), day(3)}, ts.Partitions())
+	assert.Equal(t, "ignored", get("a", day(3)))
+	assert.Equal(t, "2", get("a", day(2)))
+	assert.Equal(t, "<nil>", get("b", day(10)))
+}
+
+func TestTimeSeriesSetReloadError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	layout := "2006-01-02T15.cdb"
+	writeTimePartition(t, filepath.Join(dir, "2024-03-01T10.cdb"), map[string]string{"a": "1"})
+
+	ts, err := cdb.OpenTimeSeriesSet(dir, layout)
+	require.NoError(t, err)
+	defer ts.Close()
+
+	// A file that can't be opened leaves the set as it was.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "2024-03-01T11.cdb"), []byte("short"), 0644))
+	assert.Error(t, ts.Reload())
+	assert.Len(t, ts.Partitions(), 1)
+
+	v, err := ts.GetLatest([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(v))
+}
@@ -0,0 +1,94 @@
+package cdb
+
+import (
+	"io"
+	"time"
+)
+
+// TraceEvent describes a single lookup, for a CDB opened with WithTrace.
+type TraceEvent struct {
+	// Key is the key that was looked up, after normalization. It's only
+	// valid until the trace function returns.
+	Key []byte
+	// Hash is the hash of the key, and Table is the hash table it selects.
+	Hash  uint32
+	Table int
+	// Probes is the number of hash table slots that were read.
+	Probes int
+	// Offsets are the offsets of the records that were read because their
+	// hash matched, in the order they were read.
+	Offsets []uint32
+	// Reads and BytesRead count the reads from the underlying io.ReaderAt.
+	Reads     int
+	BytesRead int64
+	// Found is true if the key was found.
+	Found bool
+	// Duration is how long the lookup took, including decoding the value.
+	Duration time.Duration
+	// Err is the error the lookup returned, if any.
+	Err error
+}
+
+// WithTrace causes a CDB to call fn after every lookup with Get, GetStrict,
+// GetHashed, GetInto, GetAll, or Scan, describing how it went. It's meant for
+// debugging, such as by logging a sample of the events in production; fn is
+// called synchronously, and must be safe for concurrent use.
+//
+// Tracing adds a few small allocations to each lookup.
+func WithTrace(fn func(ev TraceEvent)) Option {
+	return func(o *options) {
+		o.trace = fn
+	}
+}
+
+// traceLookup runs fn with a copy of the database that records what the
+// lookup does, then passes the event to the trace function.
+func (cdb *CDB) traceLookup(hash uint32, key []byte, fn func(db *CDB) error) error {
+	ev := &TraceEvent{Key: key, Hash: hash, Table: int(hash & 0xff)}
+	traced := *cdb
+	traced.reader = traceReader{cdb.reader, ev}
+	traced.tracing = ev
+
+	start := time.Now()
+	err := fn(&traced)
+	ev.Duration = time.Since(start)
+	ev.Err = err
+
+	cdb.opts.trace(*ev)
+	return err
+}
+
+// traceRecord notes that the record at offset was read during a traced
+// lookup.
+func (cdb *CDB) traceRecord(offset uint32) {
+	if cdb.tracing != nil {
+		cdb.tracing.Offsets = append(cdb.tracing.Offsets, offset)
+	}
+}
+
+// traceReader counts the reads made on behalf of a traced lookup.
+type traceReader struct {
+	io.ReaderAt
+	ev *TraceEvent
+}
+
+func (tr traceReader) ReadAt(b []byte, off int64) (int, error) {
+	n, err := tr.ReaderAt.ReadAt(b, off)
+	tr.ev.Reads++
+	tr.ev.BytesRead += int64(n)
+	return n, err
+}
+
+// ReadAtv counts a batch of reads as a single read, if the underlying reader
+// is a ReaderAtv.
+func (tr traceReader) ReadAtv(bufs [][]byte, offsets []int64) (int, error) {
+	rv, ok := tr.ReaderAt.(ReaderAtv)
+	if !ok {
+		return readEach(tr, bufs, offsets)
+	}
+
+	n, err := rv.ReadAtv(bufs, offsets)
+	tr.ev.Reads++
+	tr.ev.BytesRead += int64(n)
+	return n, err
+}
@@ -0,0 +1,92 @@
+package cdb_test
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// traceRecorder collects trace events.
+type traceRecorder struct {
+	mu     sync.Mutex
+	events []cdb.TraceEvent
+}
+
+func (tr *traceRecorder) record(ev cdb.TraceEvent) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	ev.Key = append([]byte(nil), ev.Key...)
+	tr.events = append(tr.events, ev)
+}
+
+func (tr *traceRecorder) last(t *testing.T) cdb.TraceEvent {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	require.NotEmpty(t, tr.events)
+	return tr.events[len(tr.events)-1]
+}
+
+func TestTrace(t *testing.T) {
+	tr := &traceRecorder{}
+	db, err := cdb.Open("./test/test.cdb", cdb.WithTrace(tr.record))
+	require.NoError(t, err)
+	defer db.Close()
+
+	v, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(v))
+
+	ev := tr.last(t)
+	assert.Equal(t, "foo", string(ev.Key))
+	assert.Equal(t, cdb.HashKey([]byte("foo")), ev.Hash)
+	assert.Equal(t, int(ev.Hash&0xff), ev.Table)
+	assert.True(t, ev.Found)
+	assert.True(t, ev.Probes >= 1)
+	require.Len(t, ev.Offsets, 1)
+	assert.True(t, ev.Offsets[0] >= cdb.DataOffset)
+	assert.Equal(t, ev.Probes+2, ev.Reads)
+	assert.EqualValues(t, 8*ev.Probes+8+3+3, ev.BytesRead)
+	assert.True(t, ev.Duration > 0)
+	assert.NoError(t, ev.Err)
+
+	v, err = db.Get([]byte("not in the table"))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	ev = tr.last(t)
+	assert.False(t, ev.Found)
+	assert.Equal(t, "not in the table", string(ev.Key))
+
+	buf := make([]byte, 64)
+	n, found, err := db.GetInto([]byte("foo"), buf)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "bar", string(buf[:n]))
+	assert.True(t, tr.last(t).Found)
+
+	values, err := db.GetAll([]byte("foo"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, values)
+	assert.True(t, tr.last(t).Found)
+	assert.Len(t, tr.events, 4)
+}
+
+func TestTraceError(t *testing.T) {
+	data := readTestData(t)
+	tr := &traceRecorder{}
+	errDisk := errors.New("disk on fire")
+	r := failingReader{bytes.NewReader(data), cdb.DataOffset, errDisk}
+	db, err := cdb.New(r, nil, cdb.WithTrace(tr.record))
+	require.NoError(t, err)
+
+	_, err = db.Get([]byte("foo"))
+	assert.True(t, errors.Is(err, errDisk))
+	assert.True(t, errors.Is(tr.last(t).Err, errDisk))
+}
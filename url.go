@@ -0,0 +1,14 @@
+package cdb
+
+import (
+	"github.com/colinmarc/cdb/httprange"
+)
+
+// OpenURL opens an existing CDB database over HTTP, using range requests to
+// read only the parts of the file needed for each lookup. The server must
+// support range requests, as most static file servers and object stores do.
+// To configure the block size, caching, or HTTP client, use httprange.New
+// with New instead.
+func OpenURL(url string, opts ...Option) (*CDB, error) {
+	return New(httprange.New(url, nil), nil, opts...)
+}
@@ -0,0 +1,62 @@
+package cdb_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenURL(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("./test")))
+	defer server.Close()
+
+	db, err := cdb.OpenURL(server.URL + "/test.cdb")
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		value, err := db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+}
+
+func TestOpenURLAligned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-cdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writer, err := cdb.Create(filepath.Join(dir, "aligned.cdb"), cdb.WithAlignment(256))
+	require.NoError(t, err)
+	var records [][][]byte
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, writer.Put(record[0], record[1]))
+			records = append(records, record)
+		}
+	}
+	require.NoError(t, writer.Close())
+
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	// The alignment should be picked up without passing WithAlignment, so
+	// the padding isn't read as records.
+	db, err := cdb.OpenURL(server.URL + "/aligned.cdb")
+	require.NoError(t, err)
+
+	iter := db.Iter()
+	for _, record := range records {
+		require.True(t, iter.Next())
+		assert.Equal(t, string(record[0]), string(iter.Key()))
+		assert.Equal(t, string(record[1]), string(iter.Value()))
+	}
+	assert.False(t, iter.Next())
+	require.NoError(t, iter.Err())
+}
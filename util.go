@@ -5,22 +5,27 @@ import (
 	"io"
 )
 
-func readTuple(r io.ReaderAt, offset uint32) (uint32, uint32, error) {
-	tuple := make([]byte, 8)
+func readTuple(r io.ReaderAt, order binary.ByteOrder, offset uint32) (uint32, uint32, error) {
+	return readTupleInto(r, order, offset, make([]byte, 8))
+}
+
+// readTupleInto is like readTuple, but reads into the given 8-byte buffer
+// instead of allocating one.
+func readTupleInto(r io.ReaderAt, order binary.ByteOrder, offset uint32, tuple []byte) (uint32, uint32, error) {
 	_, err := r.ReadAt(tuple, int64(offset))
 	if err != nil {
 		return 0, 0, err
 	}
 
-	first := binary.LittleEndian.Uint32(tuple[:4])
-	second := binary.LittleEndian.Uint32(tuple[4:])
+	first := order.Uint32(tuple[:4])
+	second := order.Uint32(tuple[4:])
 	return first, second, nil
 }
 
-func writeTuple(w io.Writer, first, second uint32) error {
+func writeTuple(w io.Writer, order binary.ByteOrder, first, second uint32) error {
 	tuple := make([]byte, 8)
-	binary.LittleEndian.PutUint32(tuple[:4], first)
-	binary.LittleEndian.PutUint32(tuple[4:], second)
+	order.PutUint32(tuple[:4], first)
+	order.PutUint32(tuple[4:], second)
 
 	_, err := w.Write(tuple)
 	return err
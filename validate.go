@@ -0,0 +1,121 @@
+package cdb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TextSafe is a validator for use with WithValidator, which rejects keys and
+// values containing NUL, newline, or carriage return bytes. It's useful for
+// datasets that will be consumed by line-oriented text tools.
+var TextSafe = RejectBytes("\x00\n\r")
+
+// InvalidByteError is returned by validators created with RejectBytes, for a
+// record containing a forbidden byte.
+type InvalidByteError struct {
+	// InKey is true if the byte was found in the key, and false if it was in
+	// the value.
+	InKey bool
+	// Offset is the position of the byte in the key or value.
+	Offset int
+	// Byte is the forbidden byte.
+	Byte byte
+}
+
+func (e *InvalidByteError) Error() string {
+	field := "value"
+	if e.InKey {
+		field = "key"
+	}
+
+	return fmt.Sprintf("invalid byte %q at offset %d in %s", e.Byte, e.Offset, field)
+}
+
+// SizeError is returned by Put and PutReader for a key or value larger than
+// the limit set with WithMaxKeySize or WithMaxValueSize.
+type SizeError struct {
+	// InKey is true if the key was too large, and false if the value was.
+	InKey bool
+	// Size is the size of the key or value.
+	Size int64
+	// Max is the limit it exceeded.
+	Max int64
+}
+
+func (e *SizeError) Error() string {
+	field := "value"
+	if e.InKey {
+		field = "key"
+	}
+
+	return fmt.Sprintf("%s of %d bytes exceeds the limit of %d bytes", field, e.Size, e.Max)
+}
+
+// WithMaxKeySize causes Put and PutReader to reject keys longer than n bytes
+// with a *SizeError, before anything is written.
+func WithMaxKeySize(n int64) Option {
+	return func(o *options) {
+		o.maxKeySize = n
+	}
+}
+
+// WithMaxValueSize causes Put and PutReader to reject values longer than n
+// bytes with a *SizeError, before anything is written. The limit applies to
+// values as they're passed in, before any compression.
+func WithMaxValueSize(n int64) Option {
+	return func(o *options) {
+		o.maxValueSize = n
+	}
+}
+
+// checkLimits checks the sizes of a key and value against the limits set in
+// the options.
+func (o *options) checkLimits(keyLength, valueLength int64) error {
+	if o.maxKeySize > 0 && keyLength > o.maxKeySize {
+		return &SizeError{InKey: true, Size: keyLength, Max: o.maxKeySize}
+	}
+
+	if o.maxValueSize > 0 && valueLength > o.maxValueSize {
+		return &SizeError{InKey: false, Size: valueLength, Max: o.maxValueSize}
+	}
+
+	return nil
+}
+
+// WithValidator causes Put to check each record with fn before writing it. If
+// fn returns an error, the record is rejected and Put returns the error. This
+// option can be given more than once, in which case the validators are called
+// in order. Keys are checked as the caller passed them, after WithKeyNormalizer
+// but before WithKeyHMAC or WithHashedKeys replace them.
+func WithValidator(fn func(key, value []byte) error) Option {
+	return func(o *options) {
+		o.validators = append(o.validators, fn)
+	}
+}
+
+// RejectBytes returns a validator for use with WithValidator, which rejects
+// keys and values that contain any of the bytes in chars with an
+// *InvalidByteError.
+func RejectBytes(chars string) func(key, value []byte) error {
+	return func(key, value []byte) error {
+		if i := indexAnyByte(key, chars); i >= 0 {
+			return &InvalidByteError{InKey: true, Offset: i, Byte: key[i]}
+		}
+
+		if i := indexAnyByte(value, chars); i >= 0 {
+			return &InvalidByteError{InKey: false, Offset: i, Byte: value[i]}
+		}
+
+		return nil
+	}
+}
+
+func indexAnyByte(b []byte, chars string) int {
+	for i, c := range b {
+		if strings.IndexByte(chars, c) >= 0 {
+			return i
+		}
+	}
+
+	return -1
+}
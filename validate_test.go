@@ -0,0 +1,118 @@
+package cdb_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextSafe(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil, cdb.WithValidator(cdb.TextSafe))
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+
+	err = writer.Put([]byte("foo\n"), []byte("bar"))
+	assert.Equal(t, &cdb.InvalidByteError{InKey: true, Offset: 3, Byte: '\n'}, err)
+
+	err = writer.Put([]byte("foo"), []byte("b\x00r"))
+	assert.Equal(t, &cdb.InvalidByteError{InKey: false, Offset: 1, Byte: 0}, err)
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestMultipleValidators(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	errEmpty := errors.New("empty key")
+	writer, err := cdb.NewWriter(f, nil,
+		cdb.WithValidator(cdb.RejectBytes("\t")),
+		cdb.WithValidator(func(key, value []byte) error {
+			if len(key) == 0 {
+				return errEmpty
+			}
+
+			return nil
+		}))
+	require.NoError(t, err)
+
+	assert.Equal(t, errEmpty, writer.Put([]byte(""), []byte("value")))
+	assert.IsType(t, &cdb.InvalidByteError{}, writer.Put([]byte("a\tb"), []byte("value")))
+	assert.NoError(t, writer.Put([]byte("a b"), []byte("value")))
+}
+
+func TestMaxKeyAndValueSize(t *testing.T) {
+	writer := cdb.NewMem(cdb.WithMaxKeySize(4), cdb.WithMaxValueSize(8))
+	require.NoError(t, writer.Put([]byte("abcd"), []byte("12345678")))
+
+	err := writer.Put([]byte("abcde"), []byte("x"))
+	assert.Equal(t, &cdb.SizeError{InKey: true, Size: 5, Max: 4}, err)
+
+	err = writer.Put([]byte("a"), []byte("123456789"))
+	assert.Equal(t, &cdb.SizeError{InKey: false, Size: 9, Max: 8}, err)
+
+	err = writer.PutReader([]byte("b"), 1<<30, strings.NewReader(""))
+	assert.Equal(t, &cdb.SizeError{InKey: false, Size: 1 << 30, Max: 8}, err)
+	assert.Equal(t, "value of 1073741824 bytes exceeds the limit of 8 bytes", err.Error())
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	assertRecords(t, db, [][2]string{{"abcd", "12345678"}})
+}
+
+func TestValidatorSeesKeyBeforeHashing(t *testing.T) {
+	for name, opt := range map[string]cdb.Option{
+		"hmac":   cdb.WithKeyHMAC([]byte("secret")),
+		"hashed": cdb.WithHashedKeys(64),
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "test-cdb")
+			require.NoError(t, err)
+			defer os.Remove(f.Name())
+
+			writer, err := cdb.NewWriter(f, nil, cdb.WithValidator(cdb.TextSafe), opt)
+			require.NoError(t, err)
+
+			// Some of these keys will have digests with forbidden bytes in
+			// them, which shouldn't matter.
+			for i := 0; i < 100; i++ {
+				key := []byte(fmt.Sprintf("key%d", i))
+				require.NoError(t, writer.Put(key, []byte("value")))
+				require.NoError(t, writer.PutReader(key, 5, strings.NewReader("value")))
+			}
+
+			err = writer.Put([]byte("foo\n"), []byte("bar"))
+			assert.Equal(t, &cdb.InvalidByteError{InKey: true, Offset: 3, Byte: '\n'}, err)
+
+			err = writer.PutReader([]byte("foo\n"), 3, strings.NewReader("bar"))
+			assert.Equal(t, &cdb.InvalidByteError{InKey: true, Offset: 3, Byte: '\n'}, err)
+
+			pw := cdb.NewParallelWriter(writer, "")
+			for i := 100; i < 200; i++ {
+				require.NoError(t, pw.Put([]byte(fmt.Sprintf("key%d", i)), []byte("value")))
+			}
+
+			err = pw.Put([]byte("foo\n"), []byte("bar"))
+			assert.Equal(t, &cdb.InvalidByteError{InKey: true, Offset: 3, Byte: '\n'}, err)
+			require.NoError(t, pw.Close())
+		})
+	}
+}
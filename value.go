@@ -0,0 +1,91 @@
+package cdb
+
+import (
+	"time"
+)
+
+// Values can be stored with a prefix, depending on the options used to write
+// the database. With WithExpiry, there's an eight-byte expiration time, and
+// with WithCompression, a byte identifying the compressor, in that order.
+// With WithEncryption, everything after the expiration time is encrypted.
+
+// WithValueDecoder causes a CDB to pass every value it reads through fn
+// before returning it, so that post-processing such as decompression,
+// decryption, or migrating old formats happens in one place. fn is called
+// with the key and the value as stored, after any decoding done by the other
+// options, and returns the value to use instead; if it returns an error, the
+// lookup or iteration stops and returns it.
+//
+// The decoder applies to Get, GetAll, GetInto, Scan, Record.Value, and
+// iteration, but not to ValueReader, which always reads the value as stored.
+// fn may be called concurrently, and may keep or modify the value it's
+// given.
+func WithValueDecoder(fn func(key, raw []byte) ([]byte, error)) Option {
+	return func(o *options) {
+		o.decoder = fn
+	}
+}
+
+// encodeValue returns the value for key as it should be stored in the
+// database.
+func (cdb *Writer) encodeValue(key, value []byte, expires time.Time) ([]byte, error) {
+	compressed, err := cdb.opts.compressValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := cdb.opts.sealValue(key, compressed)
+	if err != nil {
+		return nil, err
+	}
+
+	if cdb.opts.expiry {
+		return append(expiryPrefix(expires), sealed...), nil
+	}
+
+	return sealed, nil
+}
+
+// streamPrefix returns the prefix for a value that's streamed in by
+// PutReader, which is never compressed and never expires.
+func (cdb *Writer) streamPrefix() []byte {
+	var prefix []byte
+	if cdb.opts.expiry {
+		prefix = expiryPrefix(time.Time{})
+	}
+
+	if cdb.opts.compressor != nil {
+		prefix = append(prefix, 0)
+	}
+
+	return prefix
+}
+
+// decodeValue reverses encodeValue for a value read from the database.
+func (cdb *CDB) decodeValue(key, value []byte) ([]byte, error) {
+	if cdb.opts.expiry {
+		if len(value) < expiryPrefixSize {
+			return nil, ErrCorruptRecord
+		}
+
+		value = value[expiryPrefixSize:]
+	}
+
+	value, err := cdb.opts.openValue(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = cdb.opts.decompressValue(value)
+	if err != nil || cdb.opts.decoder == nil {
+		return value, err
+	}
+
+	return cdb.opts.decoder(key, value)
+}
+
+// encodesValues returns true if values are stored with a prefix, encrypted,
+// or passed through a decoder when they're read.
+func (o *options) encodesValues() bool {
+	return o.expiry || o.compressor != nil || o.aead != nil || o.decoder != nil
+}
@@ -0,0 +1,60 @@
+package cdb_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueDecoder(t *testing.T) {
+	errOld := errors.New("old format")
+	decoder := cdb.WithValueDecoder(func(key, raw []byte) ([]byte, error) {
+		if bytes.HasPrefix(raw, []byte("v1:")) {
+			return nil, errOld
+		}
+
+		return append([]byte(string(key)+"="), raw...), nil
+	})
+
+	writer := cdb.NewMem(decoder)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Put([]byte("foo"), []byte("baz")))
+	require.NoError(t, writer.Put([]byte("old"), []byte("v1:qux")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	value, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo=bar", string(value))
+
+	values, err := db.GetAll([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("foo=bar"), []byte("foo=baz")}, values)
+
+	buf := make([]byte, 16)
+	n, found, err := db.GetInto([]byte("foo"), buf)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "foo=bar", string(buf[:n]))
+
+	rec, err := db.Record(0)
+	require.NoError(t, err)
+	value, err = rec.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "foo=bar", string(value))
+
+	_, err = db.Get([]byte("old"))
+	assert.Equal(t, errOld, err)
+
+	iter := db.Iter()
+	for iter.Next() {
+		assert.True(t, bytes.HasPrefix(iter.Value(), []byte("foo=")))
+	}
+
+	assert.Equal(t, errOld, iter.Err())
+}
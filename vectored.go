@@ -0,0 +1,326 @@
+package cdb
+
+import (
+	"bytes"
+	"io"
+)
+
+// ReaderAtv is an optional interface for the io.ReaderAt underlying a
+// database, for readers that can serve several reads in a single request,
+// such as an HTTP client using multi-range requests, or an object store
+// client with a batch API. It fills each of bufs from the corresponding
+// offset, and returns the total number of bytes read, or an error if any of
+// the reads came up short, like ReadAt.
+type ReaderAtv interface {
+	ReadAtv(bufs [][]byte, offsets []int64) (int, error)
+}
+
+// vectoredReadahead is the number of bytes of the value read along with a
+// record's header and key, when looking up keys through a ReaderAtv.
+const vectoredReadahead = 1024
+
+// A lookup reads a hash table slot to find the offset of a record, then the
+// record itself, so the two reads can't be combined into one request. What
+// can be saved, when the underlying reader is a ReaderAtv, is the separate
+// read of the record header: instead, the header, key, and start of the
+// value are read all at once, which usually takes a lookup from three round
+// trips to two. Lookups of several keys at once with GetVectored also share
+// their round trips, so a batch of keys takes about as long as one.
+
+// GetVectored looks up each of keys, and returns their values in the same
+// order, with nil for any that aren't found. If the underlying reader is a
+// ReaderAtv, each round of reads for every key is sent as a single request;
+// otherwise, the reads are made one by one, as with Get.
+func (cdb *CDB) GetVectored(keys [][]byte) ([][]byte, error) {
+	err := cdb.acquire(opGet)
+	if err != nil {
+		return nil, err
+	}
+	defer cdb.release(opGet)
+
+	lookups := make([]*vectoredLookup, 0, len(keys))
+	byKey := make([]*vectoredLookup, len(keys))
+	for i, key := range keys {
+		key = cdb.opts.normalizeKey(key)
+		if cdb.hidden(key) {
+			continue
+		}
+
+		l := cdb.newVectoredLookup(cdb.hash(key), key)
+		lookups = append(lookups, l)
+		byKey[i] = l
+	}
+
+	err = cdb.runVectored(lookups)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]byte, len(keys))
+	for i, l := range byKey {
+		if l == nil || l.value == nil {
+			continue
+		}
+
+		values[i], err = cdb.decodeValue(l.key, l.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// lookupVectored is lookup, for a database whose reader is a ReaderAtv.
+func (cdb *CDB) lookupVectored(hash uint32, key []byte) ([]byte, uint32, error) {
+	l := cdb.newVectoredLookup(hash, key)
+	err := cdb.runVectored([]*vectoredLookup{l})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return l.value, l.offset, nil
+}
+
+const (
+	// The lookup needs to read the next slot in its probe chain.
+	stateSlot = iota
+	// The lookup needs to read the header and key of a candidate record,
+	// along with the start of the value.
+	stateRecord
+	// The lookup has found the record, and needs to read the rest of the
+	// value.
+	stateRest
+	stateDone
+)
+
+// vectoredLookup is the state of one key being looked up by runVectored.
+type vectoredLookup struct {
+	key   []byte
+	probe probe
+	state int
+
+	// offset is the offset of the candidate record, and buf holds what's
+	// been read of it.
+	offset uint32
+	buf    []byte
+
+	// value is the value, as stored, once the record is found.
+	value []byte
+}
+
+func (cdb *CDB) newVectoredLookup(hash uint32, key []byte) *vectoredLookup {
+	l := &vectoredLookup{key: key, probe: cdb.newProbe(hash)}
+	if cdb.bloom != nil && !cdb.bloom.mayContain(hash) {
+		l.state = stateDone
+		cdb.observeGet(false, &l.probe)
+	}
+
+	return l
+}
+
+// runVectored advances every lookup in lockstep, making the next read for
+// each of them in a single batch, until they're all done.
+func (cdb *CDB) runVectored(lookups []*vectoredLookup) error {
+	var bufs [][]byte
+	var offsets []int64
+	var pending []*vectoredLookup
+	for {
+		bufs, offsets, pending = bufs[:0], offsets[:0], pending[:0]
+		for _, l := range lookups {
+			buf, offset, err := cdb.nextVectoredRead(l)
+			if err != nil {
+				return err
+			} else if buf != nil {
+				bufs = append(bufs, buf)
+				offsets = append(offsets, offset)
+				pending = append(pending, l)
+			}
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		// The batch doesn't say which read failed, so the error refers to
+		// the first one.
+		err := readAtv(cdb.reader, bufs, offsets)
+		if err != nil {
+			kind := ErrCorruptRecord
+			if pending[0].state == stateSlot {
+				kind = ErrCorruptIndex
+			}
+
+			return readError(kind, offsets[0], err)
+		}
+
+		for i, l := range pending {
+			err := cdb.advanceVectored(l, bufs[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// nextVectoredRead returns the buffer and offset for the next read a lookup
+// needs, or a nil buffer if it's done. Slots in pinned tables are read from
+// memory straight away.
+func (cdb *CDB) nextVectoredRead(l *vectoredLookup) ([]byte, int64, error) {
+	p := &l.probe
+	switch l.state {
+	case stateSlot:
+		for cdb.tables != nil && l.state == stateSlot {
+			if p.seen >= p.table.length {
+				cdb.finishVectored(l, false)
+				return nil, 0, nil
+			}
+
+			hash, offset, err := cdb.readSlot(p.table.offset+(8*p.slot), nil)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			cdb.advanceSlot(l, hash, offset)
+		}
+
+		if l.state == stateSlot {
+			if p.seen >= p.table.length {
+				cdb.finishVectored(l, false)
+				return nil, 0, nil
+			}
+
+			return make([]byte, 8), int64(p.table.offset + (8 * p.slot)), nil
+		}
+
+		return cdb.nextVectoredRead(l)
+	case stateRecord:
+		// Read as much of the record as it's likely to need, without going
+		// past the end of the data section.
+		n := int64(8 + len(l.key) + vectoredReadahead)
+		if remaining := int64(cdb.index[0].offset) - int64(l.offset); n > remaining {
+			n = remaining
+		}
+
+		if n < 8 {
+			return nil, 0, corruptAt(ErrCorruptRecord, int64(l.offset))
+		}
+
+		return make([]byte, n), int64(l.offset), nil
+	case stateRest:
+		return l.value[len(l.buf):], int64(l.offset) + 8 + int64(len(l.key)) + int64(len(l.buf)), nil
+	default:
+		return nil, 0, nil
+	}
+}
+
+// advanceSlot moves a lookup past the slot it just read.
+func (cdb *CDB) advanceSlot(l *vectoredLookup, hash, offset uint32) {
+	p := &l.probe
+	p.seen++
+	p.slot = (p.slot + 1) % p.table.length
+	if offset == 0 {
+		cdb.finishVectored(l, false)
+	} else if hash == p.hash {
+		l.state = stateRecord
+		l.offset = offset
+		cdb.traceRecord(offset)
+	}
+}
+
+// advanceVectored updates a lookup with the data it just read.
+func (cdb *CDB) advanceVectored(l *vectoredLookup, buf []byte) error {
+	switch l.state {
+	case stateSlot:
+		order := cdb.opts.order()
+		cdb.advanceSlot(l, order.Uint32(buf), order.Uint32(buf[4:]))
+	case stateRecord:
+		order := cdb.opts.order()
+		keyLength, valueLength := order.Uint32(buf), order.Uint32(buf[4:])
+		err := cdb.checkRecord(l.offset, keyLength, valueLength)
+		if err != nil {
+			return err
+		}
+
+		// The whole key is always in the buffer, if the lengths are right.
+		if int(keyLength) != len(l.key) || !bytes.Equal(buf[8:8+keyLength], l.key) {
+			l.state = stateSlot
+			return nil
+		}
+
+		// Keep the part of the value we have, and read the rest, if any.
+		l.buf = buf[8+keyLength:]
+		if uint32(len(l.buf)) >= valueLength {
+			l.value = l.buf[:valueLength]
+			cdb.foundVectored(l)
+			return nil
+		}
+
+		l.value = make([]byte, valueLength)
+		copy(l.value, l.buf)
+		l.state = stateRest
+	case stateRest:
+		cdb.foundVectored(l)
+	}
+
+	return nil
+}
+
+// foundVectored finishes a lookup once it's read a whole record with a
+// matching key, unless it's expired, in which case the lookup carries on.
+func (cdb *CDB) foundVectored(l *vectoredLookup) {
+	l.buf = nil
+	if cdb.expired(l.value) {
+		l.value = nil
+		l.state = stateSlot
+		return
+	}
+
+	cdb.finishVectored(l, true)
+}
+
+func (cdb *CDB) finishVectored(l *vectoredLookup, found bool) {
+	l.state = stateDone
+	if !found {
+		l.value = nil
+		l.offset = 0
+	}
+
+	l.probe.finish()
+	cdb.observeGet(found, &l.probe)
+}
+
+// readAtv fills each of bufs from the corresponding offset, with a single
+// call to ReadAtv if r supports it.
+func readAtv(r io.ReaderAt, bufs [][]byte, offsets []int64) error {
+	if rv, ok := r.(ReaderAtv); ok {
+		_, err := rv.ReadAtv(bufs, offsets)
+		return err
+	}
+
+	_, err := readEach(r, bufs, offsets)
+	return err
+}
+
+// readEach fills each of bufs from the corresponding offset with ReadAt.
+func readEach(r io.ReaderAt, bufs [][]byte, offsets []int64) (int, error) {
+	total := 0
+	for i, buf := range bufs {
+		n, err := r.ReadAt(buf, offsets[i])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func totalLength(bufs [][]byte) int {
+	n := 0
+	for _, buf := range bufs {
+		n += len(buf)
+	}
+
+	return n
+}
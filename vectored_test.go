@@ -0,0 +1,119 @@
+package cdb_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vectoredReader is a ReaderAtv that counts requests.
+type vectoredReader struct {
+	*bytes.Reader
+	requests int
+}
+
+func (vr *vectoredReader) ReadAt(b []byte, off int64) (int, error) {
+	vr.requests++
+	return vr.Reader.ReadAt(b, off)
+}
+
+func (vr *vectoredReader) ReadAtv(bufs [][]byte, offsets []int64) (int, error) {
+	vr.requests++
+	total := 0
+	for i, buf := range bufs {
+		n, err := vr.Reader.ReadAt(buf, offsets[i])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func buildVectoredTestData(t *testing.T) ([]byte, map[string][]byte) {
+	var buf bytes.Buffer
+	expected := make(map[string][]byte)
+	writer, err := cdb.NewStreamWriter(&buf, "", nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		value := bytes.Repeat([]byte{byte(i)}, i*50)
+		require.NoError(t, writer.Put([]byte(key), value))
+		expected[key] = value
+	}
+
+	// Only the first value for a key is returned.
+	require.NoError(t, writer.Put([]byte("key1"), []byte("shadowed")))
+	require.NoError(t, writer.Close())
+	return buf.Bytes(), expected
+}
+
+func TestVectoredGet(t *testing.T) {
+	data, expected := buildVectoredTestData(t)
+	reader := &vectoredReader{Reader: bytes.NewReader(data)}
+	db, err := cdb.New(reader, nil)
+	require.NoError(t, err)
+
+	for key, value := range expected {
+		reader.requests = 0
+		got, err := db.Get([]byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, value, got, key)
+
+		// One request for the slot, and one for the record, unless the value
+		// is too long to read up front or the hash collides.
+		if len(value) < 1024 {
+			assert.True(t, reader.requests <= 3, "%s took %d requests", key, reader.requests)
+		}
+	}
+
+	reader.requests = 0
+	got, err := db.Get([]byte("key0"))
+	require.NoError(t, err)
+	assert.NotNil(t, got)
+	assert.Empty(t, got)
+	assert.Equal(t, 2, reader.requests)
+
+	got, err = db.Get([]byte("missing"))
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestGetVectored(t *testing.T) {
+	data, expected := buildVectoredTestData(t)
+	keys := [][]byte{[]byte("missing")}
+	for key := range expected {
+		keys = append(keys, []byte(key))
+	}
+
+	reader := &vectoredReader{Reader: bytes.NewReader(data)}
+	vectored, err := cdb.New(reader, nil)
+	require.NoError(t, err)
+
+	plain, err := cdb.New(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+
+	for _, db := range []*cdb.CDB{vectored, plain} {
+		reader.requests = 0
+		values, err := db.GetVectored(keys)
+		require.NoError(t, err)
+		require.Len(t, values, len(keys))
+
+		assert.Nil(t, values[0])
+		for i, key := range keys[1:] {
+			assert.Equal(t, expected[string(key)], values[i+1], string(key))
+		}
+	}
+
+	// The whole batch shares a handful of round trips.
+	reader.requests = 0
+	_, err = vectored.GetVectored(keys)
+	require.NoError(t, err)
+	assert.True(t, reader.requests <= 5, "took %d requests", reader.requests)
+}
@@ -0,0 +1,29 @@
+package cdb
+
+// View returns a read-only view of the database that only contains the keys
+// for which filter returns true. The view shares the underlying reader, so
+// creating one is cheap, and nothing is copied. Calling View on a view
+// returns a view containing the keys that pass both filters.
+//
+// Get, GetInto, Iter, and EachRecord (and so Diff) hide keys that don't match
+// the filter. Methods that work on the hash tables or raw offsets, such as
+// Len, Stats, and GetAt, see the whole database.
+//
+// Closing a view closes the underlying database, and every other view of it.
+func (cdb *CDB) View(filter func(key []byte) bool) *CDB {
+	view := *cdb
+	if parent := cdb.filter; parent != nil {
+		view.filter = func(key []byte) bool {
+			return parent(key) && filter(key)
+		}
+	} else {
+		view.filter = filter
+	}
+
+	return &view
+}
+
+// hidden returns true if key is excluded from the database by a filter.
+func (cdb *CDB) hidden(key []byte) bool {
+	return cdb.filter != nil && !cdb.filter(key)
+}
@@ -0,0 +1,56 @@
+package cdb_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestView(t *testing.T) {
+	db := makeDB(t, [][2]string{
+		{"a/foo", "1"},
+		{"b/bar", "2"},
+		{"a/baz", "3"},
+		{"b/qux", "4"},
+	})
+
+	a := db.View(func(key []byte) bool { return bytes.HasPrefix(key, []byte("a/")) })
+	assertRecords(t, a, [][2]string{{"a/foo", "1"}, {"a/baz", "3"}})
+
+	value, err := a.Get([]byte("a/baz"))
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(value))
+
+	value, err = a.Get([]byte("b/bar"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	_, found, err := a.GetInto([]byte("b/bar"), make([]byte, 8))
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	var keys []string
+	err = a.EachRecord(func(rec cdb.Record) error {
+		key, err := rec.Key()
+		keys = append(keys, string(key))
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/foo", "a/baz"}, keys)
+
+	// Views of views apply both filters.
+	nested := a.View(func(key []byte) bool { return bytes.HasSuffix(key, []byte("z")) })
+	assertRecords(t, nested, [][2]string{{"a/baz", "3"}})
+
+	// The original database is unaffected.
+	value, err = db.Get([]byte("b/bar"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(value))
+
+	require.NoError(t, a.Close())
+	_, err = db.Get([]byte("b/bar"))
+	assert.Equal(t, cdb.ErrClosed, err)
+}
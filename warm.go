@@ -0,0 +1,129 @@
+package cdb
+
+import (
+	"context"
+	"time"
+)
+
+// The default size of the reads made by Warm.
+const defaultWarmChunkSize = 1 << 20
+
+// WarmOptions configures Warm. A nil *WarmOptions uses the defaults.
+type WarmOptions struct {
+	// Data causes the data section to be read as well as the index and hash
+	// tables. Without it, lookups still need to read each record from disk,
+	// but never more than once.
+	Data bool
+	// BytesPerSecond limits the rate at which Warm reads, so that it doesn't
+	// compete with other I/O. If it's zero, there's no limit.
+	BytesPerSecond int64
+	// ChunkSize is the size of each read. It defaults to 1MB.
+	ChunkSize int
+}
+
+// Warm reads the index and hash tables from beginning to end, and optionally
+// the data section too, so that they're in the operating system's page cache
+// before the first lookups. This avoids the slow first requests of a freshly
+// deployed database, at the cost of reading the regions up front.
+//
+// Warm blocks until it's done or ctx is cancelled, in which case it returns
+// the context's error. It's safe to call alongside lookups, for example in a
+// separate goroutine after opening a database.
+func (cdb *CDB) Warm(ctx context.Context, opts *WarmOptions) error {
+	if opts == nil {
+		opts = &WarmOptions{}
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultWarmChunkSize
+	}
+
+	err := cdb.acquire(opScan)
+	if err != nil {
+		return err
+	}
+	defer cdb.release(opScan)
+
+	w := warmer{
+		cdb:   cdb,
+		ctx:   ctx,
+		buf:   make([]byte, chunkSize),
+		rate:  opts.BytesPerSecond,
+		start: time.Now(),
+	}
+
+	end := int64(indexSize)
+	if opts.Data {
+		end = int64(cdb.index[0].offset)
+	}
+
+	err = w.read(0, end)
+	if err != nil || cdb.tables != nil {
+		return err
+	}
+
+	start, tablesEnd := cdb.tablesRegion()
+	return w.read(int64(start), int64(tablesEnd))
+}
+
+// warmer reads regions of a file for Warm, keeping to a rate limit.
+type warmer struct {
+	cdb   *CDB
+	ctx   context.Context
+	buf   []byte
+	rate  int64
+	start time.Time
+	total int64
+}
+
+func (w *warmer) read(start, end int64) error {
+	for off := start; off < end; {
+		err := w.ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		chunk := w.buf
+		if end-off < int64(len(chunk)) {
+			chunk = chunk[:end-off]
+		}
+
+		_, err = w.cdb.reader.ReadAt(chunk, off)
+		if err != nil {
+			return readError(ErrCorruptIndex, off, err)
+		}
+
+		off += int64(len(chunk))
+		w.total += int64(len(chunk))
+		err = w.wait()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// wait sleeps until the bytes read so far are within the rate limit.
+func (w *warmer) wait() error {
+	if w.rate <= 0 {
+		return nil
+	}
+
+	due := w.start.Add(time.Duration(float64(w.total) / float64(w.rate) * float64(time.Second)))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
@@ -0,0 +1,62 @@
+package cdb_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/cdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rangeReader records the total number of bytes read.
+type rangeReader struct {
+	countingReader
+	bytes int64
+}
+
+func (rr *rangeReader) ReadAt(b []byte, off int64) (int, error) {
+	rr.bytes += int64(len(b))
+	return rr.countingReader.ReadAt(b, off)
+}
+
+func TestWarm(t *testing.T) {
+	f, err := os.Open("./test/test.cdb")
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader := &rangeReader{countingReader: countingReader{ReaderAt: f}}
+	db, err := cdb.New(reader, nil)
+	require.NoError(t, err)
+
+	reader.bytes = 0
+	require.NoError(t, db.Warm(context.Background(), nil))
+	assert.EqualValues(t, cdb.DataOffset+db.TablesSize(), reader.bytes)
+
+	info, err := f.Stat()
+	require.NoError(t, err)
+
+	reader.bytes, reader.reads = 0, 0
+	require.NoError(t, db.Warm(context.Background(), &cdb.WarmOptions{Data: true, ChunkSize: 16}))
+	assert.EqualValues(t, info.Size(), reader.bytes)
+	assert.True(t, reader.reads > info.Size()/16)
+}
+
+func TestWarmRateLimit(t *testing.T) {
+	db, err := cdb.Open("./test/test.cdb")
+	require.NoError(t, err)
+
+	// Reading the index alone should take about 100ms at this rate.
+	start := time.Now()
+	opts := &cdb.WarmOptions{BytesPerSecond: cdb.DataOffset * 10, ChunkSize: 256}
+	require.NoError(t, db.Warm(context.Background(), opts))
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	opts.BytesPerSecond = 1
+	assert.Equal(t, context.DeadlineExceeded, db.Warm(ctx, opts))
+}
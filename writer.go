@@ -4,27 +4,48 @@ import (
 	"bufio"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
 	"math"
 	"os"
-	"sync"
+	"time"
 )
 
 var ErrTooMuchData = errors.New("CDB files are limited to 4GB of data")
 
+// ErrFinalized is returned by a Writer's methods once the database has been
+// finalized by Close or Freeze. It matches ErrWriterClosed, with errors.Is.
+var ErrFinalized = fmt.Errorf("%w: database is already finalized", ErrWriterClosed)
+
 // Writer provides an API for creating a CDB database record by record.
 //
 // Close or Freeze must be called to finalize the database, or the resulting
 // file will be invalid.
 type Writer struct {
-	hash         func([]byte) uint32
-	writer       io.WriteSeeker
-	entries      [256][]entry
-	finalizeOnce sync.Once
+	hash       func([]byte) uint32
+	customHash bool
+	writer     interface{} // an io.WriterAt or io.WriteSeeker
+	sink       io.Writer
+	opts       options
+	entries    [256][]entry
+	dead       []deadRecord
+	finalized  bool
 
 	bufferedWriter      *bufio.Writer
 	bufferedOffset      int64
 	estimatedFooterSize int64
+	records             int64
+	unsynced            int64
+	tablesChecksum      hash.Hash32
+	metadata            map[string]string
+
+	// path is set if the Writer was created with Create, and appendOrigin if
+	// it was opened for appending, so that Abort can undo the changes.
+	path         string
+	appendOrigin *appendOrigin
+	aborted      bool
 }
 
 type entry struct {
@@ -34,88 +55,357 @@ type entry struct {
 
 // Create opens a CDB database at the given path. If the file exists, it will
 // be overwritten. The returned database is not safe for concurrent writes.
-func Create(path string) (*Writer, error) {
-	f, err := os.Create(path)
+func Create(path string, opts ...Option) (*Writer, error) {
+	f, err := openFile(path, os.O_RDWR|os.O_CREATE, buildOptions(opts))
 	if err != nil {
 		return nil, err
 	}
 
-	return NewWriter(f, nil)
+	// The file is only truncated once it's locked, if it's going to be.
+	err = f.Truncate(0)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	writer, err := NewWriter(f, nil, opts...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	writer.path = path
+	return writer, nil
 }
 
-// NewWriter opens a CDB database for the given io.WriteSeeker.
+// NewWriter opens a CDB database for the given stream, which must be an
+// io.WriterAt or an io.WriteSeeker; otherwise, NewWriter returns
+// os.ErrInvalid. If it's an io.WriterAt, the database is written entirely with
+// WriteAt, relative to the start of the stream, and Seek is never called.
 //
 // If hash is nil, it will default to the CDB hash function.
-func NewWriter(writer io.WriteSeeker, hash func([]byte) uint32) (*Writer, error) {
+func NewWriter(writer io.Writer, hash func([]byte) uint32, opts ...Option) (*Writer, error) {
+	return newWriter(writer, hash, opts)
+}
+
+// NewWriterAt opens a CDB database for the given io.WriterAt, which is used
+// for every write, relative to the start of the stream. Since WriteAt doesn't
+// use or change the file position, finalizing the database doesn't disturb
+// anything else using the same file descriptor.
+//
+// Freeze and Delete work if writer is also an io.ReaderAt, and Close closes
+// it if it's an io.Closer. If hash is nil, it will default to the CDB hash
+// function.
+func NewWriterAt(writer io.WriterAt, hash func([]byte) uint32, opts ...Option) (*Writer, error) {
+	return newWriter(writer, hash, opts)
+}
+
+func newWriter(writer interface{}, hash func([]byte) uint32, opts []Option) (*Writer, error) {
+	customHash := hash != nil
+	if hash == nil {
+		hash = cdbHash
+	}
+
+	cdb := &Writer{
+		hash:       hash,
+		customHash: customHash,
+		writer:     writer,
+		opts:       buildOptions(opts),
+	}
+
+	err := cdb.preallocate()
+	if err != nil {
+		return nil, err
+	}
+
+	cdb.preallocateEntries()
+
 	// Leave 256 * 8 bytes for the index at the head of the file.
-	_, err := writer.Seek(0, os.SEEK_SET)
+	err = cdb.writeAt(make([]byte, indexSize), 0)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = writer.Write(make([]byte, indexSize))
+	err = cdb.resetBuffer(indexSize)
 	if err != nil {
 		return nil, err
 	}
 
-	if hash == nil {
-		hash = cdbHash
+	return cdb, nil
+}
+
+// writeAt writes b at the given offset in the stream, using WriteAt if
+// possible, and Seek otherwise.
+func (cdb *Writer) writeAt(b []byte, offset int64) error {
+	switch w := cdb.writer.(type) {
+	case io.WriterAt:
+		_, err := w.WriteAt(b, offset)
+		return err
+	case io.WriteSeeker:
+		_, err := w.Seek(offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(b)
+		return err
+	default:
+		return os.ErrInvalid
+	}
+}
+
+// resetBuffer sets up the buffered writer to continue writing records at the
+// given offset in the stream. Anything in the previous buffer is discarded.
+func (cdb *Writer) resetBuffer(offset int64) error {
+	switch w := cdb.writer.(type) {
+	case io.WriterAt:
+		cdb.bufferedWriter = bufio.NewWriterSize(io.NewOffsetWriter(w, offset), 65536)
+	case io.WriteSeeker:
+		_, err := w.Seek(offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+
+		cdb.bufferedWriter = bufio.NewWriterSize(w, 65536)
+	default:
+		return os.ErrInvalid
 	}
 
-	return &Writer{
-		hash:           hash,
-		writer:         writer,
-		bufferedWriter: bufio.NewWriterSize(writer, 65536),
-		bufferedOffset: indexSize,
-	}, nil
+	cdb.bufferedOffset = offset
+	return nil
 }
 
 // Put adds a key/value pair to the database. If the amount of data written
 // would exceed the limit, Put returns ErrTooMuchData.
 func (cdb *Writer) Put(key, value []byte) error {
+	return cdb.put(key, value, time.Time{})
+}
+
+// PutHashed is like Put, but uses a hash for the key computed in advance, for
+// callers that already have it, such as from routing the key to a shard. The
+// hash must be the one the Writer's hash function returns for the key, after
+// any normalization applied by WithKeyNormalizer; otherwise, the key won't be
+// found in the finished database.
+func (cdb *Writer) PutHashed(hash uint32, key, value []byte) error {
+	key = cdb.opts.applyNormalizer(key)
+	return cdb.putHashed(hash, key, cdb.opts.storedKey(key), value, time.Time{})
+}
+
+func (cdb *Writer) put(key, value []byte, expires time.Time) error {
+	key = cdb.opts.applyNormalizer(key)
+	stored := cdb.opts.storedKey(key)
+	return cdb.putHashed(cdb.hash(stored), key, stored, value, expires)
+}
+
+// putStored adds a record with a key that's already been normalized, such as
+// one read back from another database, along with its expiration time.
+func (cdb *Writer) putStored(key, value []byte, expires time.Time) error {
+	return cdb.putHashed(cdb.hash(key), key, key, value, expires)
+}
+
+// putHashed adds a record under the stored key. Validators are called with
+// key, the normalized key before any HMAC or hash replaced it, so that they
+// check what the caller passed in.
+func (cdb *Writer) putHashed(hash uint32, key, stored, value []byte, expires time.Time) error {
+	err := cdb.checkWritable()
+	if err != nil {
+		return err
+	}
+
+	err = cdb.opts.checkLimits(int64(len(stored)), int64(len(value)))
+	if err != nil {
+		return err
+	}
+
+	err = cdb.validate(key, value)
+	if err != nil {
+		return err
+	}
+
+	key = stored
+	value, err = cdb.encodeValue(key, value, expires)
+	if err != nil {
+		return err
+	}
+
 	entrySize := int64(8 + len(key) + len(value))
-	if (cdb.bufferedOffset + entrySize + cdb.estimatedFooterSize + 16) > math.MaxUint32 {
+	err = cdb.writeHeader(key, uint32(len(value)), entrySize)
+	if err != nil {
+		return err
+	}
+
+	_, err = cdb.bufferedWriter.Write(value)
+	if err != nil {
+		return err
+	}
+
+	cdb.addEntry(hash, entrySize)
+	return cdb.syncEvery(entrySize)
+}
+
+// PutReader adds a key/value pair to the database, streaming the value from r
+// instead of requiring it to be in memory. Exactly valueLength bytes are read
+// from r; if it returns fewer, PutReader returns io.ErrUnexpectedEOF.
+//
+// Validators registered with WithValidator are called with a nil value, since
+// the value isn't available up front. With WithEncryption, the value is read
+// into memory and passed to Put instead, since it has to be encrypted whole.
+//
+// If PutReader fails after it begins copying the value, the partially
+// written record can't be removed, and the Writer should be discarded.
+func (cdb *Writer) PutReader(key []byte, valueLength uint32, r io.Reader) error {
+	err := cdb.checkWritable()
+	if err != nil {
+		return err
+	}
+
+	if cdb.opts.aead != nil {
+		value := make([]byte, valueLength)
+		_, err := io.ReadFull(r, value)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+
+		return cdb.Put(key, value)
+	}
+
+	key = cdb.opts.applyNormalizer(key)
+	err = cdb.validate(key, nil)
+	if err != nil {
+		return err
+	}
+
+	key = cdb.opts.storedKey(key)
+	err = cdb.opts.checkLimits(int64(len(key)), int64(valueLength))
+	if err != nil {
+		return err
+	}
+
+	// The value is stored as-is, behind any prefix the options call for.
+	prefix := cdb.streamPrefix()
+	storedLength := int64(len(prefix)) + int64(valueLength)
+	if storedLength > math.MaxUint32 {
 		return ErrTooMuchData
 	}
 
-	// Record the entry in the hash table, to be written out at the end.
-	hash := cdb.hash(key)
-	table := hash & 0xff
+	entrySize := int64(8+len(key)) + storedLength
+	err = cdb.writeHeader(key, uint32(storedLength), entrySize)
+	if err != nil {
+		return err
+	}
 
-	entry := entry{hash: hash, offset: uint32(cdb.bufferedOffset)}
-	cdb.entries[table] = append(cdb.entries[table], entry)
+	_, err = cdb.bufferedWriter.Write(prefix)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(cdb.bufferedWriter, r, int64(valueLength))
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+
+	cdb.addEntry(cdb.hash(key), entrySize)
+	return cdb.syncEvery(entrySize)
+}
+
+// checkWritable returns ErrAborted or ErrFinalized if the Writer can't be
+// changed any more.
+func (cdb *Writer) checkWritable() error {
+	if cdb.aborted {
+		return ErrAborted
+	} else if cdb.finalized {
+		return ErrFinalized
+	}
+
+	return nil
+}
 
-	// Write the key length, then value length, then key, then value.
-	err := writeTuple(cdb.bufferedWriter, uint32(len(key)), uint32(len(value)))
+func (cdb *Writer) validate(key, value []byte) error {
+	for _, fn := range cdb.opts.validators {
+		err := fn(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeHeader checks that a record will fit in the database, then writes any
+// padding needed before it, followed by the key length, value length, and
+// key.
+func (cdb *Writer) writeHeader(key []byte, valueLength uint32, entrySize int64) error {
+	pad := cdb.opts.align(cdb.bufferedOffset) - cdb.bufferedOffset
+	err := cdb.checkSize(pad + entrySize)
 	if err != nil {
 		return err
 	}
 
-	_, err = cdb.bufferedWriter.Write(key)
+	err = cdb.writePadding()
 	if err != nil {
 		return err
 	}
 
-	_, err = cdb.bufferedWriter.Write(value)
+	err = writeTuple(cdb.bufferedWriter, cdb.opts.order(), uint32(len(key)), valueLength)
 	if err != nil {
 		return err
 	}
 
+	_, err = cdb.bufferedWriter.Write(key)
+	return err
+}
+
+// addEntry records a newly written record with the given hash in the hash
+// table, to be written out at the end.
+func (cdb *Writer) addEntry(hash uint32, entrySize int64) {
+	table := hash & 0xff
+
+	entry := entry{hash: hash, offset: uint32(cdb.bufferedOffset)}
+	cdb.entries[table] = append(cdb.entries[table], entry)
+
 	cdb.bufferedOffset += entrySize
-	cdb.estimatedFooterSize += 16
-	return nil
+	cdb.estimatedFooterSize += cdb.footerSizePerEntry()
+	cdb.records++
+	cdb.reportProgress(PhaseWriting)
+}
+
+// EntryCount returns the number of records written so far, not counting any
+// that have been deleted.
+func (cdb *Writer) EntryCount() int64 {
+	return cdb.records
+}
+
+// EstimatedSize returns an upper bound on the size of the file if it were
+// finalized now, including the hash tables and anything else written when the
+// database is finalized. Put returns ErrTooMuchData once a record would take
+// it past 4GB, or past the limit set with WithMaxSize, so comparing it against
+// the limit is a way to decide when to start a new shard.
+//
+// The hash tables are kept in memory until the database is finalized, taking
+// about eight bytes per record.
+func (cdb *Writer) EstimatedSize() int64 {
+	return cdb.bufferedOffset + cdb.estimatedFooterSize + 256*8 + cdb.extensionsSize(cdb.records)
 }
 
 // Close finalizes the database, then closes it to further writes.
 //
 // Close or Freeze must be called to finalize the database, or the resulting
-// file will be invalid.
+// file will be invalid. The database can only be finalized once, even if that
+// fails: afterwards, Close, Freeze, and the methods that add records return
+// ErrFinalized. In particular, calling Close after Freeze doesn't close the
+// underlying stream, which the frozen database still reads from.
 func (cdb *Writer) Close() error {
-	var err error
-	cdb.finalizeOnce.Do(func() {
-		_, err = cdb.finalize()
-	})
+	err := cdb.checkWritable()
+	if err != nil {
+		return err
+	}
 
+	cdb.finalized = true
+	_, err = cdb.finalize()
 	if err != nil {
 		return err
 	}
@@ -131,54 +421,108 @@ func (cdb *Writer) Close() error {
 // be converted to a io.ReaderAt, Freeze will return os.ErrInvalid.
 //
 // Close or Freeze must be called to finalize the database, or the resulting
-// file will be invalid.
+// file will be invalid. Like Close, Freeze returns ErrFinalized if the
+// database has already been finalized.
 func (cdb *Writer) Freeze() (*CDB, error) {
-	var err error
-	var index index
-	cdb.finalizeOnce.Do(func() {
-		index, err = cdb.finalize()
-	})
+	err := cdb.checkWritable()
+	if err != nil {
+		return nil, err
+	}
 
+	cdb.finalized = true
+	index, err := cdb.finalize()
 	if err != nil {
 		return nil, err
 	}
 
+	// Now that the database is complete, other processes can read it too.
+	if f, ok := cdb.writer.(*os.File); ok && cdb.opts.locking {
+		err = lockFile(f, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if readerAt, ok := cdb.writer.(io.ReaderAt); ok {
-		return &CDB{reader: readerAt, index: index, hash: cdb.hash}, nil
+		db := &CDB{reader: readerAt, hash: cdb.hash, opts: cdb.opts, customHash: cdb.customHash}
+		err = db.init(&index)
+		if err != nil {
+			return nil, err
+		}
+
+		return db, nil
 	} else {
 		return nil, os.ErrInvalid
 	}
 }
 
+// tableLength returns the number of slots in a hash table with the given
+// number of entries.
+func (cdb *Writer) tableLength(entries int) uint32 {
+	f := cdb.opts.loadFactor
+	if f == 0 {
+		f = DefaultLoadFactor
+	}
+
+	return uint32(math.Ceil(float64(entries) / f))
+}
+
+// footerSizePerEntry returns the amount of hash table space used by an entry,
+// in bytes.
+func (cdb *Writer) footerSizePerEntry() int64 {
+	f := cdb.opts.loadFactor
+	if f == 0 {
+		f = DefaultLoadFactor
+	}
+
+	return int64(math.Ceil(8 / f))
+}
+
 func (cdb *Writer) finalize() (index, error) {
 	var index index
 
+	// Remove any records that were deleted, so that they don't show up when
+	// iterating.
+	if len(cdb.dead) > 0 {
+		cdb.reportProgress(PhaseCompacting)
+		err := cdb.compact()
+		if err != nil {
+			return index, err
+		}
+	}
+
+	if cdb.opts.grouping != 0 {
+		cdb.reportProgress(PhaseGrouping)
+		err := cdb.regroup()
+		if err != nil {
+			return index, err
+		}
+	}
+
+	waitSync, err := cdb.startSync()
+	if err != nil {
+		return index, err
+	}
+
 	// Write the hashtables out, one by one, at the end of the file.
+	cdb.reportProgress(PhaseTables)
+	var tablesWriter io.Writer = cdb.bufferedWriter
+	if cdb.opts.header {
+		cdb.tablesChecksum = crc32.New(castagnoli)
+		tablesWriter = io.MultiWriter(cdb.bufferedWriter, cdb.tablesChecksum)
+	}
+
 	for i := 0; i < 256; i++ {
 		tableEntries := cdb.entries[i]
-		tableSize := uint32(len(tableEntries) << 1)
+		tableSize := cdb.tableLength(len(tableEntries))
 
 		index[i] = table{
 			offset: uint32(cdb.bufferedOffset),
 			length: tableSize,
 		}
 
-		sorted := make([]entry, tableSize)
-		for _, entry := range tableEntries {
-			slot := (entry.hash >> 8) % tableSize
-
-			for {
-				if sorted[slot].hash == 0 {
-					sorted[slot] = entry
-					break
-				}
-
-				slot = (slot + 1) % tableSize
-			}
-		}
-
-		for _, entry := range sorted {
-			err := writeTuple(cdb.bufferedWriter, entry.hash, entry.offset)
+		for _, entry := range layoutTable(tableEntries, tableSize) {
+			err := writeTuple(tablesWriter, cdb.opts.order(), entry.hash, entry.offset)
 			if err != nil {
 				return index, err
 			}
@@ -190,30 +534,77 @@ func (cdb *Writer) finalize() (index, error) {
 		}
 	}
 
+	err = cdb.writeExtensions()
+	if err != nil {
+		return index, err
+	} else if cdb.bufferedOffset > math.MaxUint32 {
+		return index, ErrTooMuchData
+	}
+
 	// We're done with the buffer.
-	err := cdb.bufferedWriter.Flush()
+	err = cdb.bufferedWriter.Flush()
 	cdb.bufferedWriter = nil
 	if err != nil {
 		return index, err
 	}
 
-	// Seek to the beginning of the file and write out the index.
-	_, err = cdb.writer.Seek(0, os.SEEK_SET)
+	err = waitSync()
 	if err != nil {
 		return index, err
 	}
 
-	buf := make([]byte, indexSize)
-	for i, table := range index {
-		off := i * 8
-		binary.LittleEndian.PutUint32(buf[off:off+4], table.offset)
-		binary.LittleEndian.PutUint32(buf[off+4:off+8], table.length)
+	// Go back to the beginning of the file and write out the index.
+	cdb.reportProgress(PhaseIndex)
+	err = cdb.writeAt(index.marshal(cdb.opts.order()), 0)
+	if err != nil {
+		return index, err
 	}
 
-	_, err = cdb.writer.Write(buf)
+	err = cdb.finishSync()
 	if err != nil {
 		return index, err
 	}
 
+	if cdb.sink != nil {
+		err = cdb.streamOut(cdb.bufferedOffset)
+		if err != nil {
+			return index, err
+		}
+	}
+
+	cdb.reportProgress(PhaseDone)
 	return index, nil
 }
+
+// layoutTable places the entries in the slots of a hash table with the given
+// number of slots, in order, each in the first empty slot from the one its
+// hash selects.
+func layoutTable(entries []entry, length uint32) []entry {
+	slots := make([]entry, length)
+	for _, entry := range entries {
+		slot := (entry.hash >> 8) % length
+
+		for {
+			if slots[slot].offset == 0 {
+				slots[slot] = entry
+				break
+			}
+
+			slot = (slot + 1) % length
+		}
+	}
+
+	return slots
+}
+
+// marshal encodes the index as it's stored at the start of the file.
+func (idx *index) marshal(order binary.ByteOrder) []byte {
+	buf := make([]byte, indexSize)
+	for i, table := range idx {
+		off := i * 8
+		order.PutUint32(buf[off:off+4], table.offset)
+		order.PutUint32(buf[off+4:off+8], table.length)
+	}
+
+	return buf
+}
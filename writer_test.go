@@ -1,13 +1,18 @@
 package cdb_test
 
 import (
+	"bytes"
+	"errors"
 	"hash/fnv"
+	"io"
 	"io/ioutil"
 	"log"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"testing/quick"
 	"time"
@@ -70,6 +75,52 @@ func TestWritesReadableFnv(t *testing.T) {
 	testWritesReadable(t, writer)
 }
 
+// memWriterAt is an in-memory io.WriterAt and io.ReaderAt that can't seek.
+type memWriterAt struct {
+	buf []byte
+}
+
+func (m *memWriterAt) Write(b []byte) (int, error) {
+	return 0, errors.New("Write should not be called")
+}
+
+func (m *memWriterAt) WriteAt(b []byte, off int64) (int, error) {
+	if end := int(off) + len(b); end > len(m.buf) {
+		m.buf = append(m.buf, make([]byte, end-len(m.buf))...)
+	}
+
+	return copy(m.buf[off:], b), nil
+}
+
+func (m *memWriterAt) ReadAt(b []byte, off int64) (int, error) {
+	return bytes.NewReader(m.buf).ReadAt(b, off)
+}
+
+func TestWritesReadableWriterAt(t *testing.T) {
+	writer, err := cdb.NewWriter(&memWriterAt{}, nil)
+	require.NoError(t, err)
+
+	testWritesReadable(t, writer)
+}
+
+func TestDeleteWriterAt(t *testing.T) {
+	writer, err := cdb.NewWriter(&memWriterAt{}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Put([]byte("baz"), []byte("quux")))
+	require.NoError(t, writer.Delete([]byte("foo")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	assertRecords(t, db, [][2]string{{"baz", "quux"}})
+}
+
+func TestNewWriterNotSeekable(t *testing.T) {
+	_, err := cdb.NewWriter(new(bytes.Buffer), nil)
+	assert.Equal(t, os.ErrInvalid, err)
+}
+
 func testWritesRandom(t *testing.T, writer *cdb.Writer) {
 	random := rand.New(rand.NewSource(time.Now().UnixNano()))
 	records := make([][][]byte, 0, 1000)
@@ -129,6 +180,45 @@ func TestWritesRandomFnv(t *testing.T) {
 	testWritesRandom(t, writer)
 }
 
+func TestPutReader(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+
+	value := strings.Repeat("streamed value ", 100000)
+	err = writer.PutReader([]byte("big"), uint32(len(value)), strings.NewReader(value))
+	require.NoError(t, err)
+
+	err = writer.Put([]byte("small"), []byte("value"))
+	require.NoError(t, err)
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	v, err := db.Get([]byte("big"))
+	require.NoError(t, err)
+	assert.Equal(t, value, string(v))
+
+	v, err = db.Get([]byte("small"))
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(v))
+}
+
+func TestPutReaderShort(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+
+	err = writer.PutReader([]byte("key"), 100, strings.NewReader("too short"))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
 func benchmarkPut(b *testing.B, writer *cdb.Writer) {
 	random := rand.New(rand.NewSource(time.Now().UnixNano()))
 	stringType := reflect.TypeOf("")
@@ -186,3 +276,151 @@ func ExampleWriter() {
 	// records.
 	writer.Close()
 }
+
+func TestPutHashed(t *testing.T) {
+	writer := cdb.NewMem()
+	for _, record := range expectedRecords {
+		if record[1] != nil {
+			require.NoError(t, writer.PutHashed(cdb.HashKey(record[0]), record[0], record[1]))
+		}
+	}
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	for _, record := range expectedRecords {
+		value, err := db.GetHashed(cdb.HashKey(record[0]), record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+
+		value, err = db.Get(record[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(record[1]), string(value))
+	}
+
+	// A wrong hash means the key can't be found.
+	value, err := db.GetHashed(cdb.HashKey([]byte("foo"))+1, []byte("foo"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+// readerWriterAt is an io.WriterAt and io.ReaderAt, and nothing else.
+type readerWriterAt struct {
+	mem *memWriterAt
+}
+
+func (rw readerWriterAt) WriteAt(b []byte, off int64) (int, error) {
+	return rw.mem.WriteAt(b, off)
+}
+
+func (rw readerWriterAt) ReadAt(b []byte, off int64) (int, error) {
+	return rw.mem.ReadAt(b, off)
+}
+
+func TestNewWriterAt(t *testing.T) {
+	writer, err := cdb.NewWriterAt(readerWriterAt{&memWriterAt{}}, nil)
+	require.NoError(t, err)
+
+	testWritesReadable(t, writer)
+}
+
+func TestNewWriterAtKeepsFilePosition(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "writerat.cdb"))
+	require.NoError(t, err)
+
+	writer, err := cdb.NewWriterAt(f, nil)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+	defer db.Close()
+
+	pos, err := f.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pos)
+
+	value, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(value))
+}
+
+func TestEstimatedSize(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil, cdb.WithHeader())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), writer.EntryCount())
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, writer.Put([]byte(strconv.Itoa(i)), []byte("value")))
+	}
+
+	require.NoError(t, writer.Delete([]byte("0")))
+	assert.Equal(t, int64(999), writer.EntryCount())
+
+	estimate := writer.EstimatedSize()
+	require.NoError(t, writer.Close())
+
+	info, err := os.Stat(f.Name())
+	require.NoError(t, err)
+	assert.True(t, estimate >= info.Size(), "%d < %d", estimate, info.Size())
+	assert.True(t, estimate-info.Size() <= 256*8+100, "%d is too far from %d", estimate, info.Size())
+}
+
+func TestPutAfterClose(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+	require.NoError(t, writer.Close())
+
+	assert.Equal(t, cdb.ErrFinalized, writer.Put([]byte("baz"), []byte("qux")))
+	assert.Equal(t, cdb.ErrFinalized, writer.PutReader([]byte("baz"), 3, strings.NewReader("qux")))
+	assert.Equal(t, cdb.ErrFinalized, writer.Delete([]byte("foo")))
+	assert.Equal(t, cdb.ErrFinalized, writer.SetMetadata("foo", "bar"))
+	assert.True(t, errors.Is(writer.Put([]byte("baz"), []byte("qux")), cdb.ErrWriterClosed))
+
+	assert.Equal(t, cdb.ErrFinalized, writer.Close())
+	_, err = writer.Freeze()
+	assert.Equal(t, cdb.ErrFinalized, err)
+
+	db, err := cdb.Open(f.Name())
+	require.NoError(t, err)
+	defer db.Close()
+
+	v, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(v))
+
+	n, err := db.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestCloseAfterFreeze(t *testing.T) {
+	f, err := ioutil.TempFile("", "test-cdb")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	writer, err := cdb.NewWriter(f, nil)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put([]byte("foo"), []byte("bar")))
+
+	db, err := writer.Freeze()
+	require.NoError(t, err)
+
+	// The file is still open for the frozen database.
+	assert.Equal(t, cdb.ErrFinalized, writer.Close())
+	assert.Equal(t, cdb.ErrFinalized, writer.Put([]byte("baz"), []byte("qux")))
+
+	v, err := db.Get([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(v))
+}